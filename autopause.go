@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// autoPauseInterval is how often the streams are checked for being idle.
+const autoPauseInterval = time.Second
+
+// watchIdle stops downloading once nothing has been streamed for the
+// AutoPauseGrace. The download resumes when a stream starts.
+func (c *Client) watchIdle() {
+	if !c.waitInfo() {
+		return
+	}
+
+	// The grace period starts now for a client nobody streamed from yet.
+	c.mutex.Lock()
+	if c.idleSince.IsZero() {
+		c.idleSince = c.now()
+	}
+	c.mutex.Unlock()
+
+	for c.sleep(autoPauseInterval) {
+		c.mutex.Lock()
+		idle := c.streams == 0 && c.now().Sub(c.idleSince) >= c.Config.AutoPauseGrace
+		pause := idle && !c.idlePaused
+		if pause {
+			c.idlePaused = true
+		}
+		c.mutex.Unlock()
+
+		if pause {
+			log.Printf("Nothing streamed for %s, pausing the download\n", c.Config.AutoPauseGrace)
+			setPiecePriorities(c.Torrent, c.bulkPriority())
+		}
+	}
+}
+
+// resumeFromIdle reprioritizes the pieces of a download paused by watchIdle.
+func (c *Client) resumeFromIdle() {
+	c.mutex.Lock()
+	paused := c.idlePaused
+	c.idlePaused = false
+	c.mutex.Unlock()
+
+	if !paused {
+		return
+	}
+
+	log.Println("Streaming again, resuming the download")
+	if c.infoReady() {
+		c.prioritizeTorrent()
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestWatchIdlePausesAndResumes(t *testing.T) {
+	c := newTestClient(t, 4)
+	waitHashed(t, c)
+	c.Config.AutoPauseGrace = time.Minute
+	c.prioritizeTorrent()
+
+	// The clock is moved by the test while watchIdle reads it.
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	c.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+	done := make(chan struct{})
+	go func() {
+		c.watchIdle()
+		close(done)
+	}()
+	defer func() {
+		close(c.closing)
+		<-done
+	}()
+
+	waitPriority := func(want torrent.PiecePriority) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for i := 0; i < c.Torrent.NumPieces(); i++ {
+			for c.Torrent.PieceState(i).Priority != want {
+				select {
+				case <-deadline:
+					t.Fatalf("piece %d priority = %v, want %v", i, c.Torrent.PieceState(i).Priority, want)
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		}
+	}
+
+	// Streaming since before the grace period keeps the download going.
+	c.streamStarted()
+	now.Add(int64(2 * time.Minute))
+	time.Sleep(autoPauseInterval + 100*time.Millisecond)
+	waitPriority(torrent.PiecePriorityNormal)
+
+	c.streamEnded()
+	now.Add(int64(2 * time.Minute))
+	waitPriority(torrent.PiecePriorityNone)
+
+	c.streamStarted()
+	waitPriority(torrent.PiecePriorityNormal)
+	c.streamEnded()
+}
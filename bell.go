@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// bell is the terminal bell character.
+const bell = "\a"
+
+// notifyReady rings the bell and runs BellCommand the first time the stream
+// is ready for playback, for users who switched away from the terminal.
+func (c *Client) notifyReady(out io.Writer) {
+	if c.notifiedReady || !c.ReadyForPlayback() {
+		return
+	}
+	c.notifiedReady = true
+
+	if c.Config.Bell {
+		fmt.Fprint(out, bell)
+	}
+
+	if command := strings.Fields(c.Config.BellCommand); len(command) > 0 {
+		if err := exec.Command(command[0], command[1:]...).Start(); err != nil {
+			log.Printf("Error running bell command: %s\n", err)
+		}
+	}
+}
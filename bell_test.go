@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNotifyReady(t *testing.T) {
+	c := newSeededTestClient(t, make([]byte, 2*testPieceLength))
+	waitHashed(t, c)
+	rung := filepath.Join(t.TempDir(), "rung")
+	c.Config.Bell = true
+	c.Config.BellCommand = "touch " + rung
+	c.Config.ReadyPercentage = 100
+
+	var out bytes.Buffer
+	c.notifyReady(&out)
+	if out.Len() != 0 {
+		t.Errorf("notifyReady() before being ready wrote %q, want nothing", out.String())
+	}
+
+	c.Config.ReadyPercentage = 50
+	for i := 0; i < 3; i++ {
+		c.notifyReady(&out)
+	}
+	if out.String() != bell {
+		t.Errorf("notifyReady() once ready wrote %q, want a single %q", out.String(), bell)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, err := os.Stat(rung); err == nil {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("bell command not run")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestNotifyReadyWithoutBell(t *testing.T) {
+	c := newSeededTestClient(t, make([]byte, testPieceLength))
+	waitHashed(t, c)
+
+	var out bytes.Buffer
+	c.notifyReady(&out)
+	if out.Len() != 0 {
+		t.Errorf("notifyReady() without Bell wrote %q, want nothing", out.String())
+	}
+	if !c.notifiedReady {
+		t.Error("notifyReady() once ready didn't record it")
+	}
+}
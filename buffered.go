@@ -0,0 +1,46 @@
+package main
+
+// BufferedRange returns the contiguous range of downloaded bytes around the
+// read position in the streamed file, relative to the start of the file. It's
+// empty when the piece at the read position isn't downloaded yet.
+func (c *Client) BufferedRange() (start, end int64) {
+	if !c.infoReady() {
+		return 0, 0
+	}
+
+	file := c.selectedFile()
+	pieceLength := c.Torrent.Info().PieceLength
+	c.mutex.Lock()
+	playhead := c.playhead
+	c.mutex.Unlock()
+
+	fileEnd := file.Offset() + file.Length()
+	if playhead < file.Offset() || playhead >= fileEnd {
+		return 0, 0
+	}
+
+	position := playhead - file.Offset()
+	first := int(playhead / pieceLength)
+	if !c.Torrent.PieceState(first).Complete {
+		return position, position
+	}
+
+	begin, last := first, first
+	for begin > 0 && int64(begin)*pieceLength > file.Offset() && c.Torrent.PieceState(begin-1).Complete {
+		begin--
+	}
+	for int64(last+1)*pieceLength < fileEnd && c.Torrent.PieceState(last+1).Complete {
+		last++
+	}
+
+	start = int64(begin)*pieceLength - file.Offset()
+	if start < 0 {
+		start = 0
+	}
+	end = int64(last+1)*pieceLength - file.Offset()
+	if end > file.Length() {
+		end = file.Length()
+	}
+
+	return start, end
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestBufferedRange(t *testing.T) {
+	// A partial download, missing pieces 0 and 3.
+	length := int64(6*testPieceLength - 100)
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	info := metainfo.Info{Name: "movie.mkv", PieceLength: testPieceLength, Length: length}
+	onDisk := append([]byte(nil), data...)
+	for begin := int64(0); begin < length; begin += testPieceLength {
+		end := min(begin+testPieceLength, length)
+		hash := sha1.Sum(data[begin:end])
+		info.Pieces = append(info.Pieces, hash[:]...)
+	}
+	for _, missing := range []int64{0, 3} {
+		clear(onDisk[missing*testPieceLength : (missing+1)*testPieceLength])
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "movie.mkv.part"), onDisk, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := startTestClient(t, info, dir)
+	waitHashed(t, c)
+
+	tests := []struct {
+		playhead   int64
+		start, end int64
+	}{
+		{10, 10, 10},
+		{testPieceLength, testPieceLength, 3 * testPieceLength},
+		{2*testPieceLength + 500, testPieceLength, 3 * testPieceLength},
+		{3*testPieceLength + 1, 3*testPieceLength + 1, 3*testPieceLength + 1},
+		{5 * testPieceLength, 4 * testPieceLength, length},
+		{length, 0, 0},
+	}
+	for _, test := range tests {
+		c.mutex.Lock()
+		c.playhead = test.playhead
+		c.mutex.Unlock()
+
+		if start, end := c.BufferedRange(); start != test.start || end != test.end {
+			t.Errorf("BufferedRange() at %d = [%d, %d), want [%d, %d)", test.playhead, start, end, test.start, test.end)
+		}
+		if stats := c.Stats(); stats.BufferedStart != test.start || stats.BufferedEnd != test.end {
+			t.Errorf("Stats() buffered range at %d = [%d, %d), want [%d, %d)", test.playhead, stats.BufferedStart, stats.BufferedEnd, test.start, test.end)
+		}
+	}
+}
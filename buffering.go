@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// readBuffering tracks a reader's pending read, marking playback as
+// buffering if it isn't done within the threshold. Each reader reuses one
+// timer, which doesn't hold a goroutine until it fires.
+type readBuffering struct {
+	mutex   sync.Mutex
+	timer   *time.Timer
+	reading bool
+	started time.Time
+	stalled bool
+}
+
+// startRead starts waiting on a read.
+func (f *FileEntry) startRead() {
+	threshold := f.client.StreamingConfig().BufferingThreshold
+	if threshold <= 0 {
+		return
+	}
+
+	b := &f.buffering
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.reading = true
+	b.started = time.Now()
+	if b.timer == nil {
+		b.timer = time.AfterFunc(threshold, func() { f.stallRead(threshold) })
+	} else {
+		b.timer.Reset(threshold)
+	}
+}
+
+// stallRead marks playback as buffering if the read is still pending. A
+// timer firing late for a previous read does nothing.
+func (f *FileEntry) stallRead(threshold time.Duration) {
+	b := &f.buffering
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.reading || b.stalled || time.Since(b.started) < threshold {
+		return
+	}
+	b.stalled = true
+	f.client.setBuffering(true)
+}
+
+// endRead stops waiting on the read, ending the buffering it caused.
+func (f *FileEntry) endRead() {
+	b := &f.buffering
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.reading = false
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	if b.stalled {
+		b.stalled = false
+		f.client.setBuffering(false)
+	}
+}
+
+// setBuffering tracks stalled reads, notifying when playback starts or stops
+// buffering.
+func (c *Client) setBuffering(buffering bool) {
+	c.mutex.Lock()
+	before := c.buffering > 0
+	if buffering {
+		c.buffering++
+	} else {
+		c.buffering--
+	}
+	after := c.buffering > 0
+	c.mutex.Unlock()
+
+	if before == after {
+		return
+	}
+	c.publishEvent(statsEvent{Type: "buffering", Buffering: &after})
+	if c.Config.OnBuffering != nil {
+		c.Config.OnBuffering(after)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadBuffering(t *testing.T) {
+	c := &Client{}
+	c.Config.BufferingThreshold = 20 * time.Millisecond
+	f := &FileEntry{client: c}
+
+	tests := []struct {
+		wait bool
+		want int
+	}{
+		{false, 0},
+		{true, 1},
+		{false, 0},
+		{true, 1},
+	}
+
+	for i, test := range tests {
+		f.startRead()
+		if test.wait {
+			time.Sleep(100 * time.Millisecond)
+		}
+		c.mutex.Lock()
+		got := c.buffering
+		c.mutex.Unlock()
+		f.endRead()
+
+		if got != test.want {
+			t.Errorf("read %d: buffering = %d, want %d", i, got, test.want)
+		}
+		if c.buffering != 0 {
+			t.Errorf("read %d: buffering after the read = %d, want 0", i, c.buffering)
+		}
+	}
+}
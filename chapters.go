@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// chapter is a chapter marker of the streamed file.
+type chapter struct {
+	Start time.Duration
+	End   time.Duration
+	Title string
+}
+
+// probeChapters extracts the chapters of the stream with ffprobe, skipping
+// the empty ones.
+func (c *Client) probeChapters(ctx context.Context) ([]chapter, error) {
+	output, err := c.ffprobe(ctx, "-show_chapters")
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Chapters []struct {
+			StartTime string `json:"start_time"`
+			EndTime   string `json:"end_time"`
+			Tags      struct {
+				Title string `json:"title"`
+			} `json:"tags"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, err
+	}
+
+	chapters := make([]chapter, 0, len(probe.Chapters))
+	for i, probed := range probe.Chapters {
+		start, err := strconv.ParseFloat(probed.StartTime, 64)
+		if err != nil {
+			return nil, err
+		}
+		end, err := strconv.ParseFloat(probed.EndTime, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		// Zero-length chapters, like the markers some muxers leave at the
+		// end, can't be seeked to.
+		if end <= start {
+			continue
+		}
+
+		title := probed.Tags.Title
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+
+		chapters = append(chapters, chapter{
+			Start: time.Duration(start * float64(time.Second)),
+			End:   time.Duration(end * float64(time.Second)),
+			Title: title,
+		})
+	}
+
+	return chapters, nil
+}
+
+// GetChapters is an http handler serving the chapters of the file as a
+// WebVTT track.
+func (c *Client) GetChapters(w http.ResponseWriter, r *http.Request) {
+	if !c.ReadyForPlayback() {
+		http.Error(w, "not enough of the file is buffered yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	c.mutex.Lock()
+	chapters := c.chapters
+	c.mutex.Unlock()
+
+	if chapters == nil {
+		var err error
+		if chapters, err = c.probeChapters(r.Context()); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		c.mutex.Lock()
+		c.chapters = chapters
+		c.mutex.Unlock()
+	}
+
+	if len(chapters) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Write(formatChapters(chapters))
+}
+
+// formatChapters renders the chapters as WebVTT cues.
+func formatChapters(chapters []chapter) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("WEBVTT\n")
+
+	for i, chapter := range chapters {
+		fmt.Fprintf(&buffer, "\n%d\n%s --> %s\n%s\n", i+1, formatVTTTime(chapter.Start), formatVTTTime(chapter.End), chapter.Title)
+	}
+
+	return buffer.Bytes()
+}
+
+// formatVTTTime formats a duration as a WebVTT timestamp.
+func formatVTTTime(d time.Duration) string {
+	milliseconds := d.Nanoseconds() / int64(time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
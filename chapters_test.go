@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestFormatChapters(t *testing.T) {
+	tests := []struct {
+		chapters []chapter
+		want     string
+	}{
+		{nil, "WEBVTT\n"},
+		{
+			[]chapter{{0, 90 * time.Second, "Intro"}},
+			"WEBVTT\n\n1\n00:00:00.000 --> 00:01:30.000\nIntro\n",
+		},
+		{
+			[]chapter{
+				{0, 1500 * time.Millisecond, "Cold open"},
+				{1500 * time.Millisecond, 2*time.Hour + 3*time.Minute + 4*time.Second + 5*time.Millisecond, "Chapter 2"},
+			},
+			"WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.500\nCold open\n" +
+				"\n2\n00:00:01.500 --> 02:03:04.005\nChapter 2\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := string(formatChapters(test.chapters)); got != test.want {
+			t.Errorf("formatChapters(%v) = %q, want %q", test.chapters, got, test.want)
+		}
+	}
+}
+
+func TestProbeChapters(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffprobe is a shell script")
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		want    []chapter
+		wantErr bool
+	}{
+		{"no chapters", `{}`, []chapter{}, false},
+		{
+			"titled",
+			`{"chapters": [{"start_time": "0.000000", "end_time": "60.500000", "tags": {"title": "Intro"}}]}`,
+			[]chapter{{0, 60500 * time.Millisecond, "Intro"}},
+			false,
+		},
+		{
+			"missing titles",
+			`{"chapters": [{"start_time": "0", "end_time": "10"}, {"start_time": "10", "end_time": "20", "tags": {}}]}`,
+			[]chapter{{0, 10 * time.Second, "Chapter 1"}, {10 * time.Second, 20 * time.Second, "Chapter 2"}},
+			false,
+		},
+		{
+			"zero-length",
+			`{"chapters": [{"start_time": "0", "end_time": "10", "tags": {"title": "Intro"}}, {"start_time": "10", "end_time": "10", "tags": {"title": "Marker"}}]}`,
+			[]chapter{{0, 10 * time.Second, "Intro"}},
+			false,
+		},
+		{"invalid time", `{"chapters": [{"start_time": "N/A", "end_time": "10"}]}`, nil, true},
+		{"invalid json", `chapters`, nil, true},
+	}
+
+	for _, test := range tests {
+		// A fake ffprobe printing the output.
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "output.json"), []byte(test.output), 0644); err != nil {
+			t.Fatal(err)
+		}
+		script := "#!/bin/sh\ncat " + filepath.Join(dir, "output.json") + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		c := &Client{}
+		got, err := c.probeChapters(context.Background())
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: probeChapters() = %v, %v, want %v, error %v", test.name, got, err, test.want, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/anacrolix/torrent"
+)
+
+// isChecking reports whether the existing data is being hashed again.
+func (c *Client) isChecking() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.checking
+}
+
+// pieceChecking reports if a piece is being hashed or waiting for it.
+func pieceChecking(state torrent.PieceState) bool {
+	return state.Hashing || state.QueuedForHash
+}
+
+// recheck hashes the existing data again in the background, piece by piece.
+// Reads wait for their pieces to be verified meanwhile, unless
+// ServeWhileChecking is set.
+func (c *Client) recheck() {
+	c.mutex.Lock()
+	c.checking = true
+	c.checkedPieces = 0
+	c.mutex.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-c.closing:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		for i := 0; i < c.Torrent.NumPieces(); i++ {
+			if err := c.Torrent.Piece(i).VerifyDataContext(ctx); err != nil {
+				log.Printf("Error checking the data: %s\n", err)
+				break
+			}
+
+			c.mutex.Lock()
+			c.checkedPieces = i + 1
+			c.mutex.Unlock()
+		}
+
+		c.mutex.Lock()
+		c.checking = false
+		c.mutex.Unlock()
+	}()
+}
+
+// checkingProgress returns the percentage of pieces done being checked. The
+// pieces the recheck hasn't reached yet, and the ones queued for hashing, are
+// still to check.
+func (c *Client) checkingProgress() float64 {
+	pieces := c.Torrent.NumPieces()
+	if pieces == 0 {
+		return 0
+	}
+
+	c.mutex.Lock()
+	reached := pieces
+	if c.checking {
+		reached = c.checkedPieces
+	}
+	c.mutex.Unlock()
+
+	checked := 0
+	for i := 0; i < reached; i++ {
+		if !pieceChecking(c.Torrent.PieceState(i)) {
+			checked++
+		}
+	}
+
+	return float64(checked) / float64(pieces) * 100
+}
+
+// waitsVerified checks if reads have to wait for their pieces to be verified:
+// always with VerifyReads, and during a recheck unless ServeWhileChecking.
+func (c *Client) waitsVerified() bool {
+	return c.Config.VerifyReads || (!c.Config.ServeWhileChecking && c.isChecking())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// waitHashed waits for the pieces queued for hashing when added to settle.
+func waitHashed(t *testing.T, c *Client) {
+	t.Helper()
+
+	deadline := time.After(5 * time.Second)
+	for i := 0; i < c.Torrent.NumPieces(); i++ {
+		for pieceChecking(c.Torrent.PieceState(i)) {
+			select {
+			case <-deadline:
+				t.Fatal("pieces not hashed")
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+}
+
+func TestCheckingProgress(t *testing.T) {
+	c := newTestClient(t, 4)
+	waitHashed(t, c)
+	tests := []struct {
+		checking bool
+		checked  int
+		want     float64
+	}{
+		{false, 0, 100},
+		{true, 0, 0},
+		{true, 1, 25},
+		{true, 3, 75},
+		{true, 4, 100},
+	}
+
+	for _, test := range tests {
+		c.checking = test.checking
+		c.checkedPieces = test.checked
+		if got := c.checkingProgress(); got != test.want {
+			t.Errorf("checkingProgress() with %d of 4 pieces checked = %v, want %v", test.checked, got, test.want)
+		}
+	}
+}
+
+func TestRecheckInBackground(t *testing.T) {
+	c := newTestClient(t, 4)
+
+	c.recheck()
+	deadline := time.After(5 * time.Second)
+	for c.isChecking() {
+		select {
+		case <-deadline:
+			t.Fatal("recheck didn't finish")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if got := c.checkingProgress(); got != 100 {
+		t.Errorf("checkingProgress() after the recheck = %v, want 100", got)
+	}
+}
@@ -1,24 +1,59 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
 	"github.com/dustin/go-humanize"
+	"golang.org/x/term"
+	"golang.org/x/time/rate"
 )
 
 const clearScreen = "\033[H\033[2J"
 
+// defaultRenderWidth is used when the width of the terminal can't be detected.
+const defaultRenderWidth = 80
+
 var isHTTP = regexp.MustCompile(`^https?:\/\/`)
 
+// torrentContentType is the content type torrent files are served with.
+const torrentContentType = "application/x-bittorrent"
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ErrNoTorrent is returned when no magnet, torrent file or url is given.
+var ErrNoTorrent = errors.New("a magnet url, torrent path or torrent url is required")
+
+// ErrMetadataTimeout is returned when the torrent info couldn't be fetched in time.
+var ErrMetadataTimeout = errors.New("timed out waiting for the torrent metadata")
+
+// ErrMetadataNotReady is returned when something needs the torrent info before
+// it's fetched.
+var ErrMetadataNotReady = errors.New("the torrent metadata isn't fetched yet")
+
+// ErrClientClosed is returned by the reads still waiting when the client is
+// closed.
+var ErrClientClosed = errors.New("the client is closed")
+
 // ClientError formats errors coming from the client.
 type ClientError struct {
 	Type   string
@@ -29,28 +64,400 @@ func (clientError ClientError) Error() string {
 	return fmt.Sprintf("Error %s: %s\n", clientError.Type, clientError.Origin)
 }
 
+// ClientConfig specifies the behaviour of a client.
+type ClientConfig struct {
+	TorrentPath string
+	// ExpectedInfoHash refuses a TorrentPath with another infohash, to catch
+	// tampered mirrors of torrent files.
+	ExpectedInfoHash string
+	// QRCode reads the magnet link or url from the QR code image at
+	// TorrentPath.
+	QRCode bool
+	Port   int
+	// PortRange is the number of ports after Port to try when it's taken.
+	PortRange int
+	// PortFromInfoHash derives the port within PortRange from the infohash.
+	PortFromInfoHash bool
+	Seed             bool
+	// LANOnlySeed only uploads to peers on the local network, once the
+	// download is complete.
+	LANOnlySeed bool
+	DLNA        bool
+	DataDir     string
+	// FileIndex picks the file to stream by its index in the torrent.
+	// Negative picks the largest file.
+	FileIndex int
+	// FileSelector picks the file to stream from the files of the torrent,
+	// returning its index, when FileIndex is negative. Without one, the
+	// largest file is streamed.
+	FileSelector func(files []FileInfo) int
+	// CloseOnFileSwitch closes the streams of the previous file when
+	// SetSelectedFile picks another one, so players reconnect to the new
+	// file. Otherwise they finish on the previous file.
+	CloseOnFileSwitch bool
+	// PrefetchNext downloads the first PrefetchBytes of the next video, like
+	// the next episode of a pack, while the streamed one is buffered far
+	// enough ahead, so switching to it is instant.
+	PrefetchNext  bool
+	PrefetchBytes int64
+	// PlaylistAllFiles lists every video of the torrent on /playlist.m3u,
+	// rather than only the streamed file.
+	PlaylistAllFiles bool
+	// AdvertisedHost is the host:port other devices reach this client on,
+	// used in SessionURL, the playlist and the command line interface.
+	// Defaults to the requested host, or localhost and the stream port.
+	AdvertisedHost string
+	// PrivateMode only finds peers through the trackers, disabling the DHT
+	// and peer exchange. Private torrents are refused without it.
+	PrivateMode bool
+	// ForceRecheck hashes the existing data again instead of trusting the
+	// pieces verified in a previous run.
+	ForceRecheck bool
+	// SeedSchedule only uploads within a time of day when seeding.
+	SeedSchedule SeedSchedule
+	// UploadRateLimit and DownloadRateLimit cap the speeds in bytes per
+	// second, and can be tuned on /config. Zero doesn't limit them.
+	UploadRateLimit   int64
+	DownloadRateLimit int64
+	// PersistPriorities saves the piece priorities and read position on
+	// Close, and restores them when the torrent is streamed again.
+	PersistPriorities bool
+	// ServeWhileChecking streams the existing data while ForceRecheck is
+	// hashing it, instead of waiting for its pieces to be verified.
+	ServeWhileChecking bool
+	// DataDirFunc picks the directory the data of each torrent is stored in,
+	// to spread torrents across disks. An empty result, like a nil func,
+	// stores it in DataDir.
+	DataDirFunc func(infoHash string, info *metainfo.Info) string
+	// StorageRoutes stores files with these extensions in other directories
+	// than DataDir.
+	StorageRoutes StorageRoutes
+	// DataTTL removes the downloaded data once it has been complete and
+	// unwatched for this long. Only applies when not seeding.
+	DataTTL time.Duration
+	// AutoPauseWhenIdle stops downloading once nothing has been streamed for
+	// AutoPauseGrace, and resumes when a stream starts.
+	AutoPauseWhenIdle bool
+	AutoPauseGrace    time.Duration
+	// ShutdownDrain is how long the streams are given to finish after a
+	// shutdown is requested on /shutdown.
+	ShutdownDrain time.Duration
+	// MaxRuntime exits the program after running for this long.
+	MaxRuntime time.Duration
+	// AggressiveMetadata looks for the metadata of magnets on public
+	// trackers and by querying the DHT repeatedly. Ignored in PrivateMode.
+	AggressiveMetadata bool
+	// MetadataTimeout gives up on torrents whose info can't be fetched from
+	// peers in time. Zero waits forever.
+	MetadataTimeout time.Duration
+	// Verbose adds the torrent metadata and piece map to the cli output.
+	Verbose bool
+	// ExcludePattern is a regular expression matched against the file paths
+	// in the torrent. Matching files are never downloaded.
+	ExcludePattern string
+	// MaxPatternLength and MaxPatternComplexity reject the exclude and
+	// selection patterns longer than this many characters, or compiling to
+	// more than this many instructions. Zero doesn't limit them.
+	MaxPatternLength     int
+	MaxPatternComplexity int
+	// ConnectionFilter is consulted before connecting to or accepting a
+	// peer, which is only allowed when it returns true. The address is a
+	// *net.IPAddr, as the port isn't known. Nil allows all peers.
+	ConnectionFilter func(addr net.Addr) bool
+	// PreferFastPeers drops the slowest peers while playback is buffering,
+	// so the urgent pieces are downloaded from the faster ones.
+	PreferFastPeers bool
+	// IndexTemplate is the path of an html/template replacing the web ui
+	// served on /ui. It gets the Name, StreamURL and StatusURL.
+	IndexTemplate string
+	// TMDbAPIKey enables looking up the poster and synopsis of the torrent
+	// on The Movie Database, for /metadata.
+	TMDbAPIKey string
+	// SpeedInBits shows speeds in bits per second, like Mbps, instead of
+	// bytes.
+	SpeedInBits bool
+	// Output is where the cli is rendered to, os.Stdout when nil.
+	Output io.Writer
+	// Bell rings the terminal bell once the stream is ready for playback,
+	// and BellCommand is run then, like a command playing a sound.
+	Bell        bool
+	BellCommand string
+	// RenderOnChange only redraws the cli when what it shows changed.
+	RenderOnChange bool
+	// RenderWidth overrides the detected width of the terminal.
+	RenderWidth int
+	// VerifyReads makes sure the stream never contains data from pieces
+	// that haven't passed their hash check.
+	VerifyReads bool
+	// AudioLanguage picks the audio track to keep when transcoding, like eng.
+	AudioLanguage string
+	// SubtitleLanguage picks the subtitles in the torrent served on
+	// /subtitles.vtt, by a language tag in their name, like eng.
+	SubtitleLanguage string
+	// DefaultExtension is added to the served file name when the file has
+	// no extension, like .mp4.
+	DefaultExtension string
+	// Transcode serves the file remuxed by ffmpeg on /transcode. At most
+	// MaxTranscodes ffmpeg processes run at once, for /transcode, /preview
+	// and /audio, with zero not limiting them.
+	Transcode     bool
+	MaxTranscodes int
+	// Preview serves a low bitrate transcode on /preview while the file
+	// buffers, at PreviewBitrate kbit/s and PreviewHeight lines.
+	Preview        bool
+	PreviewBitrate int
+	PreviewHeight  int
+	// AudioOnly serves only the audio of the file on /audio, extracted by
+	// ffmpeg, for listening to it.
+	AudioOnly bool
+	// Fallback serves a built-in image with the error instead of the file
+	// when the torrent failed, or FallbackAsset when it's set.
+	Fallback      bool
+	FallbackAsset string
+	// DirectCompleted serves completed files straight from disk, rather than
+	// through the torrent reader.
+	DirectCompleted bool
+	// ProgressiveDownload reads further ahead for requests of the whole
+	// file, without a Range header, as they are read from start to end.
+	ProgressiveDownload bool
+	// ResponseBufferSize is the size of the buffer used to copy the file
+	// into http responses. Zero uses the 32 KiB of io.Copy.
+	ResponseBufferSize int
+	// Readahead is how many bytes the readers of a file read ahead. Zero
+	// reads ahead 1% of the file, or 5% when reading it whole.
+	Readahead int64
+	// ReadyPercentage is how much of the torrent is downloaded before it's
+	// ready for playback.
+	ReadyPercentage float64
+	// BufferingThreshold is how long a read has to wait on missing pieces
+	// before playback is considered to be buffering. Zero disables it.
+	BufferingThreshold time.Duration
+	// OnBuffering is called when playback starts and stops buffering.
+	OnBuffering func(buffering bool)
+	// CompleteWholeTorrent makes WaitForComplete wait for every file, rather
+	// than just the streamed one.
+	CompleteWholeTorrent bool
+	// MaxTorrents caps how many torrents the client has, counting the
+	// streamed one. Adding more fails, unless EvictTorrents removes the
+	// least recently streamed one to make room. Zero doesn't limit them.
+	MaxTorrents   int
+	EvictTorrents bool
+	// WarmStartConcurrency and WarmStartTimeout limit how many magnets
+	// WarmStart resolves at once, and for how long.
+	WarmStartConcurrency int
+	WarmStartTimeout     time.Duration
+	// OnCompleteWebhook is a url the name, infohash, size and path of the
+	// file, or the torrent with CompleteWholeTorrent, are posted to as json
+	// once it's downloaded.
+	OnCompleteWebhook string
+	// MetricsAddr is the address to serve line protocol metrics on. Empty
+	// disables them.
+	MetricsAddr     string
+	MetricsInterval time.Duration
+	// WebSocketInterval is how often /ws pushes the stats.
+	WebSocketInterval time.Duration
+	// PerFileMetrics adds the progress of every file to the prometheus
+	// metrics on /metrics.
+	PerFileMetrics bool
+	// FTPAddr is the address to serve the file over FTP on. Empty disables
+	// it. Without an FTPUser, anyone can login.
+	FTPAddr     string
+	FTPUser     string
+	FTPPassword string
+	// Middlewares wrap the handlers of the http api, in order.
+	Middlewares []Middleware
+	// CORSOrigin allows browser ui on this origin, or any for "*", to use the
+	// http api.
+	CORSOrigin string
+	// AuthToken protects endpoints like /logs and /config. Requests send it as a bearer
+	// token or a token parameter. Empty allows everyone to read them, and only
+	// the local host to change the state of the client.
+	AuthToken string
+	// GRPCAddr is the address to serve the Peerflix grpc service of
+	// peerflix.proto on. Empty disables it.
+	GRPCAddr string
+	// MaxPiecesAhead caps how many pieces past the read position are
+	// requested. Zero downloads the whole torrent.
+	MaxPiecesAhead int
+	// BackgroundRarest downloads the rarest pieces outside the MaxPiecesAhead
+	// window while the window itself is downloaded, so the torrent completes
+	// without holding back the stream. It does nothing without a
+	// MaxPiecesAhead, as every piece ahead is requested then.
+	BackgroundRarest bool
+	// MemoryLimit is the memory usage in bytes above which the readahead
+	// and MaxPiecesAhead are reduced. Zero disables it.
+	MemoryLimit uint64
+	// PriorityDebounce coalesces the piece priority updates of a moving
+	// playhead within this interval.
+	PriorityDebounce time.Duration
+	// DropBehindBytes releases the pieces further than this behind the read
+	// position, so they aren't downloaded or kept wanted. Only applies when
+	// not seeding.
+	DropBehindBytes int64
+}
+
+// NewClientConfig creates a new default configuration.
+func NewClientConfig() ClientConfig {
+	return ClientConfig{
+		Port:                 8080,
+		FileIndex:            -1,
+		ProgressiveDownload:  true,
+		ResponseBufferSize:   128 << 10,
+		MaxPatternLength:     256,
+		MaxPatternComplexity: 2000,
+		MaxTranscodes:        2,
+		PreviewBitrate:       800,
+		PreviewHeight:        480,
+		DataDir:              os.TempDir(),
+		StorageRoutes:        StorageRoutes{},
+		ReadyPercentage:      readyPercentage,
+		BufferingThreshold:   500 * time.Millisecond,
+		MetricsInterval:      10 * time.Second,
+		WebSocketInterval:    time.Second,
+		WarmStartConcurrency: 4,
+		WarmStartTimeout:     time.Minute,
+		AutoPauseGrace:       time.Minute,
+		ShutdownDrain:        30 * time.Second,
+		PrefetchBytes:        20 << 20,
+	}
+}
+
 // Client manages the torrent downloading.
 type Client struct {
 	Client   *torrent.Client
-	Torrent  torrent.Torrent
+	Torrent  *torrent.Torrent
 	Progress int64
 	Port     int
+	Config   ClientConfig
+
+	blocklist    *connectionBlocklist
+	completion   *bitfieldCompletion
+	lastRender   string
+	lock         *os.File
+	fileCache    []*torrent.File
+	savedFiles   []FileInfo
+	chapters     []chapter
+	pieceTimes   *pieceTimer
+	prioritizing *debouncer
+	exclude      *regexp.Regexp
+	excluded     []bool
+	index        *template.Template
+
+	metadataProvider metadataProvider
+	metadata         *metadataCache
+	// dlna is the DLNA server advertising the stream, if any.
+	dlna *DLNAServer
+	// metaInfo is the torrent file the torrent was added from, nil for
+	// magnets. The library only returns a metainfo of its own making.
+	metaInfo *metainfo.MetaInfo
+	// uploadLimiter and downloadLimiter are the rate limiters of the torrent
+	// client, adjusted by SetStreamingConfig.
+	uploadLimiter   *rate.Limiter
+	downloadLimiter *rate.Limiter
+	// audioLanguages caches the language of each audio stream of the file,
+	// like chapters.
+	audioLanguages []string
+
+	// now is the clock, which tests can replace.
+	now func() time.Time
+
+	fileCompleted    chan struct{}
+	torrentCompleted chan struct{}
+	mutex            sync.Mutex
+	playhead         int64
+	streams          int
+	idleSince        time.Time
+	idlePaused       bool
+	backgroundPieces map[int]struct{}
+	overrides        map[int]torrent.PiecePriority
+	lastStreamed     map[metainfo.Hash]time.Time
+	directReads      int
+	prefetching      bool
+	eventSubscribers map[chan statsEvent]struct{}
+	shutdown         chan struct{}
+	shutdownOnce     sync.Once
+	closing          chan struct{}
+	closeOnce        sync.Once
+	downloadSpeed    int64
+	smoothedSpeed    float64
+	swarmHealth      SwarmHealth
+	checking         bool
+	checkedPieces    int
+	buffering        int
+	transcodes       int
+	torrentPriority  TorrentPriority
+	notifiedReady    bool
+	memoryPressure   bool
+	uploading        bool
+	readers          map[*FileEntry]struct{}
+	err              error
 }
 
 // NewClient creates a new torrent client based on a magnet or a torrent file.
 // If the torrent file is on http, we try downloading it.
-func NewClient(torrentPath string, port int, seed bool) (client Client, err error) {
-	var t torrent.Torrent
+func NewClient(cfg ClientConfig) (client *Client, err error) {
+	var t *torrent.Torrent
 	var c *torrent.Client
 
-	client.Port = port
+	client = &Client{
+		prioritizing:     newDebouncer(cfg.PriorityDebounce),
+		pieceTimes:       newPieceTimer(),
+		fileCompleted:    make(chan struct{}),
+		torrentCompleted: make(chan struct{}),
+		readers:          make(map[*FileEntry]struct{}),
+		backgroundPieces: make(map[int]struct{}),
+		overrides:        make(map[int]torrent.PiecePriority),
+		lastStreamed:     make(map[metainfo.Hash]time.Time),
+		shutdown:         make(chan struct{}),
+		closing:          make(chan struct{}),
+		eventSubscribers: make(map[chan statsEvent]struct{}),
+		now:              time.Now,
+		torrentPriority:  TorrentPriorityNormal,
+		uploading:        cfg.Seed,
+	}
+	client.Config = cfg
+	client.Port = cfg.Port
+	torrentPath := cfg.TorrentPath
+
+	if strings.TrimSpace(torrentPath) == "" {
+		return client, ClientError{Type: "no torrent specified", Origin: ErrNoTorrent}
+	}
+	if cfg.ExpectedInfoHash != "" && !isInfoHash.MatchString(cfg.ExpectedInfoHash) {
+		return client, ClientError{Type: "parsing expected infohash", Origin: fmt.Errorf("%q isn't a hex infohash", cfg.ExpectedInfoHash)}
+	}
+
+	if cfg.ExcludePattern != "" {
+		if client.exclude, err = client.compilePattern(cfg.ExcludePattern); err != nil {
+			return client, ClientError{Type: "parsing exclude pattern", Origin: err}
+		}
+	}
+
+	if client.index, err = parseIndexTemplate(cfg.IndexTemplate); err != nil {
+		return client, ClientError{Type: "parsing index template", Origin: err}
+	}
+
+	if cfg.TMDbAPIKey != "" {
+		client.metadataProvider = newTMDbProvider(cfg.TMDbAPIKey)
+	}
 
 	// Create client.
-	c, err = torrent.NewClient(&torrent.Config{
-		DataDir:  os.TempDir(),
-		NoUpload: !seed,
-		Seed:     seed,
-	})
+	config := torrent.NewDefaultClientConfig()
+	config.DataDir = cfg.DataDir
+	config.NoUpload = !cfg.Seed
+	config.Seed = cfg.Seed
+	config.NoDHT = cfg.PrivateMode
+	config.DisablePEX = cfg.PrivateMode
+	client.completion = newBitfieldCompletion(cfg.stateDir())
+	config.DefaultStorage = newStorage(cfg, client.completion)
+	client.blocklist = &connectionBlocklist{filter: cfg.ConnectionFilter}
+	config.IPBlocklist = client.blocklist
+	// The limiters always exist, as they can't be added once running.
+	client.uploadLimiter = newRateLimiter(cfg.UploadRateLimit)
+	client.downloadLimiter = newRateLimiter(cfg.DownloadRateLimit)
+	config.UploadRateLimiter = client.uploadLimiter
+	config.DownloadRateLimiter = client.downloadLimiter
+
+	c, err = torrent.NewClient(config)
 
 	if err != nil {
 		return client, ClientError{Type: "creating torrent client", Origin: err}
@@ -60,10 +467,280 @@ func NewClient(torrentPath string, port int, seed bool) (client Client, err erro
 
 	// Add torrent.
 
+	// Read the magnet or url from a QR code.
+	if cfg.QRCode {
+		if torrentPath, err = decodeQRCode(torrentPath); err != nil {
+			return client, ClientError{Type: "reading qr code", Origin: err}
+		}
+	}
+
+	// Join the session shared by another client.
+	if strings.HasPrefix(torrentPath, sessionScheme+"://") {
+		var session sessionLink
+		if session, err = parseSessionURL(torrentPath); err != nil {
+			return client, ClientError{Type: "parsing session url", Origin: err}
+		}
+		client.Config.FileIndex = session.FileIndex
+		torrentPath = session.magnet()
+	}
+
+	var spec *torrent.TorrentSpec
+	if spec, client.metaInfo, err = torrentSpec(torrentPath, cfg.ExpectedInfoHash); err == nil {
+		err = client.checkSpec(spec)
+	}
+	if err != nil {
+		c.Close()
+		return client, err
+	}
+
+	// Lock the data before adding the torrent, so a second process never
+	// starts verifying or writing it.
+	if err = client.lockData(spec.InfoHash); err != nil {
+		c.Close()
+		return client, ClientError{Type: "locking data directory", Origin: err}
+	}
+
+	if t, _, err = addTorrentSpec(c, spec); err != nil {
+		client.unlockData()
+		c.Close()
+		return client, err
+	}
+
+	client.Torrent = t
+	if cfg.Seed && (cfg.LANOnlySeed || cfg.SeedSchedule.Enabled()) {
+		// Uploading is enabled once the peers are restricted to the LAN, or
+		// within the seeding schedule.
+		client.setUploading(false)
+	}
+	if cfg.AggressiveMetadata && !cfg.PrivateMode && isMagnet(torrentPath) {
+		go client.fetchMetadataAggressively()
+	}
+
+	client.loadFileList()
+
+	if cfg.MetadataTimeout > 0 {
+		if err = client.waitMetadata(); err != nil {
+			client.Close()
+			return client, err
+		}
+	}
+
+	go client.watchCompletion()
+	go client.watchPieceTimes()
+	go client.watchSwarm()
+
+	if cfg.MemoryLimit > 0 {
+		go client.watchMemory()
+	}
+
+	if cfg.PreferFastPeers {
+		go client.preferFastPeers()
+	}
+
+	if cfg.Seed && cfg.LANOnlySeed {
+		go client.seedToLAN()
+	} else if cfg.Seed && cfg.SeedSchedule.Enabled() {
+		go client.followSeedSchedule()
+	}
+
+	if cfg.AutoPauseWhenIdle {
+		go client.watchIdle()
+	}
+
+	if cfg.OnCompleteWebhook != "" {
+		go client.postCompleteWebhook()
+	}
+
+	if cfg.PrefetchNext {
+		go client.watchPrefetch()
+	}
+
+	if cfg.BackgroundRarest {
+		go client.downloadRarest()
+	}
+
+	if cfg.DataTTL > 0 && !cfg.Seed {
+		go client.expireData()
+	}
+
+	go func() {
+		<-t.GotInfo()
+		if err := client.checkInfo(t.Info()); err != nil {
+			log.Printf("Error: %s\n", err)
+			client.setErr(err)
+			t.Drop()
+			return
+		}
+
+		if cfg.ForceRecheck {
+			client.recheck()
+		}
+
+		client.saveFileList()
+		client.excludeFiles()
+		if cfg.PersistPriorities && client.restorePriorities() {
+			return
+		}
+
+		client.setPlayhead(client.selectedFile().Offset())
+		client.prioritizeTorrent()
+	}()
+
+	return
+}
+
+// prioritizeTorrent sets the priorities of all the pieces: either around the
+// playhead, or the whole torrent with the start first.
+func (c *Client) prioritizeTorrent() {
+	if c.followsPlayhead() {
+		c.prioritize()
+		return
+	}
+
+	t := c.Torrent
+	priority := c.bulkPriority()
+	for i := 0; i < t.NumPieces(); i++ {
+		t.Piece(i).SetPriority(priority)
+	}
+
+	// Prioritize first 5% of the file.
+	if priority != torrent.PiecePriorityNone {
+		for i := 0; i < t.NumPieces()/100*5; i++ {
+			t.Piece(i).SetPriority(torrent.PiecePriorityReadahead)
+		}
+	}
+
+	c.applyOverrides()
+}
+
+// setPlayhead records the torrent offset being read, reprioritizing the
+// pieces when it moves to another piece.
+func (c *Client) setPlayhead(offset int64) {
+	if !c.infoReady() {
+		return
+	}
+
+	c.mutex.Lock()
+	pieceLength := c.Torrent.Info().PieceLength
+	moved := c.playhead/pieceLength != offset/pieceLength
+	c.playhead = offset
+	c.mutex.Unlock()
+
+	if !moved {
+		return
+	}
+
+	// Seeking around moves the playhead a lot, so the updates are coalesced.
+	if c.Config.PriorityDebounce > 0 {
+		c.prioritizing.trigger(c.prioritize)
+	} else {
+		c.prioritize()
+	}
+}
+
+// followsPlayhead checks if the piece priorities depend on the playhead,
+// rather than downloading everything.
+func (c *Client) followsPlayhead() bool {
+	return c.StreamingConfig().MaxPiecesAhead > 0 || c.dropsBehind()
+}
+
+// dropsBehind checks if pieces behind the playhead are released.
+func (c *Client) dropsBehind() bool {
+	return c.StreamingConfig().DropBehindBytes > 0 && !c.Config.Seed
+}
+
+// prioritize sets the piece priorities around the playhead: only the pieces
+// within MaxPiecesAhead are requested, and the ones more than DropBehindBytes
+// behind are released.
+func (c *Client) prioritize() {
+	if !c.followsPlayhead() {
+		return
+	}
+
+	t := c.Torrent
+	pieceLength := t.Info().PieceLength
+	c.mutex.Lock()
+	playhead := c.playhead
+	c.mutex.Unlock()
+
+	current := int(playhead / pieceLength)
+	piecesAhead := c.maxPiecesAhead()
+	dropBefore := c.dropBefore(playhead, pieceLength)
+
+	c.mutex.Lock()
+	background := make(map[int]struct{}, len(c.backgroundPieces))
+	for i := range c.backgroundPieces {
+		background[i] = struct{}{}
+	}
+	c.mutex.Unlock()
+
+	c.mutex.Lock()
+	prefetching := c.prefetching
+	c.mutex.Unlock()
+
+	// The background and prefetched pieces come strictly after the window,
+	// unless the torrent is paused.
+	window := c.bulkPriority()
+	var backgroundPriority torrent.PiecePriority
+	if window != torrent.PiecePriorityNone && (len(background) > 0 || prefetching) {
+		window, backgroundPriority = backgroundPriorities(window)
+	}
+	for i := 0; i < t.NumPieces(); i++ {
+		priority := window
+		switch {
+		case i < dropBefore:
+			priority = torrent.PiecePriorityNone
+		case piecesAhead > 0 && (i < current || i > current+piecesAhead):
+			priority = torrent.PiecePriorityNone
+			if _, ok := background[i]; ok {
+				priority = backgroundPriority
+			}
+		}
+		t.Piece(i).SetPriority(priority)
+	}
+
+	c.applyOverrides()
+}
+
+// dropBefore returns the first piece kept wanted behind the playhead.
+func (c *Client) dropBefore(playhead, pieceLength int64) int {
+	if !c.dropsBehind() {
+		return -1
+	}
+	return int((playhead - c.StreamingConfig().DropBehindBytes) / pieceLength)
+}
+
+// waitMetadata waits up to the MetadataTimeout for the torrent info, and
+// checks it.
+func (c *Client) waitMetadata() error {
+	select {
+	case <-c.Torrent.GotInfo():
+	case <-time.After(c.Config.MetadataTimeout):
+		return ClientError{Type: "fetching torrent metadata", Origin: ErrMetadataTimeout}
+	}
+
+	return c.checkInfo(c.Torrent.Info())
+}
+
+// isRemoteTorrent checks a torrent path is a magnet, an infohash or an http
+// url, rather than a local file.
+func isRemoteTorrent(torrentPath string) bool {
+	return strings.HasPrefix(torrentPath, "magnet:") || isInfoHash.MatchString(torrentPath) || isHTTP.MatchString(torrentPath)
+}
+
+// torrentSpec resolves a magnet url, torrent file or torrent url to the spec
+// of its torrent, without adding it. The metainfo is the torrent file's, nil
+// for magnets.
+func torrentSpec(torrentPath, expectedInfoHash string) (spec *torrent.TorrentSpec, metaInfo *metainfo.MetaInfo, err error) {
+	// A bare infohash is added as a magnet.
+	if isInfoHash.MatchString(torrentPath) {
+		torrentPath = "magnet:?xt=urn:btih:" + torrentPath
+	}
+
 	// Add as magnet url.
 	if strings.HasPrefix(torrentPath, "magnet:") {
-		if t, err = c.AddMagnet(torrentPath); err != nil {
-			return client, ClientError{Type: "adding torrent", Origin: err}
+		if spec, err = torrent.TorrentSpecFromMagnetUri(torrentPath); err != nil {
+			return nil, nil, ClientError{Type: "adding torrent", Origin: err}
 		}
 	} else {
 		// Otherwise add as a torrent file.
@@ -71,39 +748,111 @@ func NewClient(torrentPath string, port int, seed bool) (client Client, err erro
 		// If it's online, we try downloading the file.
 		if isHTTP.MatchString(torrentPath) {
 			if torrentPath, err = downloadFile(torrentPath); err != nil {
-				return client, ClientError{Type: "downloading torrent file", Origin: err}
+				if _, ok := err.(ClientError); ok {
+					return nil, nil, err
+				}
+				return nil, nil, ClientError{Type: "downloading torrent file", Origin: err}
 			}
 		}
 
 		// Check if the file exists.
 		if _, err = os.Stat(torrentPath); err != nil {
-			return client, ClientError{Type: "file not found", Origin: err}
+			return nil, nil, ClientError{Type: "file not found", Origin: err}
 		}
 
-		if t, err = c.AddTorrentFromFile(torrentPath); err != nil {
-			return client, ClientError{Type: "adding torrent to the client", Origin: err}
+		if metaInfo, err = metainfo.LoadFromFile(torrentPath); err != nil {
+			return nil, nil, ClientError{Type: "adding torrent to the client", Origin: err}
 		}
+		spec = torrent.TorrentSpecFromMetaInfo(metaInfo)
 	}
 
-	client.Torrent = t
+	// Mirrors of torrent files can't be trusted to serve the right one.
+	if expectedInfoHash != "" && !strings.EqualFold(spec.InfoHash.HexString(), expectedInfoHash) {
+		return nil, nil, ClientError{
+			Type:   "infohash mismatch",
+			Origin: fmt.Errorf("expected %s, got %s", strings.ToLower(expectedInfoHash), spec.InfoHash.HexString()),
+		}
+	}
 
-	go func() {
-		<-t.GotInfo()
-		t.DownloadAll()
+	return spec, metaInfo, nil
+}
 
-		// Prioritize first 5% of the file.
-		for i := 0; i < len(t.Pieces)/100*5; i++ {
-			t.Pieces[i].Priority = torrent.PiecePriorityReadahead
-		}
-	}()
+// addTorrentSpec adds a resolved torrent spec to a client.
+func addTorrentSpec(c *torrent.Client, spec *torrent.TorrentSpec) (t *torrent.Torrent, added bool, err error) {
+	if t, added, err = c.AddTorrentSpec(spec); err != nil {
+		return t, false, ClientError{Type: "adding torrent to the client", Origin: err}
+	}
 
 	return
 }
 
-// Close cleans up the connections.
+// AddTorrent adds another torrent next to the streamed one. Adding a torrent
+// that's already in the client, even from a different magnet or file, merges
+// its trackers into the existing torrent and returns it instead. Past the
+// MaxTorrents, it fails with ErrTooManyTorrents unless EvictTorrents is set.
+// Only magnets, infohashes and http urls are accepted.
+func (c *Client) AddTorrent(torrentPath string) (*torrent.Torrent, error) {
+	t, added, err := c.addRemoteTorrent(torrentPath)
+	if err == nil && !added {
+		log.Printf("%s was already added, merged its trackers\n", t.Name())
+	}
+	if err == nil && added {
+		go c.checkReceivedInfo(t)
+		c.applyUploading(t)
+		err = c.enforceMaxTorrents(t)
+	}
+
+	return t, err
+}
+
+// addRemoteTorrent adds a magnet, infohash or http url to the client, once
+// its spec passes checkSpec.
+func (c *Client) addRemoteTorrent(torrentPath string) (t *torrent.Torrent, added bool, err error) {
+	if !isRemoteTorrent(torrentPath) {
+		return nil, false, ClientError{Type: "adding torrent", Origin: ErrNotRemote}
+	}
+
+	spec, _, err := torrentSpec(torrentPath, "")
+	if err == nil {
+		err = c.checkSpec(spec)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return addTorrentSpec(c.Client, spec)
+}
+
+// Close cleans up the connections, and stops the background loops.
 func (c *Client) Close() {
+	c.closeOnce.Do(func() { close(c.closing) })
+	if c.prioritizing != nil {
+		c.prioritizing.Close()
+	}
+
+	if c.Config.PersistPriorities {
+		c.savePriorities()
+	}
+
 	c.Torrent.Drop()
 	c.Client.Close()
+	// The library doesn't close the storage it's given.
+	if c.completion != nil {
+		if err := c.completion.Close(); err != nil {
+			log.Printf("Error saving piece completion: %s\n", err)
+		}
+	}
+
+	c.unlockData()
+}
+
+// formatSpeed formats a speed in bytes per second in the configured unit.
+func (c *Client) formatSpeed(bytesPerSecond int64) string {
+	if c.Config.SpeedInBits {
+		return humanize.SI(float64(bytesPerSecond*8), "bps")
+	}
+
+	return humanize.Bytes(uint64(bytesPerSecond)) + "/s"
 }
 
 // Render outputs the command line interface for the client.
@@ -111,58 +860,203 @@ func (c *Client) Render() {
 	t := c.Torrent
 
 	var currentProgress = t.BytesCompleted()
-	speed := humanize.Bytes(uint64(currentProgress-c.Progress)) + "/s"
+	speed := c.formatSpeed(currentProgress - c.Progress)
+	c.mutex.Lock()
+	c.downloadSpeed = currentProgress - c.Progress
+	c.mutex.Unlock()
+	c.observeSpeed(currentProgress - c.Progress)
 	c.Progress = currentProgress
 
 	complete := humanize.Bytes(uint64(currentProgress))
 	size := humanize.Bytes(uint64(t.Length()))
 
-	print(clearScreen)
-	fmt.Println(t.Name())
-	fmt.Println("=============================================================")
+	width := c.renderWidth()
+
+	out := &bytes.Buffer{}
+	fmt.Fprintln(out, truncate(t.Name(), width))
+	if c.Config.Verbose {
+		c.renderMetaInfo(out)
+	}
+	fmt.Fprintln(out, truncate("=============================================================", width))
+	if err := c.Err(); err != nil {
+		fmt.Fprintf(out, "Error: \t\t%s\n", err)
+	}
 	if c.ReadyForPlayback() {
-		fmt.Printf("Stream: \thttp://localhost:%d\n", c.Port)
+		fmt.Fprintf(out, "Stream: \thttp://%s\n", c.advertisedHost(nil))
+	} else if eta := c.ReadyETA(); eta != UnknownETA {
+		fmt.Fprintf(out, "Buffering, ready in ~%s\n", eta)
 	}
 
+	if c.isChecking() {
+		fmt.Fprintf(out, "Checking: \t%.2f%%\n", c.checkingProgress())
+	}
 	if currentProgress > 0 {
-		fmt.Printf("Progress: \t%s / %s  %.2f%%\n", complete, size, c.percentage())
+		fmt.Fprintf(out, "Progress: \t%s / %s  %.2f%%\n", complete, size, c.percentage())
 	}
 	if currentProgress < t.Length() {
-		fmt.Printf("Download speed: %s\n", speed)
+		fmt.Fprintf(out, "Download speed: %s\n", speed)
 	}
-	fmt.Printf("Connections: \t%d\n", len(t.Conns))
-	//fmt.Printf("%s\n", c.RenderPieces())
+	fmt.Fprintf(out, "Connections: \t%d\n", len(t.PeerConns()))
+	if swarm := c.SwarmHealth(); swarm.Trackers > 0 {
+		fmt.Fprintf(out, "Swarm: \t\t%s\n", swarm)
+	}
+	if c.Config.Verbose {
+		fmt.Fprintf(out, "Peers: \t\t%s\n", c.ConnectionStats())
+		fmt.Fprintf(out, "%s\n", c.RenderPieces(width))
+	}
+
+	// Redrawing the same screen only flickers.
+	screen := out.String()
+	if !c.Config.RenderOnChange || screen != c.lastRender {
+		fmt.Fprint(c.output(), clearScreen+screen)
+		c.lastRender = screen
+	}
+
+	c.notifyReady(c.output())
 }
 
-func (c Client) getLargestFile() *torrent.File {
-	var target torrent.File
-	var maxSize int64
+// output returns the writer the cli is rendered to.
+func (c *Client) output() io.Writer {
+	if c.Config.Output == nil {
+		return os.Stdout
+	}
+	return c.Config.Output
+}
+
+// renderWidth returns the number of columns available for the cli output.
+func (c *Client) renderWidth() int {
+	if c.Config.RenderWidth > 0 {
+		return c.Config.RenderWidth
+	}
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultRenderWidth
+	}
+	return width
+}
+
+// truncate shortens a line to fit in width characters.
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	if width <= 3 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+// renderMetaInfo outputs the comment and creation details of the torrent
+// file, which magnets don't have.
+func (c *Client) renderMetaInfo(out io.Writer) {
+	metaInfo := c.metaInfo
+	if metaInfo == nil {
+		return
+	}
+
+	if metaInfo.Comment != "" {
+		fmt.Fprintf(out, "Comment: \t%s\n", metaInfo.Comment)
+	}
+	if metaInfo.CreatedBy != "" {
+		fmt.Fprintf(out, "Created by: \t%s\n", metaInfo.CreatedBy)
+	}
+	if metaInfo.CreationDate != 0 {
+		fmt.Fprintf(out, "Created on: \t%s\n", time.Unix(metaInfo.CreationDate, 0).Format("2006-01-02 15:04:05"))
+	}
+}
+
+// files returns the files of the torrent. They're listed once after the info
+// is received, so huge torrents aren't scanned over and over.
+func (c *Client) files() []*torrent.File {
+	if !c.infoReady() {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fileCache == nil {
+		c.fileCache = c.Torrent.Files()
+	}
+
+	return c.fileCache
+}
+
+// selectedFile returns the streamed file: the one at FileIndex, the one picked
+// by the FileSelector, or the largest one.
+func (c *Client) selectedFile() *torrent.File {
+	files := c.files()
+	if index := c.selectedIndex(); index >= 0 {
+		return files[index]
+	}
+
+	return &torrent.File{}
+}
+
+// selectedIndex returns the index of the streamed file, or -1 before the
+// torrent info is known.
+func (c *Client) selectedIndex() int {
+	files := c.files()
+	c.mutex.Lock()
+	selected := c.Config.FileIndex
+	c.mutex.Unlock()
+	if selected >= 0 && selected < len(files) {
+		return selected
+	}
+
+	// The selector is only asked once, so the streamed file doesn't change
+	// under the players.
+	if c.Config.FileSelector != nil && len(files) > 0 {
+		if selected = c.Config.FileSelector(c.ListFiles()); selected >= 0 && selected < len(files) {
+			c.mutex.Lock()
+			c.Config.FileIndex = selected
+			c.mutex.Unlock()
+			return selected
+		}
+		log.Printf("File selector picked invalid file %d, streaming the largest file\n", selected)
+	}
 
-	for _, file := range c.Torrent.Files() {
-		if maxSize < file.Length() {
-			maxSize = file.Length()
-			target = file
+	index := -1
+	var maxSize int64
+	for i := range files {
+		if maxSize < files[i].Length() {
+			maxSize = files[i].Length()
+			index = i
 		}
 	}
 
-	return &target
+	return index
 }
 
-/*
-func (c Client) RenderPieces() (output string) {
-	for i := range c.Torrent.Pieces {
-		piece := c.Torrent.Pieces[i]
+// RenderPieces outputs the state of the pieces, scaled down to fit in width
+// characters.
+func (c *Client) RenderPieces(width int) (output string) {
+	pieces := c.Torrent.NumPieces()
+	if pieces == 0 || width <= 0 {
+		return
+	}
+	if width > pieces {
+		width = pieces
+	}
+
+	for column := 0; column < width; column++ {
+		var partial, checking bool
+		complete := true
 
-		if piece.PublicPieceState.Priority == torrent.PiecePriorityReadahead {
-			output += "!"
+		for i := column * pieces / width; i < (column+1)*pieces/width; i++ {
+			state := c.Torrent.PieceState(i)
+			partial = partial || state.Partial || state.Complete
+			checking = checking || pieceChecking(state)
+			complete = complete && state.Complete
 		}
 
-		if piece.PublicPieceState.Partial {
-			output += "P"
-		} else if piece.PublicPieceState.Checking {
+		if checking {
 			output += "c"
-		} else if piece.PublicPieceState.Complete {
+		} else if complete {
 			output += "d"
+		} else if partial {
+			output += "P"
 		} else {
 			output += "_"
 		}
@@ -170,22 +1064,137 @@ func (c Client) RenderPieces() (output string) {
 
 	return
 }
-*/
+
+// fileName returns the path of a file within the torrent. Some single-file
+// torrents leave the file path empty, so we fall back to the torrent name.
+func (c *Client) fileName(f *torrent.File) string {
+	return torrentFileName(c.Torrent, f)
+}
+
+func torrentFileName(t *torrent.Torrent, f *torrent.File) string {
+	name := f.DisplayPath()
+	if name == "" || name == "." || name == "/" {
+		return t.Name()
+	}
+	return name
+}
+
+// servedName returns the name a file is served as, adding the default
+// extension when the file has none so players can tell its format.
+func (c *Client) servedName(f *torrent.File) string {
+	name := c.fileName(f)
+	if filepath.Ext(name) == "" && c.Config.DefaultExtension != "" {
+		name += normalizeExtension(c.Config.DefaultExtension)
+	}
+	return name
+}
+
+// Trackers returns the announce list of the torrent, tier by tier, including
+// the trackers added at runtime.
+func (c *Client) Trackers() [][]string {
+	if !c.infoReady() {
+		return nil
+	}
+	metaInfo := c.Torrent.Metainfo()
+
+	if len(metaInfo.AnnounceList) == 0 && metaInfo.Announce != "" {
+		return [][]string{{metaInfo.Announce}}
+	}
+
+	return metaInfo.AnnounceList
+}
+
+// GetTrackers is an http handler listing the trackers of the torrent.
+func (c *Client) GetTrackers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Trackers()); err != nil {
+		log.Printf("Error encoding trackers: %s\n", err)
+	}
+}
+
+// Magnet returns a magnet link to share the torrent, whether it was added
+// from a magnet or a torrent file.
+func (c *Client) Magnet() string {
+	magnet := metainfo.Magnet{
+		InfoHash:    c.Torrent.InfoHash(),
+		DisplayName: c.Torrent.Name(),
+	}
+
+	// Magnet links have no tiers, so the trackers are listed tier by tier.
+	for _, tier := range c.Trackers() {
+		magnet.Trackers = append(magnet.Trackers, tier...)
+	}
+
+	return magnet.String()
+}
+
+// GetMagnet is an http handler returning the magnet link of the torrent.
+func (c *Client) GetMagnet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, c.Magnet())
+}
+
+// readyPercentage is the default ReadyPercentage.
+const readyPercentage = 5
 
 // ReadyForPlayback checks if the torrent is ready for playback or not.
-// we wait until 5% of the torrent to start playing.
-func (c Client) ReadyForPlayback() bool {
-	return c.percentage() > 5
+// we wait until ReadyPercentage of the torrent to start playing.
+func (c *Client) ReadyForPlayback() bool {
+	return c.readyFor(c.Torrent.BytesCompleted(), c.Torrent.Length())
+}
+
+// readyFor checks if more than the ReadyPercentage of length is completed.
+func (c *Client) readyFor(completed, length int64) bool {
+	return length > 0 && float64(completed)/float64(length)*100 > c.StreamingConfig().ReadyPercentage
 }
 
 // GetFile is an http handler to serve the biggest file managed by the client.
-func (c Client) GetFile(w http.ResponseWriter, r *http.Request) {
-	target := c.getLargestFile()
-	entry, err := NewFileReader(c, target)
+func (c *Client) GetFile(w http.ResponseWriter, r *http.Request) {
+	// Show players why there's nothing to play, rather than an empty file.
+	if err := c.Err(); err != nil && (c.Config.Fallback || c.Config.FallbackAsset != "") {
+		c.serveFallback(w, r, err)
+		return
+	}
+
+	c.serveFile(w, r, c.selectedFile())
+}
+
+// serveFile streams a file of the torrent.
+func (c *Client) serveFile(w http.ResponseWriter, r *http.Request, target *torrent.File) {
+	c.streamStarted()
+	defer c.streamEnded()
+
+	name := c.servedName(target)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filepath.Base(name)+"\"")
+	if c.Config.ResponseBufferSize > 0 {
+		w = bufferedResponseWriter{ResponseWriter: w, size: c.Config.ResponseBufferSize}
+	}
+
+	// Completed files are read from disk directly, skipping the pieces.
+	if c.Config.DirectCompleted && c.piecesComplete(target) {
+		if file := c.openCompleted(target); file != nil {
+			defer func() {
+				if err := file.Close(); err != nil {
+					log.Printf("Error closing completed file: %s\n", err)
+				}
+			}()
+			http.ServeContent(w, r, name, time.Now(), file)
+			return
+		}
+	}
+
+	newReader := NewFileReader
+	if c.Config.ProgressiveDownload && r.Header.Get("Range") == "" {
+		// Players downloading the whole file won't seek, so we can read
+		// further ahead.
+		newReader = NewProgressiveFileReader
+	}
+	entry, err := newReader(c, target)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	entry.SetContext(r.Context())
 
 	defer func() {
 		if err := entry.Close(); err != nil {
@@ -193,23 +1202,104 @@ func (c Client) GetFile(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	w.Header().Set("Content-Disposition", "attachment; filename=\""+c.Torrent.Name()+"\"")
-	http.ServeContent(w, r, target.DisplayPath(), time.Now(), entry)
+	http.ServeContent(w, r, name, time.Now(), entry)
+}
+
+// openCompleted opens a completed file on disk, or returns nil when it isn't
+// there in full.
+func (c *Client) openCompleted(target *torrent.File) *os.File {
+	file, err := os.Open(c.filePath(target))
+	if err != nil {
+		log.Printf("Error opening completed file: %s\n", err)
+		return nil
+	}
+
+	if info, err := file.Stat(); err != nil || info.Size() != target.Length() {
+		file.Close()
+		return nil
+	}
+
+	c.mutex.Lock()
+	c.directReads++
+	c.mutex.Unlock()
+	return file
+}
+
+// waitInfo waits for the torrent info, returning false if the client is
+// closed first.
+func (c *Client) waitInfo() bool {
+	select {
+	case <-c.Torrent.GotInfo():
+		return true
+	case <-c.closing:
+		return false
+	}
+}
+
+// sleep pauses the background loops, returning false if the client is closed
+// meanwhile.
+func (c *Client) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-c.closing:
+		return false
+	}
 }
 
-func (c Client) percentage() float64 {
+// infoReady reports whether the torrent metadata has been received.
+func (c *Client) infoReady() bool {
+	select {
+	case <-c.Torrent.GotInfo():
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) percentage() float64 {
+	if c.Torrent.Length() <= 0 {
+		return 0
+	}
 	return float64(c.Torrent.BytesCompleted()) / float64(c.Torrent.Length()) * 100
 }
 
+// downloadAttempts is how many times an incomplete torrent download is tried.
+const downloadAttempts = 3
+
+const errorIncompleteDownload = "incomplete torrent download"
+
 func downloadFile(URL string) (fileName string, err error) {
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		fileName, err = downloadFileAttempt(URL)
+		if clientError, ok := err.(ClientError); !ok || clientError.Type != errorIncompleteDownload {
+			return
+		}
+		log.Printf("Attempt %d of %d failed: %s", attempt, downloadAttempts, err)
+	}
+
+	return
+}
+
+func downloadFileAttempt(URL string) (fileName string, err error) {
 	var file *os.File
 	if file, err = ioutil.TempFile(os.TempDir(), "torrent-imageviewer"); err != nil {
 		return
 	}
 
 	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("Error closing torrent file: %s", err)
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Error closing torrent file: %s", closeErr)
+		}
+
+		// Don't leave partial downloads behind.
+		if err != nil {
+			if removeErr := os.Remove(file.Name()); removeErr != nil {
+				log.Printf("Error removing torrent file: %s", removeErr)
+			}
 		}
 	}()
 
@@ -224,7 +1314,87 @@ func downloadFile(URL string) (fileName string, err error) {
 		}
 	}()
 
-	_, err = io.Copy(file, response.Body)
+	written, err := io.Copy(file, response.Body)
+
+	// The server closed the connection before sending everything it announced.
+	if err == io.ErrUnexpectedEOF || (err == nil && response.ContentLength >= 0 && written != response.ContentLength) {
+		return "", ClientError{
+			Type:   errorIncompleteDownload,
+			Origin: fmt.Errorf("received %d of %d bytes", written, response.ContentLength),
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// Some mirrors serve gzipped torrent files, as .torrent.gz or with a
+	// Content-Encoding the transport didn't decode.
+	if err = gunzipFile(file); err != nil {
+		return "", ClientError{Type: "decompressing torrent file", Origin: err}
+	}
+
+	// Login redirects and error pages come back as html instead.
+	contentType := response.Header.Get("Content-Type")
+	var torrentFile bool
+	if torrentFile, err = isTorrentFile(file, contentType); err != nil {
+		return
+	}
+	if !torrentFile {
+		if contentType == "" {
+			contentType = "no content type"
+		}
+		return "", ClientError{Type: "not a torrent file", Origin: fmt.Errorf("%s returned %s", URL, contentType)}
+	}
 
-	return file.Name(), err
+	return file.Name(), nil
+}
+
+// isTorrentFile checks if a downloaded file is a torrent, from its content
+// type or the bencoded dictionary it starts with.
+func isTorrentFile(file *os.File, contentType string) (bool, error) {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == torrentContentType {
+		return true, nil
+	}
+
+	marker := make([]byte, 1)
+	if _, err := file.ReadAt(marker, 0); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return marker[0] == 'd', nil
+}
+
+// gunzipFile decompresses a file in place if it's gzipped.
+func gunzipFile(file *os.File) error {
+	magic := make([]byte, len(gzipMagic))
+	n, err := file.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if !bytes.Equal(magic[:n], gzipMagic) {
+		return nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if err := reader.Close(); err != nil {
+		return err
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(decompressed, 0)
+	return err
 }
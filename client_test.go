@@ -0,0 +1,828 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// newTestClient returns a client offline, streaming a torrent of the given
+// pieces whose data isn't there.
+func newTestClient(t *testing.T, pieces int) *Client {
+	t.Helper()
+
+	return startTestClient(t, metainfo.Info{
+		Name:        "movie.mkv",
+		PieceLength: testPieceLength,
+		Length:      int64(pieces) * testPieceLength,
+		Pieces:      make([]byte, 20*pieces),
+	}, t.TempDir())
+}
+
+// newSeededTestClient returns a client offline, streaming a torrent of data
+// that's all downloaded.
+func newSeededTestClient(t *testing.T, data []byte) *Client {
+	t.Helper()
+
+	return seededTestClient(t, metainfo.Info{Name: "movie.mkv", Length: int64(len(data))}, data)
+}
+
+// seededTestClient returns a client offline, streaming a torrent with the info
+// whose files hold data, one after the other, all downloaded.
+func seededTestClient(t *testing.T, info metainfo.Info, data []byte) *Client {
+	t.Helper()
+
+	dir := t.TempDir()
+	offset := int64(0)
+	for _, file := range info.UpvertedFiles() {
+		path := filepath.Join(dir, info.Name, filepath.Join(file.Path...))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data[offset:offset+file.Length], 0644); err != nil {
+			t.Fatal(err)
+		}
+		offset += file.Length
+	}
+
+	info.PieceLength = testPieceLength
+	info.Pieces = nil
+	for begin := 0; begin < len(data); begin += testPieceLength {
+		end := begin + testPieceLength
+		if end > len(data) {
+			end = len(data)
+		}
+		hash := sha1.Sum(data[begin:end])
+		info.Pieces = append(info.Pieces, hash[:]...)
+	}
+
+	c := startTestClient(t, info, dir)
+	waitHashed(t, c)
+	return c
+}
+
+// testPieceLength is the piece length of the test torrents.
+const testPieceLength = 16384
+
+func startTestClient(t testing.TB, info metainfo.Info, dataDir string) *Client {
+	t.Helper()
+
+	config := torrent.NewDefaultClientConfig()
+	config.DataDir = dataDir
+	config.ListenPort = 0
+	config.NoDHT = true
+	config.DisableTrackers = true
+	// Other test clients can fetch the torrents.
+	config.Seed = true
+	cl, err := torrent.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cl.Close() })
+
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &torrent.TorrentSpec{}
+	spec.InfoHash = metainfo.HashBytes(infoBytes)
+	spec.InfoBytes = infoBytes
+	tor, _, err := cl.AddTorrentSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-tor.GotInfo():
+	case <-time.After(5 * time.Second):
+		t.Fatal("torrent info not loaded")
+	}
+
+	return &Client{
+		Client:           cl,
+		Torrent:          tor,
+		Config:           ClientConfig{DataDir: dataDir},
+		closing:          make(chan struct{}),
+		readers:          make(map[*FileEntry]struct{}),
+		backgroundPieces: make(map[int]struct{}),
+		overrides:        make(map[int]torrent.PiecePriority),
+		lastStreamed:     make(map[metainfo.Hash]time.Time),
+		pieceTimes:       newPieceTimer(),
+		eventSubscribers: make(map[chan statsEvent]struct{}),
+		now:              time.Now,
+		torrentPriority:  TorrentPriorityNormal,
+	}
+}
+
+func TestMaxPiecesAhead(t *testing.T) {
+	c := newTestClient(t, 16)
+	c.Config.MaxPiecesAhead = 3
+	waitHashed(t, c)
+	c.prioritize()
+
+	tests := []struct {
+		playhead int64
+		wanted   []int
+	}{
+		{0, []int{0, 1, 2, 3}},
+		{5*testPieceLength + 100, []int{5, 6, 7, 8}},
+		{14 * testPieceLength, []int{14, 15}},
+	}
+	for _, test := range tests {
+		c.setPlayhead(test.playhead)
+
+		var wanted []int
+		for i := 0; i < c.Torrent.NumPieces(); i++ {
+			if c.Torrent.PieceState(i).Priority != torrent.PiecePriorityNone {
+				wanted = append(wanted, i)
+			}
+		}
+		if !reflect.DeepEqual(wanted, test.wanted) {
+			t.Errorf("pieces wanted at %d = %v, want %v", test.playhead, wanted, test.wanted)
+		}
+	}
+
+	// The readers don't read ahead past the cap either.
+	entry := &FileEntry{File: c.selectedFile(), client: c, readaheadPercentage: 100}
+	if got, want := entry.normalReadahead(), int64(3*testPieceLength); got != want {
+		t.Errorf("readahead = %d, want the %d of MaxPiecesAhead", got, want)
+	}
+}
+
+func TestRenderMetaInfo(t *testing.T) {
+	infoBytes, err := bencode.Marshal(metainfo.Info{Name: "movie.mkv", PieceLength: testPieceLength, Length: 1, Pieces: make([]byte, 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const created = 1500000000
+	path := filepath.Join(t.TempDir(), "movie.torrent")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = (&metainfo.MetaInfo{
+		InfoBytes:    infoBytes,
+		Comment:      "Ripped in 2017",
+		CreatedBy:    "mktorrent 1.1",
+		CreationDate: created,
+	}).Write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		torrentPath string
+		want        string
+	}{
+		{path, "Comment: \tRipped in 2017\nCreated by: \tmktorrent 1.1\nCreated on: \t" + time.Unix(created, 0).Format("2006-01-02 15:04:05") + "\n"},
+		{"magnet:?xt=urn:btih:" + metainfo.HashBytes(infoBytes).HexString(), ""},
+	}
+	for _, test := range tests {
+		_, metaInfo, err := torrentSpec(test.torrentPath, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := &Client{metaInfo: metaInfo}
+		out := &bytes.Buffer{}
+		c.renderMetaInfo(out)
+		if got := out.String(); got != test.want {
+			t.Errorf("renderMetaInfo() of %s = %q, want %q", test.torrentPath, got, test.want)
+		}
+	}
+}
+
+func TestWaitMetadata(t *testing.T) {
+	c := newTestClient(t, 1)
+	c.Config.MetadataTimeout = 50 * time.Millisecond
+	if err := c.waitMetadata(); err != nil {
+		t.Errorf("waitMetadata() with the info = %v, want nil", err)
+	}
+
+	// Nobody has the info of this magnet.
+	spec, _, err := torrentSpec("magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Torrent, _, err = addTorrentSpec(c.Client, spec); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = c.waitMetadata()
+	if clientError, ok := err.(ClientError); !ok || clientError.Origin != ErrMetadataTimeout {
+		t.Errorf("waitMetadata() without the info = %v, want %v", err, ErrMetadataTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < c.Config.MetadataTimeout || elapsed > 5*time.Second {
+		t.Errorf("waitMetadata() gave up after %s, want %s", elapsed, c.Config.MetadataTimeout)
+	}
+}
+
+func TestTrackers(t *testing.T) {
+	c := newTestClient(t, 1)
+	tiers := [][]string{
+		{"udp://tracker.example.com:1337/announce", "http://backup.example.com/announce"},
+		{"udp://tracker.example.org:6969/announce"},
+	}
+	c.Torrent.AddTrackers(tiers)
+
+	if got := c.Trackers(); !reflect.DeepEqual(got, tiers) {
+		t.Errorf("Trackers() = %v, want %v", got, tiers)
+	}
+
+	w := httptest.NewRecorder()
+	c.GetTrackers(w, httptest.NewRequest("GET", "/trackers", nil))
+	var got [][]string
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tiers) {
+		t.Errorf("GET /trackers = %v, want %v", got, tiers)
+	}
+}
+
+func TestFileNameFallsBackToTorrentName(t *testing.T) {
+	data := []byte("the whole movie")
+	c := newSeededTestClient(t, data)
+	if got := c.fileName(c.selectedFile()); got != "movie.mkv" {
+		t.Errorf("fileName() of a single-file torrent = %q, want %q", got, "movie.mkv")
+	}
+
+	w := httptest.NewRecorder()
+	c.GetFile(w, httptest.NewRequest("GET", "/", nil))
+	if got, want := w.Header().Get("Content-Disposition"), `attachment; filename="movie.mkv"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+	if !bytes.Equal(w.Body.Bytes(), data) {
+		t.Errorf("GET / = %q, want %q", w.Body, data)
+	}
+
+	// A file without a path is named after the torrent.
+	c = startTestClient(t, metainfo.Info{
+		Name:        "Movie",
+		PieceLength: testPieceLength,
+		Pieces:      make([]byte, 20),
+		Files:       []metainfo.FileInfo{{Length: 100}},
+	}, t.TempDir())
+	if got := c.fileName(c.files()[0]); got != "Movie" {
+		t.Errorf("fileName() of a file without a path = %q, want %q", got, "Movie")
+	}
+}
+
+func TestFileSelector(t *testing.T) {
+	info := metainfo.Info{
+		Name: "Show",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"S01E01.mkv"}, Length: 10},
+			{Path: []string{"S01E02.mkv"}, Length: 5},
+			{Path: []string{"extras.mkv"}, Length: 20},
+		},
+	}
+	data := []byte("episode 1.ep 2.and the extras here.")
+
+	tests := []struct {
+		name     string
+		selected int
+		want     string
+	}{
+		{"selected", 1, "ep 2."},
+		{"invalid", 7, "and the extras here."},
+	}
+	for _, test := range tests {
+		c := seededTestClient(t, info, data)
+		c.Config.FileIndex = -1
+		calls := 0
+		c.Config.FileSelector = func(files []FileInfo) int {
+			calls++
+			if len(files) != len(info.Files) {
+				t.Errorf("%s: FileSelector() got %d files, want %d", test.name, len(files), len(info.Files))
+			}
+			return test.selected
+		}
+
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			c.GetFile(w, httptest.NewRequest("GET", "/", nil))
+			if got := w.Body.String(); got != test.want {
+				t.Errorf("%s: GET / = %q, want %q", test.name, got, test.want)
+			}
+		}
+		if test.name == "selected" && calls != 1 {
+			t.Errorf("%s: FileSelector() called %d times, want once", test.name, calls)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{"Connections: 4", 20, "Connections: 4"},
+		{"Connections: 4", 14, "Connections: 4"},
+		{"Connections: 4", 10, "Connect..."},
+		{"Épisode 1", 5, "Ép..."},
+		{"Connections: 4", 3, "Con"},
+	}
+
+	for _, test := range tests {
+		if got := truncate(test.line, test.width); got != test.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.line, test.width, got, test.want)
+		}
+	}
+}
+
+func TestRenderPiecesFitsWidth(t *testing.T) {
+	c := newSeededTestClient(t, make([]byte, 4*testPieceLength))
+	c.Config.RenderWidth = 2
+	if got := c.RenderPieces(c.renderWidth()); got != "dd" {
+		t.Errorf("RenderPieces(2) of a downloaded torrent = %q, want %q", got, "dd")
+	}
+
+	c = newTestClient(t, 8)
+	waitHashed(t, c)
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{4, "____"},
+		{20, "________"},
+		{0, ""},
+	}
+	for _, test := range tests {
+		if got := c.RenderPieces(test.width); got != test.want {
+			t.Errorf("RenderPieces(%d) = %q, want %q", test.width, got, test.want)
+		}
+	}
+}
+
+// newManyFilesTestClient returns a client offline, streaming a torrent of
+// many small files.
+func newManyFilesTestClient(t testing.TB, files int) *Client {
+	t.Helper()
+
+	info := metainfo.Info{Name: "Archive", PieceLength: testPieceLength}
+	for i := 0; i < files; i++ {
+		info.Files = append(info.Files, metainfo.FileInfo{Path: []string{fmt.Sprintf("file%05d.mkv", i)}, Length: 100})
+	}
+	pieces := (int64(files)*100 + testPieceLength - 1) / testPieceLength
+	info.Pieces = make([]byte, 20*pieces)
+
+	return startTestClient(t, info, t.TempDir())
+}
+
+func TestFilesCached(t *testing.T) {
+	c := newManyFilesTestClient(t, 2000)
+
+	files := c.files()
+	if len(files) != 2000 {
+		t.Fatalf("files() = %d files, want 2000", len(files))
+	}
+	if again := c.files(); &again[0] != &files[0] {
+		t.Error("files() listed the files again, want the cached list")
+	}
+
+	if err := c.SelectFileByPattern(`file01234\.mkv$`); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.selectedIndex(); got != 1234 {
+		t.Errorf("selected file %d, want 1234", got)
+	}
+	if again := c.files(); &again[0] != &files[0] {
+		t.Error("selecting a file listed the files again, want the cached list")
+	}
+}
+
+func BenchmarkSelectFileByPattern(b *testing.B) {
+	c := newManyFilesTestClient(b, 5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.SelectFileByPattern(`file04999\.mkv$`); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDefaultExtension(t *testing.T) {
+	tests := []struct {
+		name      string
+		extension string
+		want      string
+	}{
+		{"movie", "", "movie"},
+		{"movie", "mp4", "movie.mp4"},
+		{"movie", ".MP4", "movie.mp4"},
+		{"movie.mkv", ".mp4", "movie.mkv"},
+	}
+
+	for _, test := range tests {
+		c := seededTestClient(t, metainfo.Info{Name: test.name, Length: 4}, []byte("data"))
+		c.Config.DefaultExtension = test.extension
+		if got := c.servedName(c.selectedFile()); got != test.want {
+			t.Errorf("servedName() of %s with %q = %q, want %q", test.name, test.extension, got, test.want)
+		}
+
+		w := httptest.NewRecorder()
+		c.GetFile(w, httptest.NewRequest("GET", "/", nil))
+		if got, want := w.Header().Get("Content-Disposition"), `attachment; filename="`+test.want+`"`; got != want {
+			t.Errorf("Content-Disposition of %s with %q = %q, want %q", test.name, test.extension, got, want)
+		}
+		if want := mime.TypeByExtension(filepath.Ext(test.want)); want != "" && w.Header().Get("Content-Type") != want {
+			t.Errorf("Content-Type of %s with %q = %q, want %q", test.name, test.extension, w.Header().Get("Content-Type"), want)
+		}
+	}
+}
+
+func TestAddTorrentTwiceMergesTrackers(t *testing.T) {
+	c := newTestClient(t, 1)
+	magnet := "magnet:?xt=urn:btih:" + c.Torrent.InfoHash().HexString() + "&tr=udp%3A%2F%2Ftracker.example.com%3A1337%2Fannounce"
+
+	got, err := c.AddTorrent(magnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != c.Torrent {
+		t.Error("AddTorrent() of the same infohash returned another torrent, want the existing one")
+	}
+	if len(c.Client.Torrents()) != 1 {
+		t.Errorf("client has %d torrents, want 1", len(c.Client.Torrents()))
+	}
+	if trackers := c.Trackers(); !reflect.DeepEqual(trackers, [][]string{{"udp://tracker.example.com:1337/announce"}}) {
+		t.Errorf("Trackers() = %v, want the tracker of the magnet merged", trackers)
+	}
+}
+
+func TestNewClientWithoutTorrent(t *testing.T) {
+	for _, torrentPath := range []string{"", "  "} {
+		cfg := NewClientConfig()
+		cfg.TorrentPath = torrentPath
+		_, err := NewClient(cfg)
+
+		clientError, ok := err.(ClientError)
+		if !ok || clientError.Type != "no torrent specified" || clientError.Origin != ErrNoTorrent {
+			t.Errorf("NewClient(%q) = %v, want %v", torrentPath, err, ClientError{Type: "no torrent specified", Origin: ErrNoTorrent})
+		}
+	}
+}
+
+func TestDropBehind(t *testing.T) {
+	tests := []struct {
+		name   string
+		seed   bool
+		wanted []int
+	}{
+		{"streaming", false, []int{4, 5, 6, 7, 8, 9, 10, 11}},
+		{"seeding", true, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+	}
+
+	for _, test := range tests {
+		c := newTestClient(t, 12)
+		waitHashed(t, c)
+		c.Config.DropBehindBytes = 2 * testPieceLength
+		c.Config.Seed = test.seed
+		c.Torrent.DownloadAll()
+		c.setPlayhead(6*testPieceLength + 10)
+
+		var wanted []int
+		for i := 0; i < c.Torrent.NumPieces(); i++ {
+			if c.Torrent.PieceState(i).Priority != torrent.PiecePriorityNone {
+				wanted = append(wanted, i)
+			}
+		}
+		if !reflect.DeepEqual(wanted, test.wanted) {
+			t.Errorf("%s: pieces wanted at piece 6 = %v, want %v", test.name, wanted, test.wanted)
+		}
+	}
+}
+
+// torrentFileBytes returns a bencoded torrent file.
+func torrentFileBytes(t *testing.T) []byte {
+	t.Helper()
+
+	infoBytes, err := bencode.Marshal(metainfo.Info{Name: "movie.mkv", PieceLength: testPieceLength, Length: 1, Pieces: make([]byte, 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bencode.Marshal(metainfo.MetaInfo{InfoBytes: infoBytes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDownloadFileDetectsTruncation(t *testing.T) {
+	data := torrentFileBytes(t)
+
+	tests := []struct {
+		name      string
+		truncated int
+		wantErr   bool
+	}{
+		{"complete", 0, false},
+		{"retried", downloadAttempts - 1, false},
+		{"truncated", downloadAttempts, true},
+	}
+	for _, test := range tests {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			if requests > test.truncated {
+				w.Write(data)
+				return
+			}
+
+			// Close the connection halfway through the body.
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n", len(data))
+			buf.Write(data[:len(data)/2])
+			buf.Flush()
+			conn.Close()
+		}))
+
+		fileName, err := downloadFile(server.URL + "/movie.torrent")
+		server.Close()
+		if test.wantErr {
+			if clientError, ok := err.(ClientError); !ok || clientError.Type != errorIncompleteDownload {
+				t.Errorf("%s: downloadFile() = %v, want an %q error", test.name, err, errorIncompleteDownload)
+			}
+			if want := downloadAttempts; requests != want {
+				t.Errorf("%s: downloaded %d times, want %d", test.name, requests, want)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: downloadFile() = %v", test.name, err)
+			continue
+		}
+		got, err := os.ReadFile(fileName)
+		os.Remove(fileName)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: downloaded %q, %v, want the torrent file", test.name, got, err)
+		}
+	}
+}
+
+func TestMagnetRoundTrips(t *testing.T) {
+	c := newTestClient(t, 1)
+	c.Torrent.AddTrackers([][]string{
+		{"udp://tracker.example.com:1337/announce", "http://backup.example.com/announce"},
+		{"udp://tracker.example.org:6969/announce"},
+	})
+
+	w := httptest.NewRecorder()
+	c.GetMagnet(w, httptest.NewRequest("GET", "/magnet", nil))
+	magnet, err := metainfo.ParseMagnetUri(strings.TrimSpace(w.Body.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trackers := []string{
+		"udp://tracker.example.com:1337/announce",
+		"http://backup.example.com/announce",
+		"udp://tracker.example.org:6969/announce",
+	}
+	if magnet.InfoHash != c.Torrent.InfoHash() || magnet.DisplayName != "movie.mkv" || !reflect.DeepEqual(magnet.Trackers, trackers) {
+		t.Errorf("GET /magnet parsed back as %s, %q, %v, want %s, %q, %v",
+			magnet.InfoHash, magnet.DisplayName, magnet.Trackers, c.Torrent.InfoHash(), "movie.mkv", trackers)
+	}
+}
+
+func TestFormatSpeed(t *testing.T) {
+	tests := []struct {
+		bytesPerSecond int64
+		bits           bool
+		want           string
+	}{
+		{0, false, "0 B/s"},
+		{1500000, false, "1.5 MB/s"},
+		{0, true, "0 bps"},
+		{1500, true, "12 kbps"},
+		{1500000, true, "12 Mbps"},
+		{12500000, true, "100 Mbps"},
+	}
+
+	for _, test := range tests {
+		c := &Client{}
+		c.Config.SpeedInBits = test.bits
+		if got := c.formatSpeed(test.bytesPerSecond); got != test.want {
+			t.Errorf("formatSpeed(%d) in bits %v = %q, want %q", test.bytesPerSecond, test.bits, got, test.want)
+		}
+	}
+
+	c := newTestClient(t, 1)
+	c.Config.SpeedInBits = true
+	c.downloadSpeed = 1500000
+	if got := c.Stats().Speed; got != "12 Mbps" {
+		t.Errorf("Stats().Speed = %q, want %q", got, "12 Mbps")
+	}
+}
+
+func TestDownloadGzippedTorrent(t *testing.T) {
+	data := torrentFileBytes(t)
+	metaInfo, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write(data)
+	writer.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/encoded.torrent" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Content-Type", torrentContentType)
+		} else {
+			w.Header().Set("Content-Type", "application/gzip")
+		}
+		w.Write(gzipped.Bytes())
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/movie.torrent.gz", "/encoded.torrent"} {
+		spec, _, err := torrentSpec(server.URL+path, "")
+		if err != nil {
+			t.Errorf("torrentSpec(%q) = %v", path, err)
+			continue
+		}
+		if spec.InfoHash != metaInfo.HashInfoBytes() {
+			t.Errorf("torrentSpec(%q) added %s, want %s", path, spec.InfoHash, metaInfo.HashInfoBytes())
+		}
+	}
+}
+
+func TestDownloadFileChecksForATorrent(t *testing.T) {
+	data := torrentFileBytes(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>Please log in</body></html>"))
+		case "/typed.torrent":
+			w.Header().Set("Content-Type", torrentContentType)
+			w.Write(data)
+		default:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	_, err := downloadFile(server.URL + "/login")
+	if clientError, ok := err.(ClientError); !ok || clientError.Type != "not a torrent file" {
+		t.Errorf("downloadFile() of an html page = %v, want a %q error", err, "not a torrent file")
+	}
+
+	for _, path := range []string{"/typed.torrent", "/untyped.torrent"} {
+		fileName, err := downloadFile(server.URL + path)
+		if err != nil {
+			t.Errorf("downloadFile(%q) = %v", path, err)
+			continue
+		}
+		os.Remove(fileName)
+	}
+}
+
+func TestRenderOnChange(t *testing.T) {
+	tests := []struct {
+		onChange bool
+		want     int
+	}{
+		{false, 4},
+		{true, 2},
+	}
+
+	for _, test := range tests {
+		c := newSeededTestClient(t, []byte("the whole movie"))
+		var out bytes.Buffer
+		c.Config.Output = &out
+		c.Config.RenderOnChange = test.onChange
+
+		for i := 0; i < 3; i++ {
+			c.Render()
+		}
+		c.setErr(ClientError{Type: "empty torrent", Origin: ErrEmptyTorrent})
+		c.Render()
+
+		if got := strings.Count(out.String(), clearScreen); got != test.want {
+			t.Errorf("RenderOnChange %v: the screen was drawn %d times, want %d", test.onChange, got, test.want)
+		}
+		if !strings.HasSuffix(out.String(), c.lastRender) || !strings.Contains(c.lastRender, ErrEmptyTorrent.Error()) {
+			t.Errorf("RenderOnChange %v: last screen %q, want the error shown", test.onChange, c.lastRender)
+		}
+	}
+}
+
+func TestTorrentSpecExpectedInfoHash(t *testing.T) {
+	data := torrentFileBytes(t)
+	metaInfo, err := metainfo.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoHash := metaInfo.HashInfoBytes().HexString()
+	path := filepath.Join(t.TempDir(), "movie.torrent")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	const other = "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		torrentPath      string
+		expectedInfoHash string
+		wantErr          bool
+	}{
+		{path, "", false},
+		{path, infoHash, false},
+		{path, strings.ToUpper(infoHash), false},
+		{path, other, true},
+		{other, other, false},
+		{"magnet:?xt=urn:btih:" + infoHash, other, true},
+	}
+	for _, test := range tests {
+		_, _, err := torrentSpec(test.torrentPath, test.expectedInfoHash)
+		clientError, ok := err.(ClientError)
+		if test.wantErr && (!ok || clientError.Type != "infohash mismatch") {
+			t.Errorf("torrentSpec(%q, %q) = %v, want an %q error", test.torrentPath, test.expectedInfoHash, err, "infohash mismatch")
+		}
+		if !test.wantErr && err != nil {
+			t.Errorf("torrentSpec(%q, %q) = %v", test.torrentPath, test.expectedInfoHash, err)
+		}
+	}
+
+	cfg := NewClientConfig()
+	cfg.TorrentPath = path
+	cfg.ExpectedInfoHash = "not a hash"
+	_, err = NewClient(cfg)
+	if clientError, ok := err.(ClientError); !ok || clientError.Type != "parsing expected infohash" {
+		t.Errorf("NewClient() with an invalid expected infohash = %v, want a %q error", err, "parsing expected infohash")
+	}
+}
+
+func TestGetFileReadsCompletedFilesDirectly(t *testing.T) {
+	data := []byte("the whole movie")
+	tests := []struct {
+		direct bool
+		want   int
+	}{
+		{false, 0},
+		{true, 2},
+	}
+
+	for _, test := range tests {
+		c := newSeededTestClient(t, data)
+		c.Config.DirectCompleted = test.direct
+
+		w := httptest.NewRecorder()
+		c.GetFile(w, httptest.NewRequest("GET", "/", nil))
+		if !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("DirectCompleted %v: GET / = %q, want %q", test.direct, w.Body, data)
+		}
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Range", "bytes=4-8")
+		w = httptest.NewRecorder()
+		c.GetFile(w, r)
+		if w.Code != http.StatusPartialContent || w.Body.String() != "whole" {
+			t.Errorf("DirectCompleted %v: GET / of bytes 4-8 = %d %q, want %d %q", test.direct, w.Code, w.Body, http.StatusPartialContent, "whole")
+		}
+
+		if got := c.Stats().DirectReads; got != test.want {
+			t.Errorf("DirectCompleted %v: %d direct reads, want %d", test.direct, got, test.want)
+		}
+	}
+
+	// A file that changed on disk is read through the torrent instead.
+	c := newSeededTestClient(t, data)
+	if err := os.Truncate(c.filePath(c.selectedFile()), 4); err != nil {
+		t.Fatal(err)
+	}
+	if file := c.openCompleted(c.selectedFile()); file != nil {
+		file.Close()
+		t.Error("openCompleted() of a truncated file succeeded, want nil")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+// completionFlushInterval is how long the changes to the bitfields are batched
+// before being written.
+const completionFlushInterval = 5 * time.Second
+
+// stateDir is where the client keeps what it remembers between runs.
+func (cfg ClientConfig) stateDir() string {
+	return filepath.Join(cfg.DataDir, ".peerflix")
+}
+
+// fileStamp is the size and modification time of a data file, telling if it
+// changed since its pieces were verified. A missing file has a Size of -1.
+type fileStamp struct {
+	Path    string
+	Size    int64
+	ModTime int64
+}
+
+// stampFiles stats the data files of a torrent.
+func stampFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		stamps[i] = fileStamp{Path: path, Size: -1}
+		if stat, err := os.Stat(path); err == nil {
+			stamps[i].Size = stat.Size()
+			stamps[i].ModTime = stat.ModTime().UnixNano()
+		}
+	}
+	return stamps
+}
+
+// bitfieldCompletion remembers which pieces were verified in a file per
+// torrent, so they don't have to be hashed again on restart. Next to each
+// bitfield are the stamps of the data files it was saved with, so it's only
+// trusted if they didn't change since.
+type bitfieldCompletion struct {
+	dir       string
+	mutex     sync.Mutex
+	bitfields map[metainfo.Hash][]byte
+	// files are the data files of the torrents opened, stamped on save.
+	files map[metainfo.Hash][]string
+	dirty map[metainfo.Hash]struct{}
+	flush *time.Timer
+}
+
+func newBitfieldCompletion(dir string) *bitfieldCompletion {
+	return &bitfieldCompletion{
+		dir:       dir,
+		bitfields: make(map[metainfo.Hash][]byte),
+		files:     make(map[metainfo.Hash][]string),
+		dirty:     make(map[metainfo.Hash]struct{}),
+	}
+}
+
+func (b *bitfieldCompletion) path(infoHash metainfo.Hash) string {
+	return filepath.Join(b.dir, infoHash.HexString()+".bitfield")
+}
+
+func (b *bitfieldCompletion) stampsPath(infoHash metainfo.Hash) string {
+	return filepath.Join(b.dir, infoHash.HexString()+".files")
+}
+
+// open loads the bitfield of a torrent about to be opened by the storage,
+// dropping it if the data files changed since it was saved.
+func (b *bitfieldCompletion) open(infoHash metainfo.Hash, paths []string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.files[infoHash] = paths
+	if _, ok := b.bitfields[infoHash]; ok {
+		return
+	}
+
+	bitfield := b.bitfield(infoHash)
+	if len(bitfield) == 0 {
+		return
+	}
+
+	var saved []fileStamp
+	data, err := ioutil.ReadFile(b.stampsPath(infoHash))
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Error loading piece completion: %s\n", err)
+	}
+
+	if !reflect.DeepEqual(saved, stampFiles(paths)) {
+		log.Printf("Data of %s changed since it was verified, hashing it again\n", infoHash.HexString())
+		b.bitfields[infoHash] = nil
+	}
+}
+
+// bitfield returns the bitfield of a torrent, loading it on first use.
+func (b *bitfieldCompletion) bitfield(infoHash metainfo.Hash) []byte {
+	bitfield, ok := b.bitfields[infoHash]
+	if !ok {
+		var err error
+		if bitfield, err = ioutil.ReadFile(b.path(infoHash)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error loading piece completion: %s\n", err)
+		}
+		b.bitfields[infoHash] = bitfield
+	}
+	return bitfield
+}
+
+// Get reports a piece as complete if it was verified before. Pieces we know
+// nothing about are hashed by the library.
+func (b *bitfieldCompletion) Get(key metainfo.PieceKey) (storage.Completion, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bitfield := b.bitfield(key.InfoHash)
+	if key.Index/8 >= len(bitfield) {
+		return storage.Completion{}, nil
+	}
+
+	complete := bitfield[key.Index/8]&(1<<uint(key.Index%8)) != 0
+	return storage.Completion{Complete: complete, Ok: complete}, nil
+}
+
+// Set records the completion of a piece. The torrent's bitfield is saved
+// with the other changes within completionFlushInterval.
+func (b *bitfieldCompletion) Set(key metainfo.PieceKey, complete bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bitfield := b.bitfield(key.InfoHash)
+	for key.Index/8 >= len(bitfield) {
+		bitfield = append(bitfield, 0)
+	}
+
+	if complete {
+		bitfield[key.Index/8] |= 1 << uint(key.Index%8)
+	} else {
+		bitfield[key.Index/8] &^= 1 << uint(key.Index%8)
+	}
+	b.bitfields[key.InfoHash] = bitfield
+
+	b.dirty[key.InfoHash] = struct{}{}
+	if b.flush == nil {
+		b.flush = time.AfterFunc(completionFlushInterval, func() {
+			if err := b.save(); err != nil {
+				log.Printf("Error saving piece completion: %s\n", err)
+			}
+		})
+	}
+	return nil
+}
+
+// forget drops the bitfield of a torrent, so its pieces are hashed again if
+// it's ever added back.
+func (b *bitfieldCompletion) forget(infoHash metainfo.Hash) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.bitfields, infoHash)
+	delete(b.files, infoHash)
+	delete(b.dirty, infoHash)
+	for _, path := range []string{b.path(infoHash), b.stampsPath(infoHash)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// save writes the bitfields changed since the last save, with the stamps of
+// their data files.
+func (b *bitfieldCompletion) save() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.flush != nil {
+		b.flush.Stop()
+		b.flush = nil
+	}
+
+	for infoHash := range b.dirty {
+		stamps, err := json.Marshal(stampFiles(b.files[infoHash]))
+		if err != nil {
+			return err
+		}
+		if err := b.write(b.stampsPath(infoHash), stamps); err != nil {
+			return err
+		}
+		if err := b.write(b.path(infoHash), b.bitfields[infoHash]); err != nil {
+			return err
+		}
+		delete(b.dirty, infoHash)
+	}
+	return nil
+}
+
+// write writes a file atomically, so a crash never leaves it half written.
+func (b *bitfieldCompletion) write(path string, data []byte) error {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Close saves the changes not written yet, stamping the data files of every
+// torrent again as they're written to until the end.
+func (b *bitfieldCompletion) Close() error {
+	b.mutex.Lock()
+	for infoHash := range b.files {
+		if len(b.bitfields[infoHash]) > 0 {
+			b.dirty[infoHash] = struct{}{}
+		}
+	}
+	b.mutex.Unlock()
+
+	return b.save()
+}
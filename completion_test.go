@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestBitfieldCompletionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	infoHash := metainfo.HashBytes([]byte("movie"))
+	tests := []struct {
+		index    int
+		complete bool
+	}{
+		{0, true},
+		{1, false},
+		{7, true},
+		{8, true},
+		{21, true},
+		{22, false},
+	}
+
+	b := newBitfieldCompletion(dir)
+	b.open(infoHash, nil)
+	for _, test := range tests {
+		if err := b.Set(metainfo.PieceKey{InfoHash: infoHash, Index: test.index}, test.complete); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(b.path(infoHash)); !os.IsNotExist(err) {
+		t.Errorf("bitfield written before the flush, stat = %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := newBitfieldCompletion(dir)
+	reloaded.open(infoHash, nil)
+	for _, test := range tests {
+		got, err := reloaded.Get(metainfo.PieceKey{InfoHash: infoHash, Index: test.index})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Complete != test.complete || got.Ok != test.complete {
+			t.Errorf("piece %d after reloading = %+v, want complete %v", test.index, got, test.complete)
+		}
+	}
+
+	if got, _ := reloaded.Get(metainfo.PieceKey{InfoHash: infoHash, Index: 100}); got.Ok {
+		t.Errorf("piece past the bitfield = %+v, want it unknown", got)
+	}
+
+	if err := reloaded.forget(infoHash); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := newBitfieldCompletion(dir).Get(metainfo.PieceKey{InfoHash: infoHash, Index: 0}); got.Ok {
+		t.Errorf("piece of a forgotten torrent = %+v, want it unknown", got)
+	}
+}
+
+func TestBitfieldCompletionValidation(t *testing.T) {
+	infoHash := metainfo.HashBytes([]byte("movie"))
+	tests := []struct {
+		name   string
+		change func(path string) error
+		want   bool
+	}{
+		{"unchanged", func(string) error { return nil }, true},
+		{"removed", os.Remove, false},
+		{"truncated", func(path string) error { return os.Truncate(path, 2) }, false},
+		{"modified", func(path string) error {
+			later := time.Now().Add(time.Hour)
+			return os.Chtimes(path, later, later)
+		}, false},
+		{"stamps lost", func(path string) error {
+			return os.Remove(filepath.Join(filepath.Dir(path), "state", infoHash.HexString()+".files"))
+		}, false},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		data := filepath.Join(dir, "movie.mkv")
+		if err := os.WriteFile(data, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		b := newBitfieldCompletion(filepath.Join(dir, "state"))
+		b.open(infoHash, []string{data})
+		if err := b.Set(metainfo.PieceKey{InfoHash: infoHash, Index: 0}, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := test.change(data); err != nil {
+			t.Fatal(err)
+		}
+
+		reloaded := newBitfieldCompletion(filepath.Join(dir, "state"))
+		reloaded.open(infoHash, []string{data})
+		if got, _ := reloaded.Get(metainfo.PieceKey{InfoHash: infoHash, Index: 0}); got.Complete != test.want {
+			t.Errorf("%s: piece complete = %v, want %v", test.name, got.Complete, test.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/anacrolix/torrent"
+)
+
+// ConnectionStats breaks the peer connections down by state.
+type ConnectionStats struct {
+	Total    int
+	Seeds    int
+	Leechers int
+	HalfOpen int
+	Incoming int
+	Outgoing int
+}
+
+// ConnectionStats returns the connections of the torrent by state.
+func (c *Client) ConnectionStats() ConnectionStats {
+	stats := c.Torrent.Stats()
+
+	conns := c.Torrent.PeerConns()
+	sources := make([]torrent.PeerSource, len(conns))
+	for i, conn := range conns {
+		sources[i] = conn.Discovery
+	}
+
+	return connectionStats(stats.ConnectedSeeders, stats.HalfOpenPeers, sources)
+}
+
+// connectionStats counts the connections from how each peer was discovered.
+func connectionStats(seeds, halfOpen int, sources []torrent.PeerSource) ConnectionStats {
+	stats := ConnectionStats{
+		Total:    len(sources),
+		Seeds:    seeds,
+		Leechers: len(sources) - seeds,
+		HalfOpen: halfOpen,
+	}
+
+	for _, source := range sources {
+		if source == torrent.PeerSourceIncoming {
+			stats.Incoming++
+		} else {
+			stats.Outgoing++
+		}
+	}
+
+	return stats
+}
+
+// String summarizes the connections on one line.
+func (s ConnectionStats) String() string {
+	return fmt.Sprintf("%d seeds, %d leechers, %d half-open (%d in / %d out)",
+		s.Seeds, s.Leechers, s.HalfOpen, s.Incoming, s.Outgoing)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestConnectionStats(t *testing.T) {
+	tests := []struct {
+		seeds    int
+		halfOpen int
+		sources  []torrent.PeerSource
+		want     ConnectionStats
+		line     string
+	}{
+		{0, 0, nil, ConnectionStats{}, "0 seeds, 0 leechers, 0 half-open (0 in / 0 out)"},
+		{
+			1, 2,
+			[]torrent.PeerSource{torrent.PeerSourceIncoming, torrent.PeerSourceTracker, torrent.PeerSourceDhtGetPeers},
+			ConnectionStats{Total: 3, Seeds: 1, Leechers: 2, HalfOpen: 2, Incoming: 1, Outgoing: 2},
+			"1 seeds, 2 leechers, 2 half-open (1 in / 2 out)",
+		},
+		{
+			2, 0,
+			[]torrent.PeerSource{torrent.PeerSourceIncoming, torrent.PeerSourceIncoming},
+			ConnectionStats{Total: 2, Seeds: 2, Incoming: 2},
+			"2 seeds, 0 leechers, 0 half-open (2 in / 0 out)",
+		},
+	}
+
+	for _, test := range tests {
+		got := connectionStats(test.seeds, test.halfOpen, test.sources)
+		if got != test.want {
+			t.Errorf("connectionStats(%d, %d, %v) = %+v, want %+v", test.seeds, test.halfOpen, test.sources, got, test.want)
+		}
+		if line := got.String(); line != test.line {
+			t.Errorf("String() = %q, want %q", line, test.line)
+		}
+	}
+}
+
+func TestConnectionStatsOfPeers(t *testing.T) {
+	seeder := newSeededTestClient(t, []byte("a movie"))
+	leecher := newTestClient(t, 1)
+	spec, _, err := torrentSpec(seedingMagnet(seeder), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leecher.Torrent, _, err = addTorrentSpec(leecher.Client, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for seeder.ConnectionStats().Total == 0 || leecher.ConnectionStats().Seeds == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("peers not connected: seeder %s, leecher %s", seeder.ConnectionStats(), leecher.ConnectionStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// They may also be dialing each other back, half-open.
+	if got := seeder.ConnectionStats(); got.Total != 1 || got.Leechers != 1 || got.Incoming != 1 {
+		t.Errorf("seeder ConnectionStats() = %s, want 1 incoming leecher", got)
+	}
+	if got := leecher.ConnectionStats(); got.Total != 1 || got.Seeds != 1 || got.Outgoing != 1 {
+		t.Errorf("leecher ConnectionStats() = %s, want 1 outgoing seed", got)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer coalesces calls made in quick succession, running only the last
+// one once no other call came in for the interval.
+type debouncer struct {
+	interval time.Duration
+
+	mutex  sync.Mutex
+	timer  *time.Timer
+	closed bool
+}
+
+func newDebouncer(interval time.Duration) *debouncer {
+	return &debouncer{interval: interval}
+}
+
+// trigger schedules f, replacing whatever was scheduled before. Nothing is
+// scheduled once the debouncer is closed.
+func (d *debouncer) trigger(f func()) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return
+	}
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.interval, func() {
+		d.mutex.Lock()
+		closed := d.closed
+		d.mutex.Unlock()
+
+		if !closed {
+			f()
+		}
+	})
+}
+
+// Close drops the scheduled call, so nothing runs on a closed client.
+func (d *debouncer) Close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.closed = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncer(t *testing.T) {
+	d := newDebouncer(20 * time.Millisecond)
+	var runs, last int32
+	for i := int32(1); i <= 3; i++ {
+		d.trigger(func() {
+			atomic.AddInt32(&runs, 1)
+			atomic.StoreInt32(&last, i)
+		})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("debounced calls ran %d times, want once", got)
+	}
+	if got := atomic.LoadInt32(&last); got != 3 {
+		t.Errorf("call %d ran, want the last one", got)
+	}
+}
+
+func TestDebouncerClose(t *testing.T) {
+	d := newDebouncer(20 * time.Millisecond)
+	var runs int32
+	run := func() { atomic.AddInt32(&runs, 1) }
+
+	d.trigger(run)
+	d.Close()
+	d.trigger(run)
+
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&runs); got != 0 {
+		t.Errorf("calls ran %d times after closing, want none", got)
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DLNA/UPnP endpoints served next to the stream.
+const (
+	dlnaDevicePath            = "/dlna/device.xml"
+	dlnaContentDirectoryPath  = "/dlna/ContentDirectory.xml"
+	dlnaConnectionManagerPath = "/dlna/ConnectionManager.xml"
+	dlnaControlPath           = "/dlna/control/"
+	dlnaEventPath             = "/dlna/event/"
+)
+
+const (
+	ssdpAddress  = "239.255.255.250:1900"
+	ssdpMaxAge   = 1800
+	ssdpInterval = 5 * time.Minute
+
+	upnpMediaServer       = "urn:schemas-upnp-org:device:MediaServer:1"
+	upnpContentDirectory  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	upnpConnectionManager = "urn:schemas-upnp-org:service:ConnectionManager:1"
+)
+
+// DLNAServer advertises the stream as a UPnP-AV media server, so smart TVs
+// can discover it and pull the file from the http endpoint.
+type DLNAServer struct {
+	client *Client
+	uuid   string
+	conn   *net.UDPConn
+	group  *net.UDPAddr
+	done   chan struct{}
+}
+
+// NewDLNAServer joins the SSDP multicast group for a client.
+func NewDLNAServer(c *Client) (*DLNAServer, error) {
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddress)
+	if err != nil {
+		return nil, ClientError{Type: "resolving ssdp address", Origin: err}
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, ClientError{Type: "joining ssdp multicast group", Origin: err}
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, ClientError{Type: "generating dlna uuid", Origin: err}
+	}
+
+	d := &DLNAServer{
+		client: c,
+		uuid:   uuid,
+		conn:   conn,
+		group:  group,
+		done:   make(chan struct{}),
+	}
+	// The endpoints are served with the client's routes from now on.
+	c.dlna = d
+	return d, nil
+}
+
+// Routes returns the UPnP endpoints, served with the http api.
+func (d *DLNAServer) Routes() []Route {
+	get := []string{http.MethodGet}
+
+	return []Route{
+		{Path: dlnaDevicePath, Methods: get, Summary: "UPnP device description, when advertising to DLNA devices", ContentType: "text/xml", Handler: d.ServeDevice},
+		{Path: dlnaContentDirectoryPath, Methods: get, Summary: "UPnP ContentDirectory service description", ContentType: "text/xml", Handler: d.ServeContentDirectory},
+		{Path: dlnaConnectionManagerPath, Methods: get, Summary: "UPnP ConnectionManager service description", ContentType: "text/xml", Handler: d.ServeConnectionManager},
+		{Path: dlnaControlPath, Methods: []string{http.MethodPost}, Summary: "SOAP actions of the UPnP services", ContentType: "text/xml", SpecPath: dlnaControlPath + "{service}", Handler: d.ServeControl},
+		{Path: dlnaEventPath, Methods: []string{"SUBSCRIBE", "UNSUBSCRIBE"}, Summary: "Event subscriptions of the UPnP services, which never send events", SpecPath: dlnaEventPath + "{service}", Handler: d.ServeEvent},
+	}
+}
+
+// Advertise answers SSDP searches and periodically announces the server
+// until Close is called.
+func (d *DLNAServer) Advertise() {
+	go d.notifyLoop()
+
+	buffer := make([]byte, 2048)
+	for {
+		n, from, err := d.conn.ReadFromUDP(buffer)
+		if err != nil {
+			select {
+			case <-d.done:
+				return
+			default:
+			}
+			log.Printf("Error reading ssdp request: %s\n", err)
+			continue
+		}
+
+		request, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buffer[:n])))
+		if err != nil || request.Method != "M-SEARCH" {
+			continue
+		}
+
+		for _, target := range d.searchTargets(request.Header.Get("St")) {
+			if err := d.respond(from, target); err != nil {
+				log.Printf("Error answering ssdp search: %s\n", err)
+			}
+		}
+	}
+}
+
+// Close says goodbye to the network and stops advertising.
+func (d *DLNAServer) Close() {
+	close(d.done)
+	d.notify("ssdp:byebye")
+	if err := d.conn.Close(); err != nil {
+		log.Printf("Error closing ssdp connection: %s\n", err)
+	}
+}
+
+func (d *DLNAServer) notifyLoop() {
+	ticker := time.NewTicker(ssdpInterval)
+	defer ticker.Stop()
+
+	for {
+		d.notify("ssdp:alive")
+
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *DLNAServer) notify(subType string) {
+	local, err := localAddressFor(d.group)
+	if err != nil {
+		log.Printf("Error finding local address for ssdp: %s\n", err)
+		return
+	}
+
+	for _, target := range d.targets() {
+		message := "NOTIFY * HTTP/1.1\r\n" +
+			"HOST: " + ssdpAddress + "\r\n" +
+			"CACHE-CONTROL: max-age=" + strconv.Itoa(ssdpMaxAge) + "\r\n" +
+			"LOCATION: " + d.location(local) + "\r\n" +
+			"NT: " + target + "\r\n" +
+			"NTS: " + subType + "\r\n" +
+			"SERVER: " + ssdpServer() + "\r\n" +
+			"USN: " + d.usn(target) + "\r\n\r\n"
+
+		if _, err := d.conn.WriteToUDP([]byte(message), d.group); err != nil {
+			log.Printf("Error sending ssdp notify: %s\n", err)
+		}
+	}
+}
+
+func (d *DLNAServer) respond(to *net.UDPAddr, target string) error {
+	local, err := localAddressFor(to)
+	if err != nil {
+		return err
+	}
+
+	message := "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=" + strconv.Itoa(ssdpMaxAge) + "\r\n" +
+		"DATE: " + time.Now().UTC().Format(http.TimeFormat) + "\r\n" +
+		"EXT:\r\n" +
+		"LOCATION: " + d.location(local) + "\r\n" +
+		"SERVER: " + ssdpServer() + "\r\n" +
+		"ST: " + target + "\r\n" +
+		"USN: " + d.usn(target) + "\r\n\r\n"
+
+	_, err = d.conn.WriteToUDP([]byte(message), to)
+	return err
+}
+
+// targets lists everything we announce ourselves as.
+func (d *DLNAServer) targets() []string {
+	return []string{
+		"upnp:rootdevice",
+		"uuid:" + d.uuid,
+		upnpMediaServer,
+		upnpContentDirectory,
+		upnpConnectionManager,
+	}
+}
+
+// searchTargets returns the targets matching an M-SEARCH ST header.
+func (d *DLNAServer) searchTargets(st string) []string {
+	if st == "ssdp:all" {
+		return d.targets()
+	}
+
+	for _, target := range d.targets() {
+		if target == st {
+			return []string{target}
+		}
+	}
+
+	return nil
+}
+
+func (d *DLNAServer) usn(target string) string {
+	if target == "uuid:"+d.uuid {
+		return target
+	}
+	return "uuid:" + d.uuid + "::" + target
+}
+
+func (d *DLNAServer) location(local net.IP) string {
+	return "http://" + net.JoinHostPort(local.String(), strconv.Itoa(d.client.Port)) + dlnaDevicePath
+}
+
+// ServeDevice serves the UPnP root device description.
+func (d *DLNAServer) ServeDevice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	fmt.Fprintf(w, dlnaDeviceTemplate, xmlEscape("go-peerflix: "+d.client.Torrent.Name()), d.uuid)
+}
+
+// ServeContentDirectory serves the ContentDirectory service description.
+func (d *DLNAServer) ServeContentDirectory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	fmt.Fprint(w, dlnaContentDirectorySCPD)
+}
+
+// ServeConnectionManager serves the ConnectionManager service description.
+func (d *DLNAServer) ServeConnectionManager(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	fmt.Fprint(w, dlnaConnectionManagerSCPD)
+}
+
+// ServeEvent accepts event subscriptions. We never change, so nothing is sent.
+func (d *DLNAServer) ServeEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "SUBSCRIBE" && r.Method != "UNSUBSCRIBE" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Method == "SUBSCRIBE" {
+		w.Header().Set("SID", "uuid:"+d.uuid)
+		w.Header().Set("TIMEOUT", "Second-"+strconv.Itoa(ssdpMaxAge))
+	}
+}
+
+// ServeControl handles the SOAP actions of both services.
+func (d *DLNAServer) ServeControl(w http.ResponseWriter, r *http.Request) {
+	soapAction := strings.Trim(r.Header.Get("SOAPACTION"), `"`)
+	separator := strings.LastIndex(soapAction, "#")
+	if separator < 0 {
+		http.Error(w, "invalid soap action", http.StatusBadRequest)
+		return
+	}
+	service, action := soapAction[:separator], soapAction[separator+1:]
+
+	var envelope struct {
+		Body struct {
+			Action struct {
+				ObjectID   string
+				BrowseFlag string
+			} `xml:",any"`
+		}
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result string
+	switch service + "#" + action {
+	case upnpContentDirectory + "#Browse":
+		result = d.browse(r, envelope.Body.Action.ObjectID, envelope.Body.Action.BrowseFlag)
+	case upnpContentDirectory + "#GetSystemUpdateID":
+		result = "<Id>1</Id>"
+	case upnpContentDirectory + "#GetSearchCapabilities":
+		result = "<SearchCaps></SearchCaps>"
+	case upnpContentDirectory + "#GetSortCapabilities":
+		result = "<SortCaps></SortCaps>"
+	case upnpConnectionManager + "#GetProtocolInfo":
+		result = "<Source>http-get:*:*:*</Source><Sink></Sink>"
+	case upnpConnectionManager + "#GetCurrentConnectionIDs":
+		result = "<ConnectionIDs>0</ConnectionIDs>"
+	default:
+		http.Error(w, "unsupported action", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("EXT", "")
+	fmt.Fprintf(w, soapResponseTemplate, action, service, result, action)
+}
+
+// browse exposes a root container holding the streamed file as its only item.
+func (d *DLNAServer) browse(r *http.Request, objectID, browseFlag string) string {
+	var didl string
+	var count int
+
+	ready := d.client.infoReady()
+	switch {
+	case objectID == "0" && browseFlag == "BrowseMetadata":
+		childCount := 0
+		if ready {
+			childCount = 1
+		}
+		didl = fmt.Sprintf(`<container id="0" parentID="-1" restricted="1" childCount="%d"><dc:title>%s</dc:title><upnp:class>object.container</upnp:class></container>`,
+			childCount, xmlEscape("go-peerflix"))
+		count = 1
+	case ready && (objectID == "0" && browseFlag == "BrowseDirectChildren" || objectID == "1" && browseFlag == "BrowseMetadata"):
+		target := d.client.selectedFile()
+		name := d.client.Torrent.Name()
+		contentType := mime.TypeByExtension(filepath.Ext(d.client.servedName(target)))
+		if contentType == "" {
+			contentType = "video/mpeg"
+		}
+
+		didl = fmt.Sprintf(`<item id="1" parentID="0" restricted="1"><dc:title>%s</dc:title><upnp:class>object.item.videoItem</upnp:class><res protocolInfo="http-get:*:%s:*" size="%d">%s</res></item>`,
+			xmlEscape(name), contentType, target.Length(), xmlEscape("http://"+r.Host+"/"))
+		count = 1
+	}
+
+	didl = `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">` +
+		didl + `</DIDL-Lite>`
+
+	return fmt.Sprintf("<Result>%s</Result><NumberReturned>%d</NumberReturned><TotalMatches>%d</TotalMatches><UpdateID>1</UpdateID>",
+		xmlEscape(didl), count, count)
+}
+
+// localAddressFor finds the address of the interface used to reach a host.
+func localAddressFor(remote *net.UDPAddr) (net.IP, error) {
+	conn, err := net.DialUDP("udp4", nil, remote)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("Error closing udp connection: %s\n", err)
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+func ssdpServer() string {
+	return runtime.GOOS + "/" + runtime.Version() + " UPnP/1.0 go-peerflix/1.0"
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func xmlEscape(s string) string {
+	var buffer bytes.Buffer
+	if err := xml.EscapeText(&buffer, []byte(s)); err != nil {
+		return ""
+	}
+	return buffer.String()
+}
+
+const soapResponseTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:%sResponse xmlns:u="%s">%s</u:%sResponse></s:Body>
+</s:Envelope>`
+
+const dlnaDeviceTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0" xmlns:dlna="urn:schemas-dlna-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>` + upnpMediaServer + `</deviceType>
+    <friendlyName>%s</friendlyName>
+    <manufacturer>go-peerflix</manufacturer>
+    <modelName>go-peerflix</modelName>
+    <UDN>uuid:%s</UDN>
+    <dlna:X_DLNADOC>DMS-1.50</dlna:X_DLNADOC>
+    <serviceList>
+      <service>
+        <serviceType>` + upnpContentDirectory + `</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ContentDirectory</serviceId>
+        <SCPDURL>` + dlnaContentDirectoryPath + `</SCPDURL>
+        <controlURL>` + dlnaControlPath + `ContentDirectory</controlURL>
+        <eventSubURL>` + dlnaEventPath + `ContentDirectory</eventSubURL>
+      </service>
+      <service>
+        <serviceType>` + upnpConnectionManager + `</serviceType>
+        <serviceId>urn:upnp-org:serviceId:ConnectionManager</serviceId>
+        <SCPDURL>` + dlnaConnectionManagerPath + `</SCPDURL>
+        <controlURL>` + dlnaControlPath + `ConnectionManager</controlURL>
+        <eventSubURL>` + dlnaEventPath + `ConnectionManager</eventSubURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+const dlnaContentDirectorySCPD = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>Browse</name>
+      <argumentList>
+        <argument><name>ObjectID</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_ObjectID</relatedStateVariable></argument>
+        <argument><name>BrowseFlag</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_BrowseFlag</relatedStateVariable></argument>
+        <argument><name>Filter</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Filter</relatedStateVariable></argument>
+        <argument><name>StartingIndex</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Index</relatedStateVariable></argument>
+        <argument><name>RequestedCount</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>SortCriteria</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_SortCriteria</relatedStateVariable></argument>
+        <argument><name>Result</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Result</relatedStateVariable></argument>
+        <argument><name>NumberReturned</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>TotalMatches</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>UpdateID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_UpdateID</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSystemUpdateID</name>
+      <argumentList>
+        <argument><name>Id</name><direction>out</direction><relatedStateVariable>SystemUpdateID</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSearchCapabilities</name>
+      <argumentList>
+        <argument><name>SearchCaps</name><direction>out</direction><relatedStateVariable>SearchCapabilities</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSortCapabilities</name>
+      <argumentList>
+        <argument><name>SortCaps</name><direction>out</direction><relatedStateVariable>SortCapabilities</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ObjectID</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_BrowseFlag</name><dataType>string</dataType>
+      <allowedValueList><allowedValue>BrowseMetadata</allowedValue><allowedValue>BrowseDirectChildren</allowedValue></allowedValueList>
+    </stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Filter</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Index</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Count</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_SortCriteria</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Result</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_UpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SystemUpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SearchCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SortCapabilities</name><dataType>string</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
+
+const dlnaConnectionManagerSCPD = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>GetProtocolInfo</name>
+      <argumentList>
+        <argument><name>Source</name><direction>out</direction><relatedStateVariable>SourceProtocolInfo</relatedStateVariable></argument>
+        <argument><name>Sink</name><direction>out</direction><relatedStateVariable>SinkProtocolInfo</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetCurrentConnectionIDs</name>
+      <argumentList>
+        <argument><name>ConnectionIDs</name><direction>out</direction><relatedStateVariable>CurrentConnectionIDs</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes"><name>SourceProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SinkProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>CurrentConnectionIDs</name><dataType>string</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchTargets(t *testing.T) {
+	d := &DLNAServer{uuid: "1234"}
+	tests := []struct {
+		st   string
+		want []string
+	}{
+		{"ssdp:all", d.targets()},
+		{"upnp:rootdevice", []string{"upnp:rootdevice"}},
+		{"uuid:1234", []string{"uuid:1234"}},
+		{upnpMediaServer, []string{upnpMediaServer}},
+		{"uuid:5678", nil},
+		{"urn:schemas-upnp-org:device:MediaRenderer:1", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		if got := d.searchTargets(test.st); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("searchTargets(%q) = %v, want %v", test.st, got, test.want)
+		}
+	}
+}
+
+func TestUSN(t *testing.T) {
+	d := &DLNAServer{uuid: "1234"}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"uuid:1234", "uuid:1234"},
+		{"upnp:rootdevice", "uuid:1234::upnp:rootdevice"},
+		{upnpContentDirectory, "uuid:1234::" + upnpContentDirectory},
+	}
+
+	for _, test := range tests {
+		if got := d.usn(test.target); got != test.want {
+			t.Errorf("usn(%q) = %q, want %q", test.target, got, test.want)
+		}
+	}
+}
+
+func TestServeDevice(t *testing.T) {
+	d := &DLNAServer{client: newTestClient(t, 1), uuid: "1234"}
+
+	w := httptest.NewRecorder()
+	d.ServeDevice(w, httptest.NewRequest(http.MethodGet, dlnaDevicePath, nil))
+
+	var device struct {
+		Device struct {
+			DeviceType   string `xml:"deviceType"`
+			FriendlyName string `xml:"friendlyName"`
+			UDN          string
+		} `xml:"device"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &device); err != nil {
+		t.Fatal(err)
+	}
+	if device.Device.DeviceType != upnpMediaServer {
+		t.Errorf("device type = %q, want %q", device.Device.DeviceType, upnpMediaServer)
+	}
+	if device.Device.FriendlyName != "go-peerflix: movie.mkv" {
+		t.Errorf("friendly name = %q, want %q", device.Device.FriendlyName, "go-peerflix: movie.mkv")
+	}
+	if device.Device.UDN != "uuid:1234" {
+		t.Errorf("UDN = %q, want %q", device.Device.UDN, "uuid:1234")
+	}
+}
+
+func TestServeControlBrowse(t *testing.T) {
+	d := &DLNAServer{client: newTestClient(t, 2), uuid: "1234"}
+	tests := []struct {
+		objectID   string
+		browseFlag string
+		want       string
+		count      string
+	}{
+		{"0", "BrowseMetadata", `<container id="0" parentID="-1" restricted="1" childCount="1">`, "1"},
+		{"0", "BrowseDirectChildren", `<res protocolInfo="http-get:*:video/x-matroska:*" size="32768">http://peerflix.local:8080/</res>`, "1"},
+		{"1", "BrowseMetadata", `<dc:title>movie.mkv</dc:title>`, "1"},
+		{"1", "BrowseDirectChildren", `<DIDL-Lite`, "0"},
+		{"2", "BrowseMetadata", `<DIDL-Lite`, "0"},
+	}
+
+	for _, test := range tests {
+		body := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+			`<u:Browse xmlns:u="` + upnpContentDirectory + `"><ObjectID>` + test.objectID + `</ObjectID><BrowseFlag>` + test.browseFlag + `</BrowseFlag></u:Browse>` +
+			`</s:Body></s:Envelope>`
+		r := httptest.NewRequest(http.MethodPost, dlnaControlPath+"ContentDirectory", strings.NewReader(body))
+		r.Host = "peerflix.local:8080"
+		r.Header.Set("SOAPACTION", `"`+upnpContentDirectory+`#Browse"`)
+		w := httptest.NewRecorder()
+		d.ServeControl(w, r)
+
+		var response struct {
+			Body struct {
+				Response struct {
+					Result         string
+					NumberReturned string
+				} `xml:",any"`
+			}
+		}
+		if err := xml.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Browse %s %s: %s", test.objectID, test.browseFlag, err)
+		}
+		if result := response.Body.Response.Result; !strings.Contains(result, test.want) {
+			t.Errorf("Browse %s %s = %s, want it to contain %s", test.objectID, test.browseFlag, result, test.want)
+		}
+		if count := response.Body.Response.NumberReturned; count != test.count {
+			t.Errorf("Browse %s %s returned %s items, want %s", test.objectID, test.browseFlag, count, test.count)
+		}
+	}
+}
+
+func TestServeControlUnsupported(t *testing.T) {
+	d := &DLNAServer{client: newTestClient(t, 1), uuid: "1234"}
+	tests := []struct {
+		soapAction string
+		want       int
+	}{
+		{"", http.StatusBadRequest},
+		{upnpContentDirectory + "#Search", http.StatusInternalServerError},
+		{upnpConnectionManager + "#GetProtocolInfo", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		body := `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:Action xmlns:u="x"/></s:Body></s:Envelope>`
+		r := httptest.NewRequest(http.MethodPost, dlnaControlPath, strings.NewReader(body))
+		r.Header.Set("SOAPACTION", test.soapAction)
+		w := httptest.NewRecorder()
+		d.ServeControl(w, r)
+
+		if w.Code != test.want {
+			t.Errorf("SOAPACTION %q = %d, want %d", test.soapAction, w.Code, test.want)
+		}
+	}
+}
+
+func TestDLNAServedWithClient(t *testing.T) {
+	c := newTestClient(t, 1)
+	c.Port = 9000
+	d := &DLNAServer{client: c, uuid: "1234"}
+	c.dlna = d
+
+	mux := http.NewServeMux()
+	c.RegisterRoutes(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, dlnaDevicePath, nil))
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<UDN>uuid:1234</UDN>") {
+		t.Errorf("GET %s = %d %s, want the device description", dlnaDevicePath, w.Code, w.Body)
+	}
+	if got, want := d.location(net.IPv4(192, 168, 1, 2)), "http://192.168.1.2:9000"+dlnaDevicePath; got != want {
+		t.Errorf("location() = %q, want %q", got, want)
+	}
+}
+
+func TestAdvertiseAnswersSearch(t *testing.T) {
+	listen := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	// The notifications go to a socket of their own instead of the group.
+	group := listen()
+	defer group.Close()
+	d := &DLNAServer{
+		client: &Client{Port: 9000},
+		uuid:   "1234",
+		conn:   listen(),
+		group:  group.LocalAddr().(*net.UDPAddr),
+		done:   make(chan struct{}),
+	}
+	go d.Advertise()
+	defer d.Close()
+
+	searcher := listen()
+	defer searcher.Close()
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddress + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: " + upnpMediaServer + "\r\n\r\n"
+	if _, err := searcher.WriteToUDP([]byte(search), d.conn.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := searcher.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buffer := make([]byte, 2048)
+	n, err := searcher.Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.ReadResponse(bufio.NewReader(strings.NewReader(string(buffer[:n]))), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"St", upnpMediaServer},
+		{"Usn", "uuid:1234::" + upnpMediaServer},
+		{"Location", "http://127.0.0.1:9000" + dlnaDevicePath},
+	}
+	for _, test := range tests {
+		if got := response.Header.Get(test.header); got != test.want {
+			t.Errorf("search response %s = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
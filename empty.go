@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// ErrEmptyTorrent is returned for torrents without any data to download.
+var ErrEmptyTorrent = errors.New("the torrent has no data, all of its files are empty")
+
+// validateInfo checks the torrent info has something to stream.
+func validateInfo(info *metainfo.Info) error {
+	if info.TotalLength() > 0 {
+		for _, f := range info.UpvertedFiles() {
+			if f.Length > 0 {
+				return nil
+			}
+		}
+	}
+
+	return ClientError{Type: "empty torrent", Origin: ErrEmptyTorrent}
+}
+
+// setErr records an error the client can't recover from, reported by Err and
+// in the stats.
+func (c *Client) setErr(err error) {
+	c.mutex.Lock()
+	c.err = err
+	c.mutex.Unlock()
+}
+
+// Err returns the error the client stopped downloading on, if any.
+func (c *Client) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// UnknownETA is returned by ReadyETA when nothing is being downloaded.
+const UnknownETA time.Duration = -1
+
+// speedSmoothing is the weight of the latest speed in the smoothed speed.
+const speedSmoothing = 0.3
+
+// observeSpeed adds a speed sample in bytes per second to the smoothed speed.
+func (c *Client) observeSpeed(bytesPerSecond int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.smoothedSpeed == 0 {
+		c.smoothedSpeed = float64(bytesPerSecond)
+		return
+	}
+	c.smoothedSpeed = speedSmoothing*float64(bytesPerSecond) + (1-speedSmoothing)*c.smoothedSpeed
+}
+
+// ReadyETA estimates how long until the stream is ready for playback, from
+// the smoothed download speed. It's zero once ready, and UnknownETA while
+// nothing is downloading.
+func (c *Client) ReadyETA() time.Duration {
+	if c.ReadyForPlayback() {
+		return 0
+	}
+
+	c.mutex.Lock()
+	speed := c.smoothedSpeed
+	c.mutex.Unlock()
+
+	return readyETA(c.Torrent.BytesCompleted(), c.Torrent.Length(), c.StreamingConfig().ReadyPercentage, speed)
+}
+
+// readyETA estimates the time to download up to ready percent of length.
+func readyETA(completed, length int64, ready, speed float64) time.Duration {
+	if speed < 1 || length <= 0 {
+		return UnknownETA
+	}
+
+	remaining := float64(length)*ready/100 - float64(completed)
+	if remaining <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(remaining/speed)) * time.Second
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadyETA(t *testing.T) {
+	tests := []struct {
+		completed int64
+		length    int64
+		ready     float64
+		speed     float64
+		want      time.Duration
+	}{
+		{0, 1000, 10, 0, UnknownETA},
+		{0, 0, 10, 100, UnknownETA},
+		{0, 1000, 10, 100, time.Second},
+		{0, 1000, 10, 30, 4 * time.Second},
+		{50, 1000, 10, 10, 5 * time.Second},
+		{100, 1000, 10, 10, 0},
+		{500, 1000, 10, 10, 0},
+	}
+
+	for _, test := range tests {
+		if got := readyETA(test.completed, test.length, test.ready, test.speed); got != test.want {
+			t.Errorf("readyETA(%d, %d, %v, %v) = %s, want %s", test.completed, test.length, test.ready, test.speed, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+
+	"github.com/anacrolix/torrent"
+)
+
+// excludeFiles finds the pieces only belonging to files matching the exclude
+// pattern, so they're never downloaded.
+func (c *Client) excludeFiles() {
+	if c.exclude == nil {
+		return
+	}
+
+	pieceLength := c.Torrent.Info().PieceLength
+	wanted := make([]bool, c.Torrent.NumPieces())
+	for _, f := range c.files() {
+		if c.exclude.MatchString(f.Path()) {
+			log.Printf("Excluding %s\n", f.Path())
+			continue
+		}
+		if f.Length() == 0 {
+			continue
+		}
+
+		first := int(f.Offset() / pieceLength)
+		last := int((f.Offset() + f.Length() - 1) / pieceLength)
+		for i := first; i <= last && i < len(wanted); i++ {
+			wanted[i] = true
+		}
+	}
+
+	excluded := make([]bool, len(wanted))
+	for i := range wanted {
+		excluded[i] = !wanted[i]
+	}
+
+	c.mutex.Lock()
+	c.excluded = excluded
+	c.mutex.Unlock()
+
+	c.applyOverrides()
+}
+
+// applyOverrides applies the priorities that take precedence over the ones
+// set around the playhead, with the ones set over http last.
+func (c *Client) applyOverrides() {
+	c.applySubtitlePriority()
+	c.applyPrefetchPriority()
+	c.applyExclusions()
+	c.applyPriorityOverrides()
+}
+
+// applyExclusions sets the pieces of excluded files back to no priority after
+// they've been reprioritized.
+func (c *Client) applyExclusions() {
+	c.mutex.Lock()
+	excluded := c.excluded
+	c.mutex.Unlock()
+
+	for i, skip := range excluded {
+		if skip {
+			c.Torrent.Piece(i).SetPriority(torrent.PiecePriorityNone)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestExcludeFiles(t *testing.T) {
+	const (
+		none   = torrent.PiecePriorityNone
+		normal = torrent.PiecePriorityNormal
+	)
+
+	// The notes share their last piece with the excluded sample.
+	c := startTestClient(t, metainfo.Info{
+		Name:        "Movie",
+		PieceLength: testPieceLength,
+		Pieces:      make([]byte, 20*6),
+		Files: []metainfo.FileInfo{
+			{Path: []string{"movie.mkv"}, Length: 2 * testPieceLength},
+			{Path: []string{"movie.nfo"}, Length: testPieceLength},
+			{Path: []string{"notes.txt"}, Length: testPieceLength + 100},
+			{Path: []string{"Sample", "sample.mkv"}, Length: 2*testPieceLength - 100},
+		},
+	}, t.TempDir())
+	waitHashed(t, c)
+	c.exclude = regexp.MustCompile(`(?i)sample|\.nfo$`)
+
+	priorities := func() []torrent.PiecePriority {
+		got := make([]torrent.PiecePriority, c.Torrent.NumPieces())
+		for i := range got {
+			got[i] = c.Torrent.PieceState(i).Priority
+		}
+		return got
+	}
+
+	c.Torrent.DownloadAll()
+	c.excludeFiles()
+	want := []torrent.PiecePriority{normal, normal, none, normal, normal, none}
+	if got := priorities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("piece priorities = %v, want %v", got, want)
+	}
+
+	// The excluded pieces stay unwanted when reprioritized.
+	c.Config.MaxPiecesAhead = 10
+	c.prioritize()
+	if got := priorities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("piece priorities after reprioritizing = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"net/http"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	fallbackWidth  = 640
+	fallbackHeight = 360
+)
+
+// fallbackImage renders the built-in fallback: the error on a dark frame,
+// which players show like a single frame video.
+func fallbackImage(lines ...string) ([]byte, error) {
+	frame := image.NewRGBA(image.Rect(0, 0, fallbackWidth, fallbackHeight))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.RGBA{0x20, 0x20, 0x20, 0xff}), image.Point{}, draw.Src)
+
+	drawer := font.Drawer{Dst: frame, Src: image.White, Face: basicfont.Face7x13}
+	const lineHeight = 20
+	top := (fallbackHeight - len(lines)*lineHeight) / 2
+	for i, line := range lines {
+		left := (fallbackWidth - drawer.MeasureString(line).Ceil()) / 2
+		if left < 0 {
+			left = 0
+		}
+		drawer.Dot = fixed.P(left, top+(i+1)*lineHeight)
+		drawer.DrawString(line)
+	}
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, frame)
+	return buffer.Bytes(), err
+}
+
+// serveFallback serves the configured FallbackAsset, or the built-in image,
+// in place of a stream that can't start.
+func (c *Client) serveFallback(w http.ResponseWriter, r *http.Request, reason error) {
+	if c.Config.FallbackAsset != "" {
+		http.ServeFile(w, r, c.Config.FallbackAsset)
+		return
+	}
+
+	data, err := fallbackImage("Stream unavailable", truncate(reason.Error(), 80))
+	if err != nil {
+		log.Printf("Error rendering the fallback image: %s\n", err)
+		http.Error(w, reason.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(data)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetFileFallback(t *testing.T) {
+	data := []byte("the whole movie")
+	asset := filepath.Join(t.TempDir(), "unavailable.mp4")
+	if err := os.WriteFile(asset, []byte("unavailable clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		failed   bool
+		fallback bool
+		asset    string
+		want     string
+	}{
+		{"streaming", false, true, asset, string(data)},
+		{"without fallback", true, false, "", string(data)},
+		{"built-in", true, true, "", ""},
+		{"asset", true, false, asset, "unavailable clip"},
+	}
+	for _, test := range tests {
+		c := newSeededTestClient(t, data)
+		c.Config.Fallback = test.fallback
+		c.Config.FallbackAsset = test.asset
+		if test.failed {
+			c.setErr(ClientError{Type: "empty torrent", Origin: errors.New("no data")})
+		}
+
+		w := httptest.NewRecorder()
+		c.GetFile(w, httptest.NewRequest("GET", "/", nil))
+		if test.want != "" {
+			if got := w.Body.String(); got != test.want {
+				t.Errorf("%s: GET / = %q, want %q", test.name, got, test.want)
+			}
+			continue
+		}
+
+		if got := w.Header().Get("Content-Type"); got != "image/png" {
+			t.Errorf("%s: Content-Type = %q, want image/png", test.name, got)
+		}
+		img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+		if err != nil {
+			t.Errorf("%s: GET / isn't an image: %s", test.name, err)
+			continue
+		}
+		if size := img.Bounds().Size(); size.X != fallbackWidth || size.Y != fallbackHeight {
+			t.Errorf("%s: fallback image is %v, want %dx%d", test.name, size, fallbackWidth, fallbackHeight)
+		}
+	}
+}
@@ -1,41 +1,202 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/anacrolix/torrent"
 )
 
+var errInvalidSeek = errors.New("invalid seek")
+
 // SeekableContent describes an io.ReadSeeker that can be closed as well.
 type SeekableContent interface {
 	io.ReadSeeker
 	io.Closer
+	// SetContext ends the reads once ctx is done, like when the request
+	// being served is cancelled.
+	SetContext(ctx context.Context)
 }
 
 // FileEntry helps reading a torrent file.
 type FileEntry struct {
 	File *torrent.File
-	*torrent.Reader
+	torrent.Reader
+	client *Client
+	pos    int64
+	// ctx is cancelled to end the reads, like when the stream is stopped by
+	// another request.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// closeMutex guards closed, so the reader is only closed once.
+	closeMutex sync.Mutex
+	closed     bool
+	buffering  readBuffering
+	// readaheadPercentage is the share of the file read ahead, unless the
+	// Readahead is configured.
+	readaheadPercentage int64
 }
 
 // Seek seeks to the correct file position, paying attention to the offset.
-func (f FileEntry) Seek(offset int64, whence int) (int64, error) {
-	return f.Reader.Seek(offset+f.File.Offset(), whence)
+func (f *FileEntry) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case os.SEEK_SET:
+		pos = offset
+	case os.SEEK_CUR:
+		pos = f.pos + offset
+	case os.SEEK_END:
+		pos = f.File.Length() + offset
+	default:
+		return f.pos, errInvalidSeek
+	}
+
+	if pos < 0 {
+		return f.pos, errInvalidSeek
+	}
+
+	if _, err := f.Reader.Seek(pos+f.File.Offset(), os.SEEK_SET); err != nil {
+		return f.pos, err
+	}
+
+	f.pos = pos
+	f.client.setPlayhead(f.File.Offset() + f.pos)
+
+	return f.pos, nil
+}
+
+// Read reads from the current position, stopping at the end of the file.
+func (f *FileEntry) Read(p []byte) (n int, err error) {
+	remaining := f.File.Length() - f.pos
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	if err = f.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// Only hand out bytes from pieces that passed their hash check.
+	if f.client.waitsVerified() {
+		verified, err := f.client.waitVerified(f.ctx, f.File.Offset()+f.pos, int64(len(p)))
+		if err != nil {
+			return 0, err
+		}
+		p = p[:verified]
+	}
+
+	f.startRead()
+	n, err = f.Reader.Read(p)
+	f.endRead()
+	f.pos += int64(n)
+	f.client.setPlayhead(f.File.Offset() + f.pos)
+
+	return
+}
+
+// Close closes the reader. Closing it again does nothing.
+func (f *FileEntry) Close() error {
+	f.closeMutex.Lock()
+	defer f.closeMutex.Unlock()
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	f.client.mutex.Lock()
+	delete(f.client.readers, f)
+	f.client.mutex.Unlock()
+
+	f.cancel()
+	f.endRead()
+	return f.Reader.Close()
+}
+
+// SetContext ends the reads once ctx is done too.
+func (f *FileEntry) SetContext(ctx context.Context) {
+	context.AfterFunc(ctx, f.cancel)
+}
+
+// stop cancels the pending and future reads, ending the stream. The reader
+// is still closed by its stream.
+func (f *FileEntry) stop() {
+	f.cancel()
 }
 
 // NewFileReader sets up a torrent file for streaming reading.
-func NewFileReader(c Client, f *torrent.File) (SeekableContent, error) {
+func NewFileReader(c *Client, f *torrent.File) (SeekableContent, error) {
 	// We read ahead 1% of the file continuously.
-	var readahead = f.Length() / 100
+	return newFileReader(c, f, 1)
+}
 
+// NewProgressiveFileReader sets up a torrent file for reading it whole from
+// the start, reading further ahead as the reads won't seek around.
+func NewProgressiveFileReader(c *Client, f *torrent.File) (SeekableContent, error) {
+	return newFileReader(c, f, progressiveReadahead)
+}
+
+// progressiveReadahead is the percentage of the file read ahead by readers
+// going through the whole file.
+const progressiveReadahead = 5
+
+func newFileReader(c *Client, f *torrent.File, readaheadPercentage int64) (SeekableContent, error) {
 	reader := c.Torrent.NewReader()
-	reader.SetReadahead(readahead)
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &FileEntry{
+		File:                f,
+		Reader:              reader,
+		client:              c,
+		ctx:                 ctx,
+		cancel:              cancel,
+		readaheadPercentage: readaheadPercentage,
+	}
+
+	reader.SetContext(ctx)
+	reader.SetReadahead(c.readahead(entry.normalReadahead()))
 	reader.SetResponsive()
 	_, err := reader.Seek(f.Offset(), os.SEEK_SET)
 
-	return &FileEntry{
-		File:   f,
-		Reader: reader,
-	}, err
+	c.mutex.Lock()
+	c.readers[entry] = struct{}{}
+	c.mutex.Unlock()
+
+	return entry, err
+}
+
+// normalReadahead is the readahead when not under memory pressure.
+func (f *FileEntry) normalReadahead() int64 {
+	config := f.client.StreamingConfig()
+	readahead := config.Readahead
+	if readahead <= 0 {
+		readahead = f.File.Length() * f.readaheadPercentage / 100
+	}
+
+	// Never read ahead past the pieces we're allowed to request.
+	if config.MaxPiecesAhead > 0 {
+		limit := int64(config.MaxPiecesAhead) * f.client.Torrent.Info().PieceLength
+		if readahead > limit {
+			readahead = limit
+		}
+	}
+	return readahead
+}
+
+// updateReadahead applies the readahead to the open readers.
+func (c *Client) updateReadahead() {
+	c.mutex.Lock()
+	readers := make([]*FileEntry, 0, len(c.readers))
+	for reader := range c.readers {
+		readers = append(readers, reader)
+	}
+	c.mutex.Unlock()
+
+	for _, reader := range readers {
+		reader.Reader.SetReadahead(c.readahead(reader.normalReadahead()))
+	}
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// closeCountingReader counts how many times it's closed.
+type closeCountingReader struct {
+	torrent.Reader
+	closes int
+}
+
+func (r *closeCountingReader) Close() error {
+	r.closes++
+	return nil
+}
+
+func newTestFileEntry(c *Client, reader torrent.Reader) *FileEntry {
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &FileEntry{Reader: reader, client: c, ctx: ctx, cancel: cancel}
+	c.readers[entry] = struct{}{}
+	return entry
+}
+
+func TestFileEntryCloseOnce(t *testing.T) {
+	c := &Client{readers: make(map[*FileEntry]struct{})}
+	reader := &closeCountingReader{}
+	entry := newTestFileEntry(c, reader)
+
+	for i := 0; i < 3; i++ {
+		if err := entry.Close(); err != nil {
+			t.Fatalf("Close() = %v", err)
+		}
+	}
+
+	if reader.closes != 1 {
+		t.Errorf("reader closed %d times, want once", reader.closes)
+	}
+	if _, ok := c.readers[entry]; ok {
+		t.Error("closed reader still registered")
+	}
+}
+
+func TestFileEntryStop(t *testing.T) {
+	c := &Client{readers: make(map[*FileEntry]struct{})}
+	reader := &closeCountingReader{}
+	entry := newTestFileEntry(c, reader)
+
+	entry.stop()
+
+	if entry.ctx.Err() == nil {
+		t.Error("stop didn't cancel the reads")
+	}
+	if reader.closes != 0 {
+		t.Error("stop closed the reader under its stream")
+	}
+	if _, ok := c.readers[entry]; !ok {
+		t.Error("stopped reader unregistered before its stream closed it")
+	}
+}
+
+func TestProgressiveDownloadReadsFurtherAhead(t *testing.T) {
+	tests := []struct {
+		rangeHeader string
+		want        int
+	}{
+		{"", progressiveReadahead},
+		{"bytes=0-", 1},
+	}
+
+	for _, test := range tests {
+		c := newTestClient(t, 100)
+		waitHashed(t, c)
+		c.Config.ProgressiveDownload = true
+
+		// Nothing is downloaded, so the request waits on the first piece.
+		ctx, cancel := context.WithCancel(context.Background())
+		r := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+		if test.rangeHeader != "" {
+			r.Header.Set("Range", test.rangeHeader)
+		}
+		done := make(chan struct{})
+		go func() {
+			c.GetFile(httptest.NewRecorder(), r)
+			close(done)
+		}()
+
+		// The pieces read ahead, from the one being read, are wanted at the
+		// readahead priority. The file is 100 pieces long.
+		readahead := func() int {
+			wanted := 0
+			for i := 0; i < c.Torrent.NumPieces(); i++ {
+				if c.Torrent.PieceState(i).Priority >= torrent.PiecePriorityReadahead {
+					wanted++
+				}
+			}
+			return wanted
+		}
+		deadline := time.After(5 * time.Second)
+		for readahead() < test.want {
+			select {
+			case <-deadline:
+				t.Fatalf("Range %q: %d pieces read ahead, want %d", test.rangeHeader, readahead(), test.want)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		got := readahead()
+		cancel()
+		<-done
+
+		if got != test.want {
+			t.Errorf("Range %q: %d pieces read ahead, want %d", test.rangeHeader, got, test.want)
+		}
+	}
+}
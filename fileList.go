@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// fileList is the file list of a torrent as saved between runs.
+type fileList struct {
+	InfoHash string
+	Files    []FileInfo
+}
+
+func (c *Client) fileListPath() string {
+	return filepath.Join(c.Config.stateDir(), c.Torrent.InfoHash().HexString()+".files.json")
+}
+
+// loadFileList loads the file list saved by a previous run, so ListFiles has
+// something to show before the metadata is fetched again.
+func (c *Client) loadFileList() {
+	data, err := ioutil.ReadFile(c.fileListPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading file list: %s\n", err)
+		}
+		return
+	}
+
+	var list fileList
+	if err := json.Unmarshal(data, &list); err != nil || list.InfoHash != c.Torrent.InfoHash().HexString() {
+		log.Printf("Ignoring invalid file list %s\n", c.fileListPath())
+		return
+	}
+
+	c.mutex.Lock()
+	c.savedFiles = list.Files
+	c.mutex.Unlock()
+}
+
+// saveFileList saves the file list once the metadata is known.
+func (c *Client) saveFileList() {
+	data, err := json.Marshal(fileList{
+		InfoHash: c.Torrent.InfoHash().HexString(),
+		Files:    c.ListFiles(),
+	})
+	if err != nil {
+		log.Printf("Error encoding file list: %s\n", err)
+		return
+	}
+
+	if err := os.MkdirAll(c.Config.stateDir(), 0755); err != nil {
+		log.Printf("Error creating state directory: %s\n", err)
+		return
+	}
+	if err := ioutil.WriteFile(c.fileListPath(), data, 0644); err != nil {
+		log.Printf("Error writing file list: %s\n", err)
+	}
+}
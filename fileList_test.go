@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFileListRoundTrips(t *testing.T) {
+	c := newManyFilesTestClient(t, 3)
+	c.Config.DataDir = t.TempDir()
+	want := c.ListFiles()
+	c.saveFileList()
+
+	// Restart with the magnet, before anyone sends the metadata again.
+	infoHash := c.Torrent.InfoHash()
+	c.Torrent.Drop()
+	var err error
+	if c.Torrent, err = c.Client.AddMagnet("magnet:?xt=urn:btih:" + infoHash.HexString()); err != nil {
+		t.Fatal(err)
+	}
+	if c.infoReady() {
+		t.Fatal("the restarted torrent has its info")
+	}
+	if got := c.ListFiles(); len(got) != 0 {
+		t.Errorf("ListFiles() before loading the file list = %v, want none", got)
+	}
+
+	c.loadFileList()
+	if got := c.ListFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFiles() after a restart = %v, want %v", got, want)
+	}
+}
+
+func TestFileListOfAnotherTorrent(t *testing.T) {
+	c := newManyFilesTestClient(t, 1)
+	c.Config.DataDir = t.TempDir()
+	if err := os.MkdirAll(c.Config.stateDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(fileList{
+		InfoHash: "0123456789abcdef0123456789abcdef01234567",
+		Files:    []FileInfo{{Path: "other.mkv"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.fileListPath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c.loadFileList()
+	if c.savedFiles != nil {
+		t.Errorf("loadFileList() of another torrent's list loaded %v, want nothing", c.savedFiles)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// FileInfo describes a file within a torrent.
+type FileInfo struct {
+	Index  int
+	Path   string
+	Length int64
+}
+
+func fileInfos(t *torrent.Torrent, files []*torrent.File) []FileInfo {
+	infos := make([]FileInfo, len(files))
+	for i := range files {
+		infos[i] = FileInfo{
+			Index:  i,
+			Path:   torrentFileName(t, files[i]),
+			Length: files[i].Length(),
+		}
+	}
+	return infos
+}
+
+// ListFiles returns the files of the streamed torrent. Until the metadata is
+// fetched, it returns the files saved by a previous run, if any.
+func (c *Client) ListFiles() []FileInfo {
+	if !c.infoReady() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.savedFiles
+	}
+
+	return fileInfos(c.Torrent, c.files())
+}
+
+// WarmStartResult holds the metadata resolved for a magnet by WarmStart.
+type WarmStartResult struct {
+	Magnet   string
+	InfoHash string
+	Name     string
+	Files    []FileInfo
+	Error    error
+}
+
+// WarmStart resolves the metadata of several magnets concurrently, without
+// downloading or serving them, so their files can be listed. The torrents are
+// dropped afterwards unless keep is set.
+func (c *Client) WarmStart(magnets []string, keep bool) []WarmStartResult {
+	results := make([]WarmStartResult, len(magnets))
+	concurrency := c.Config.WarmStartConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+
+	var wait sync.WaitGroup
+	for i, magnet := range magnets {
+		wait.Add(1)
+		go func(i int, magnet string) {
+			defer wait.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = c.warmStart(magnet, keep)
+		}(i, magnet)
+	}
+	wait.Wait()
+
+	return results
+}
+
+func (c *Client) warmStart(magnet string, keep bool) (result WarmStartResult) {
+	result.Magnet = magnet
+
+	t, added, err := c.addRemoteTorrent(magnet)
+	if err != nil {
+		result.Error = err
+		return
+	}
+	result.InfoHash = t.InfoHash().HexString()
+
+	if added && keep {
+		if err = c.enforceMaxTorrents(t); err != nil {
+			result.Error = err
+			return
+		}
+	}
+
+	// Never drop a torrent that was there before, like the streamed one.
+	if added && !keep {
+		defer t.Drop()
+	}
+
+	select {
+	case <-t.GotInfo():
+	case <-time.After(c.Config.WarmStartTimeout):
+		result.Error = ClientError{Type: "fetching torrent metadata", Origin: ErrMetadataTimeout}
+		return
+	}
+
+	if err = c.checkInfo(t.Info()); err != nil {
+		if added && keep {
+			t.Drop()
+		}
+		result.Error = err
+		return
+	}
+
+	result.Name = t.Name()
+	result.Files = fileInfos(t, t.Files())
+	return
+}
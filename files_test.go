@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// seedingMagnet returns a magnet of the torrent of c, pointing at its client
+// as a peer.
+func seedingMagnet(c *Client) string {
+	return fmt.Sprintf("magnet:?xt=urn:btih:%s&x.pe=%s", c.Torrent.InfoHash().HexString(), url.QueryEscape(fmt.Sprintf("127.0.0.1:%d", c.Client.LocalPort())))
+}
+
+func TestWarmStart(t *testing.T) {
+	movie := newSeededTestClient(t, []byte("a movie"))
+	show := seededTestClient(t, metainfo.Info{
+		Name: "Show",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"S01E01.mkv"}, Length: 3},
+			{Path: []string{"S01E02.mkv"}, Length: 4},
+		},
+	}, []byte("onetwo!"))
+	unreachable := []string{
+		"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567",
+		"magnet:?xt=urn:btih:76543210fedcba9876543210fedcba9876543210",
+	}
+
+	c := newTestClient(t, 1)
+	c.Config.WarmStartConcurrency = 4
+	c.Config.WarmStartTimeout = time.Second
+	start := time.Now()
+	results := c.WarmStart(append([]string{seedingMagnet(movie), seedingMagnet(show)}, unreachable...), false)
+
+	want := []WarmStartResult{
+		{
+			Magnet:   seedingMagnet(movie),
+			InfoHash: movie.Torrent.InfoHash().HexString(),
+			Name:     "movie.mkv",
+			Files:    []FileInfo{{Path: "movie.mkv", Length: 7}},
+		},
+		{
+			Magnet:   seedingMagnet(show),
+			InfoHash: show.Torrent.InfoHash().HexString(),
+			Name:     "Show",
+			Files:    []FileInfo{{Path: "S01E01.mkv", Length: 3}, {Index: 1, Path: "S01E02.mkv", Length: 4}},
+		},
+	}
+	for i, result := range results[:2] {
+		if !reflect.DeepEqual(result, want[i]) {
+			t.Errorf("WarmStart() result %d = %+v, want %+v", i, result, want[i])
+		}
+	}
+	for _, result := range results[2:] {
+		if clientError, ok := result.Error.(ClientError); !ok || clientError.Origin != ErrMetadataTimeout {
+			t.Errorf("WarmStart() of an unreachable magnet = %v, want %v", result.Error, ErrMetadataTimeout)
+		}
+	}
+	// The unreachable magnets timed out at the same time.
+	if elapsed := time.Since(start); elapsed > 2*c.Config.WarmStartTimeout-100*time.Millisecond {
+		t.Errorf("WarmStart() took %s, want the magnets resolved concurrently", elapsed)
+	}
+
+	if torrents := c.Client.Torrents(); len(torrents) != 1 || torrents[0] != c.Torrent {
+		t.Errorf("client kept %d torrents, want only the streamed one", len(torrents))
+	}
+}
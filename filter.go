@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net"
+	"sync/atomic"
+
+	"github.com/anacrolix/torrent/iplist"
+)
+
+// connectionBlocklist blocks the peers rejected by the ConnectionFilter, and
+// the ones outside the local network once lanOnly is set. It's consulted by
+// the torrent client before connecting to or accepting a peer.
+type connectionBlocklist struct {
+	filter  func(addr net.Addr) bool
+	lanOnly atomic.Bool
+}
+
+// Lookup reports the addresses that aren't allowed as blocked.
+func (b *connectionBlocklist) Lookup(ip net.IP) (iplist.Range, bool) {
+	if b.lanOnly.Load() && !isLANAddress(ip) {
+		return iplist.Range{First: ip, Last: ip, Description: "outside the local network"}, true
+	}
+	if b.filter != nil && !b.filter(&net.IPAddr{IP: ip}) {
+		return iplist.Range{First: ip, Last: ip, Description: "rejected by the connection filter"}, true
+	}
+	return iplist.Range{}, false
+}
+
+// NumRanges is unknown, as the ranges are decided per address.
+func (*connectionBlocklist) NumRanges() int {
+	return 0
+}
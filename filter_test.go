@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// filteredLeecher returns a torrent client whose peers go through filter,
+// fetching the torrent of seeder.
+func filteredLeecher(t *testing.T, seeder *Client, filter func(addr net.Addr) bool) *torrent.Torrent {
+	t.Helper()
+
+	config := torrent.NewDefaultClientConfig()
+	config.DataDir = t.TempDir()
+	config.ListenPort = 0
+	config.NoDHT = true
+	config.DisableTrackers = true
+	config.IPBlocklist = &connectionBlocklist{filter: filter}
+	cl, err := torrent.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cl.Close() })
+
+	// Peers of the magnet itself are trusted, and never filtered.
+	tor, err := cl.AddMagnet("magnet:?xt=urn:btih:" + seeder.Torrent.InfoHash().HexString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tor.AddPeers([]torrent.PeerInfo{{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: seeder.Client.LocalPort()}}})
+	return tor
+}
+
+func TestConnectionFilter(t *testing.T) {
+	seeder := newSeededTestClient(t, []byte("a movie"))
+	var consulted atomic.Bool
+	rejected := filteredLeecher(t, seeder, func(addr net.Addr) bool {
+		consulted.Store(true)
+		return !addr.(*net.IPAddr).IP.IsLoopback()
+	})
+	allowed := filteredLeecher(t, seeder, func(addr net.Addr) bool {
+		return !addr.(*net.IPAddr).IP.Equal(net.IPv4(192, 0, 2, 1))
+	})
+
+	select {
+	case <-allowed.GotInfo():
+	case <-time.After(5 * time.Second):
+		t.Fatal("allowed leecher didn't get the metadata")
+	}
+
+	if !consulted.Load() {
+		t.Error("connection filter not consulted")
+	}
+	select {
+	case <-rejected.GotInfo():
+		t.Error("leecher got the metadata from a rejected peer")
+	default:
+	}
+	if conns := rejected.PeerConns(); len(conns) != 0 {
+		t.Errorf("leecher connected to %d rejected peers, want none", len(conns))
+	}
+	if got := seeder.ConnectionStats(); got.Total != 1 {
+		t.Errorf("seeder ConnectionStats() = %s, want only the allowed leecher", got)
+	}
+}
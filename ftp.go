@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ftpDataTimeout is how long we wait for the client to open a data connection.
+const ftpDataTimeout = 30 * time.Second
+
+// ServeFTP serves the streamed file read-only over FTP, for devices that
+// can't play over http.
+func (c *Client) ServeFTP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("Error accepting ftp connection: %s\n", err)
+			return
+		}
+
+		session := &ftpSession{
+			client:  c,
+			control: textproto.NewConn(conn),
+			local:   conn.LocalAddr().(*net.TCPAddr).IP,
+			remote:  conn.RemoteAddr().(*net.TCPAddr).IP,
+		}
+		go session.serve()
+	}
+}
+
+// ftpSession is the state of a single FTP control connection.
+type ftpSession struct {
+	client   *Client
+	control  *textproto.Conn
+	local    net.IP
+	remote   net.IP
+	user     string
+	loggedIn bool
+	offset   int64
+	passive  net.Listener
+}
+
+func (s *ftpSession) reply(code int, format string, args ...interface{}) {
+	if err := s.control.PrintfLine("%d %s", code, fmt.Sprintf(format, args...)); err != nil {
+		log.Printf("Error writing ftp reply: %s\n", err)
+	}
+}
+
+func (s *ftpSession) serve() {
+	defer func() {
+		s.closePassive()
+		if err := s.control.Close(); err != nil {
+			log.Printf("Error closing ftp connection: %s\n", err)
+		}
+	}()
+
+	s.reply(220, "go-peerflix ftp ready")
+
+	for {
+		line, err := s.control.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, argument := line, ""
+		if separator := strings.Index(line, " "); separator >= 0 {
+			command, argument = line[:separator], line[separator+1:]
+		}
+		command = strings.ToUpper(command)
+
+		if command == "QUIT" {
+			s.reply(221, "Goodbye")
+			return
+		}
+
+		if !s.loggedIn && command != "USER" && command != "PASS" && command != "FEAT" && command != "SYST" {
+			s.reply(530, "Please login with USER and PASS")
+			continue
+		}
+
+		s.handle(command, argument)
+	}
+}
+
+func (s *ftpSession) handle(command, argument string) {
+	switch command {
+	case "USER":
+		s.user = argument
+		s.loggedIn = false
+		s.reply(331, "Password required")
+	case "PASS":
+		cfg := s.client.Config
+		// Compared in constant time, so they can't be guessed byte by byte
+		// from the reply times.
+		user := subtle.ConstantTimeCompare([]byte(s.user), []byte(cfg.FTPUser))
+		password := subtle.ConstantTimeCompare([]byte(argument), []byte(cfg.FTPPassword))
+		if cfg.FTPUser == "" || user&password == 1 {
+			s.loggedIn = true
+			s.reply(230, "Logged in")
+		} else {
+			s.reply(530, "Login incorrect")
+		}
+	case "SYST":
+		s.reply(215, "UNIX Type: L8")
+	case "FEAT":
+		if err := s.control.PrintfLine("211-Features:\r\n SIZE\r\n REST STREAM\r\n PASV\r\n EPSV\r\n UTF8\r\n211 End"); err != nil {
+			log.Printf("Error writing ftp reply: %s\n", err)
+		}
+	case "OPTS", "NOOP", "TYPE", "MODE", "STRU":
+		s.reply(200, "OK")
+	case "PWD":
+		s.reply(257, `"/" is the current directory`)
+	case "CWD", "CDUP":
+		if command == "CWD" && path.Clean("/"+argument) != "/" {
+			s.reply(550, "No such directory")
+			return
+		}
+		s.reply(250, "Directory changed")
+	case "PASV", "EPSV":
+		s.openPassive(command)
+	case "LIST", "NLST":
+		s.list(command == "NLST")
+	case "SIZE":
+		if !s.isFile(argument) {
+			s.reply(550, "No such file")
+			return
+		}
+		s.reply(213, "%d", s.client.selectedFile().Length())
+	case "REST":
+		offset, err := strconv.ParseInt(argument, 10, 64)
+		if err != nil || offset < 0 {
+			s.reply(501, "Invalid offset")
+			return
+		}
+		s.offset = offset
+		s.reply(350, "Restarting at %d", offset)
+	case "RETR":
+		s.retrieve(argument)
+	default:
+		s.reply(502, "Command not implemented")
+	}
+}
+
+// fileName is the name the streamed file is listed under.
+func (s *ftpSession) fileName() string {
+	return filepath.Base(s.client.servedName(s.client.selectedFile()))
+}
+
+func (s *ftpSession) isFile(name string) bool {
+	return s.client.infoReady() && strings.TrimPrefix(path.Clean("/"+name), "/") == s.fileName()
+}
+
+func (s *ftpSession) openPassive(command string) {
+	s.closePassive()
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.local.String(), "0"))
+	if err != nil {
+		s.reply(425, "Can't open data connection")
+		return
+	}
+	s.passive = listener
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	if command == "EPSV" {
+		s.reply(229, "Entering Extended Passive Mode (|||%d|)", port)
+		return
+	}
+
+	ip := s.local.To4()
+	if ip == nil {
+		s.closePassive()
+		s.reply(425, "Use EPSV for IPv6")
+		return
+	}
+	s.reply(227, "Entering Passive Mode (%d,%d,%d,%d,%d,%d)", ip[0], ip[1], ip[2], ip[3], port>>8, port&0xff)
+}
+
+func (s *ftpSession) closePassive() {
+	if s.passive == nil {
+		return
+	}
+	if err := s.passive.Close(); err != nil {
+		log.Printf("Error closing ftp data listener: %s\n", err)
+	}
+	s.passive = nil
+}
+
+// dataConnection accepts the data connection set up by PASV or EPSV. It must
+// come from the host of the control connection, so another host can't steal
+// the transfer by connecting to the passive port first.
+func (s *ftpSession) dataConnection() (net.Conn, error) {
+	if s.passive == nil {
+		return nil, fmt.Errorf("no data connection, use PASV or EPSV first")
+	}
+	defer s.closePassive()
+
+	if listener, ok := s.passive.(*net.TCPListener); ok {
+		if err := listener.SetDeadline(time.Now().Add(ftpDataTimeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := s.passive.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if remote, ok := conn.RemoteAddr().(*net.TCPAddr); !ok || !remote.IP.Equal(s.remote) {
+		if err := conn.Close(); err != nil {
+			log.Printf("Error closing ftp data connection: %s\n", err)
+		}
+		return nil, fmt.Errorf("data connection from %s instead of %s", conn.RemoteAddr(), s.remote)
+	}
+	return conn, nil
+}
+
+// transfer runs a data transfer, replying on the control connection.
+func (s *ftpSession) transfer(send func(io.Writer) error) {
+	s.reply(150, "Opening data connection")
+
+	conn, err := s.dataConnection()
+	if err != nil {
+		s.reply(425, "Can't open data connection: %s", err)
+		return
+	}
+
+	err = send(conn)
+	if closeErr := conn.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		s.reply(426, "Transfer aborted: %s", err)
+		return
+	}
+	s.reply(226, "Transfer complete")
+}
+
+func (s *ftpSession) list(namesOnly bool) {
+	s.transfer(func(w io.Writer) error {
+		if !s.client.infoReady() {
+			return nil
+		}
+
+		if namesOnly {
+			_, err := fmt.Fprintf(w, "%s\r\n", s.fileName())
+			return err
+		}
+
+		_, err := fmt.Fprintf(w, "-r--r--r-- 1 peerflix peerflix %d %s %s\r\n",
+			s.client.selectedFile().Length(), time.Now().Format("Jan 02 15:04"), s.fileName())
+		return err
+	})
+}
+
+func (s *ftpSession) retrieve(name string) {
+	if !s.isFile(name) {
+		s.reply(550, "No such file")
+		return
+	}
+
+	offset := s.offset
+	s.offset = 0
+
+	s.transfer(func(w io.Writer) error {
+		s.client.streamStarted()
+		defer s.client.streamEnded()
+
+		entry, err := NewFileReader(s.client, s.client.selectedFile())
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := entry.Close(); err != nil {
+				log.Printf("Error closing file reader: %s\n", err)
+			}
+		}()
+
+		if _, err := entry.Seek(offset, os.SEEK_SET); err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, entry)
+		return err
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// ftpCommand sends a command and checks the code of its reply.
+func ftpCommand(t *testing.T, control *textproto.Conn, want int, format string, args ...interface{}) string {
+	t.Helper()
+
+	if err := control.PrintfLine(format, args...); err != nil {
+		t.Fatal(err)
+	}
+	_, message, err := control.ReadResponse(want)
+	if err != nil {
+		t.Fatalf("%s: %s", format, err)
+	}
+	return message
+}
+
+func TestFTPSession(t *testing.T) {
+	data := make([]byte, 3*testPieceLength+100)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	c := newSeededTestClient(t, data)
+	c.Config.FTPUser = "peerflix"
+	c.Config.FTPPassword = "secret"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go c.ServeFTP(listener)
+
+	control, err := textproto.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer control.Close()
+	if _, _, err := control.ReadResponse(220); err != nil {
+		t.Fatal(err)
+	}
+
+	ftpCommand(t, control, 530, "SIZE movie.mkv")
+	ftpCommand(t, control, 331, "USER peerflix")
+	ftpCommand(t, control, 530, "PASS wrong")
+	ftpCommand(t, control, 331, "USER peerflix")
+	ftpCommand(t, control, 230, "PASS secret")
+
+	if size := ftpCommand(t, control, 213, "SIZE movie.mkv"); size != strconv.Itoa(len(data)) {
+		t.Errorf("SIZE = %s, want %d", size, len(data))
+	}
+	ftpCommand(t, control, 550, "SIZE other.mkv")
+
+	const offset = testPieceLength + 10
+	ftpCommand(t, control, 350, "REST %d", offset)
+
+	passive := ftpCommand(t, control, 229, "EPSV")
+	port := regexp.MustCompile(`\(\|\|\|(\d+)\|\)`).FindStringSubmatch(passive)
+	if port == nil {
+		t.Fatalf("EPSV = %q, want a port", passive)
+	}
+	dataConn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", port[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dataConn.Close()
+
+	ftpCommand(t, control, 150, "RETR movie.mkv")
+	if err := dataConn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(dataConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := control.ReadResponse(226); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data[offset:]) {
+		t.Errorf("RETR after REST %d returned %d bytes, want the %d from the offset", offset, len(got), len(data)-offset)
+	}
+
+	ftpCommand(t, control, 221, "QUIT")
+}
+
+func TestFTPDataConnectionFromAnotherHost(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &ftpSession{passive: listener, remote: net.IPv4(192, 0, 2, 1)}
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if dataConn, err := s.dataConnection(); err == nil {
+		dataConn.Close()
+		t.Error("dataConnection() from another host succeeded, want an error")
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/Sioro-Neoku/go-peerflix --go-grpc_out=. --go-grpc_opt=module=github.com/Sioro-Neoku/go-peerflix peerflix.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/Sioro-Neoku/go-peerflix/peerflixpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcChunkSize is the size of the chunks ReadFile streams.
+const grpcChunkSize = 64 * 1024
+
+// grpcMutatingMethods change the state of the client, so without an
+// AuthToken they're only served to the local host, like the http api.
+var grpcMutatingMethods = map[string]bool{
+	peerflixpb.Peerflix_AddTorrent_FullMethodName: true,
+	peerflixpb.Peerflix_SelectFile_FullMethodName: true,
+}
+
+// grpcService implements the Peerflix service of peerflix.proto on a client.
+type grpcService struct {
+	peerflixpb.UnimplementedPeerflixServer
+	client *Client
+}
+
+// ServeGRPC serves the Peerflix grpc service on the listener.
+func (c *Client) ServeGRPC(listener net.Listener) error {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := c.authorizeGRPC(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, request)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := c.authorizeGRPC(stream.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	)
+	peerflixpb.RegisterPeerflixServer(server, &grpcService{client: c})
+	return server.Serve(listener)
+}
+
+// authorizeGRPC checks a call carries the AuthToken as a bearer token in its
+// authorization metadata. Without an AuthToken, every call is allowed from
+// the local host and only the ones not changing the client from elsewhere.
+func (c *Client) authorizeGRPC(ctx context.Context, method string) error {
+	if c.Config.AuthToken == "" {
+		if !grpcMutatingMethods[method] || isLoopbackPeer(ctx) {
+			return nil
+		}
+		return status.Error(codes.PermissionDenied, "only allowed from the local host without an auth token")
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, header := range md.Get("authorization") {
+			if strings.HasPrefix(header, "Bearer ") {
+				token = strings.TrimPrefix(header, "Bearer ")
+			}
+		}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.Config.AuthToken)) != 1 {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return nil
+}
+
+// isLoopbackPeer checks the grpc call comes from the local host.
+func isLoopbackPeer(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	addr, ok := p.Addr.(*net.TCPAddr)
+	return ok && addr.IP.IsLoopback()
+}
+
+// GetStatus returns the stats of the client.
+func (s *grpcService) GetStatus(ctx context.Context, request *peerflixpb.Empty) (*peerflixpb.Stats, error) {
+	return grpcStats(s.client.Stats()), nil
+}
+
+// ListFiles returns the files of the streamed torrent.
+func (s *grpcService) ListFiles(ctx context.Context, request *peerflixpb.Empty) (*peerflixpb.ListFilesResponse, error) {
+	response := &peerflixpb.ListFilesResponse{}
+	for _, file := range s.client.ListFiles() {
+		response.Files = append(response.Files, &peerflixpb.FileInfo{Index: int64(file.Index), Path: file.Path, Length: file.Length})
+	}
+	return response, nil
+}
+
+// AddTorrent adds a torrent next to the streamed one.
+func (s *grpcService) AddTorrent(ctx context.Context, request *peerflixpb.AddTorrentRequest) (*peerflixpb.AddTorrentResponse, error) {
+	t, err := s.client.AddTorrent(request.Torrent)
+	if clientError, ok := err.(ClientError); ok && clientError.Origin == ErrTooManyTorrents {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &peerflixpb.AddTorrentResponse{InfoHash: t.InfoHash().HexString(), Name: t.Name()}, nil
+}
+
+// SelectFile switches the streamed file, by index or by pattern.
+func (s *grpcService) SelectFile(ctx context.Context, request *peerflixpb.SelectFileRequest) (*peerflixpb.Empty, error) {
+	if !s.client.infoReady() {
+		return nil, status.Error(codes.FailedPrecondition, ErrMetadataNotReady.Error())
+	}
+	if request.Pattern != "" {
+		if err := s.client.SelectFileByPattern(request.Pattern); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return &peerflixpb.Empty{}, nil
+	}
+	if err := s.client.SetSelectedFile(int(request.Index)); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &peerflixpb.Empty{}, nil
+}
+
+// ReadFile streams a range of a file in chunks, waiting on the pieces like
+// the http stream does. Without an index, it reads the streamed file.
+func (s *grpcService) ReadFile(request *peerflixpb.ReadFileRequest, stream grpc.ServerStreamingServer[peerflixpb.Chunk]) error {
+	c := s.client
+	if !c.infoReady() {
+		return status.Error(codes.FailedPrecondition, ErrMetadataNotReady.Error())
+	}
+
+	files := c.files()
+	file := c.selectedFile()
+	if request.Index != nil {
+		index := int(request.GetIndex())
+		if index < 0 || index >= len(files) {
+			return status.Errorf(codes.InvalidArgument, "invalid file index %d, the torrent has %d files", index, len(files))
+		}
+		file = files[index]
+	}
+	if request.Offset < 0 || request.Offset > file.Length() || request.Length < 0 {
+		return status.Error(codes.OutOfRange, "invalid range")
+	}
+
+	c.streamStarted()
+	defer c.streamEnded()
+
+	entry, err := NewFileReader(c, file)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	entry.SetContext(stream.Context())
+	defer func() {
+		if err := entry.Close(); err != nil {
+			log.Printf("Error closing file reader: %s\n", err)
+		}
+	}()
+
+	if _, err := entry.Seek(request.Offset, os.SEEK_SET); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	var reader io.Reader = entry
+	if request.Length > 0 {
+		reader = io.LimitReader(entry, request.Length)
+	}
+
+	offset := request.Offset
+	buffer := make([]byte, grpcChunkSize)
+	for {
+		if err := stream.Context().Err(); err != nil {
+			return err
+		}
+
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			if sendErr := stream.Send(&peerflixpb.Chunk{Offset: offset, Data: buffer[:n]}); sendErr != nil {
+				return sendErr
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+}
+
+// grpcStats converts the stats of the client to their message.
+func grpcStats(stats Stats) *peerflixpb.Stats {
+	return &peerflixpb.Stats{
+		Name:             stats.Name,
+		BytesCompleted:   stats.BytesCompleted,
+		Length:           stats.Length,
+		Percentage:       stats.Percentage,
+		DownloadSpeed:    stats.DownloadSpeed,
+		Speed:            stats.Speed,
+		Connections:      int64(stats.Connections),
+		ReadyForPlayback: stats.ReadyForPlayback,
+		Buffering:        stats.Buffering,
+		DirectPlay:       stats.DirectPlay,
+		BufferedStart:    stats.BufferedStart,
+		BufferedEnd:      stats.BufferedEnd,
+		Error:            stats.Error,
+		AveragePieceTime: int64(stats.AveragePieceTime),
+		Peers: &peerflixpb.ConnectionStats{
+			Total:    int64(stats.Peers.Total),
+			Seeds:    int64(stats.Peers.Seeds),
+			Leechers: int64(stats.Peers.Leechers),
+			HalfOpen: int64(stats.Peers.HalfOpen),
+			Incoming: int64(stats.Peers.Incoming),
+			Outgoing: int64(stats.Peers.Outgoing),
+		},
+		Swarm: &peerflixpb.SwarmHealth{
+			Seeders:   int64(stats.Swarm.Seeders),
+			Leechers:  int64(stats.Swarm.Leechers),
+			Completed: int64(stats.Swarm.Completed),
+			Trackers:  int64(stats.Swarm.Trackers),
+		},
+		Checking:         stats.Checking,
+		CheckingProgress: stats.CheckingProgress,
+		InfoReady:        stats.InfoReady,
+		FileLength:       stats.FileLength,
+		DirectReads:      int64(stats.DirectReads),
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/Sioro-Neoku/go-peerflix/peerflixpb"
+	"github.com/anacrolix/torrent/metainfo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorizeGRPC(t *testing.T) {
+	local := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4000}
+	remote := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 5), Port: 4000}
+
+	tests := []struct {
+		token  string
+		method string
+		addr   net.Addr
+		sent   string
+		want   codes.Code
+	}{
+		{"", peerflixpb.Peerflix_GetStatus_FullMethodName, remote, "", codes.OK},
+		{"", peerflixpb.Peerflix_AddTorrent_FullMethodName, local, "", codes.OK},
+		{"", peerflixpb.Peerflix_AddTorrent_FullMethodName, remote, "", codes.PermissionDenied},
+		{"", peerflixpb.Peerflix_SelectFile_FullMethodName, remote, "", codes.PermissionDenied},
+		{"secret", peerflixpb.Peerflix_GetStatus_FullMethodName, local, "", codes.Unauthenticated},
+		{"secret", peerflixpb.Peerflix_AddTorrent_FullMethodName, remote, "wrong", codes.Unauthenticated},
+		{"secret", peerflixpb.Peerflix_AddTorrent_FullMethodName, remote, "secret", codes.OK},
+	}
+
+	for _, test := range tests {
+		c := &Client{Config: ClientConfig{AuthToken: test.token}}
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: test.addr})
+		if test.sent != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+test.sent))
+		}
+
+		if got := status.Code(c.authorizeGRPC(ctx, test.method)); got != test.want {
+			t.Errorf("authorizeGRPC(%s) with token %q from %s sending %q = %s, want %s", test.method, test.token, test.addr, test.sent, got, test.want)
+		}
+	}
+}
+
+func TestServeGRPCRequiresToken(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{Config: ClientConfig{AuthToken: "secret"}}
+	go c.ServeGRPC(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := peerflixpb.NewPeerflixClient(conn)
+	_, err = client.AddTorrent(context.Background(), &peerflixpb.AddTorrentRequest{Torrent: "/etc/passwd"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("AddTorrent without the token = %v, want %s", err, codes.Unauthenticated)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	_, err = client.AddTorrent(ctx, &peerflixpb.AddTorrentRequest{Torrent: "/etc/passwd"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("AddTorrent of a local path = %v, want %s", err, codes.InvalidArgument)
+	}
+}
+
+// newGRPCTestClient serves a client with two episodes over grpc, returning
+// the client and the data of the episodes.
+func newGRPCTestClient(t *testing.T) (*Client, peerflixpb.PeerflixClient, []byte) {
+	t.Helper()
+
+	data := make([]byte, 3*testPieceLength)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	c := seededTestClient(t, metainfo.Info{
+		Name: "Show",
+		Files: []metainfo.FileInfo{
+			{Path: []string{"S01E01.mkv"}, Length: testPieceLength + 100},
+			{Path: []string{"S01E02.mkv"}, Length: 2*testPieceLength - 100},
+		},
+	}, data)
+	c.Config.FileIndex = 1
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go c.ServeGRPC(listener)
+	t.Cleanup(func() { listener.Close() })
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return c, peerflixpb.NewPeerflixClient(conn), data
+}
+
+// readGRPCFile reads a whole ReadFile stream.
+func readGRPCFile(client peerflixpb.PeerflixClient, request *peerflixpb.ReadFileRequest) ([]byte, error) {
+	stream, err := client.ReadFile(context.Background(), request)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Offset != request.Offset+int64(len(data)) {
+			return nil, status.Errorf(codes.DataLoss, "chunk at %d after %d bytes", chunk.Offset, len(data))
+		}
+		data = append(data, chunk.Data...)
+	}
+}
+
+func TestGRPCReadFile(t *testing.T) {
+	_, client, data := newGRPCTestClient(t)
+	first := data[:testPieceLength+100]
+	second := data[testPieceLength+100:]
+	index := func(i int32) *int32 { return &i }
+
+	tests := []struct {
+		name    string
+		request *peerflixpb.ReadFileRequest
+		want    []byte
+		code    codes.Code
+	}{
+		{"streamed file", &peerflixpb.ReadFileRequest{}, second, codes.OK},
+		{"first file", &peerflixpb.ReadFileRequest{Index: index(0)}, first, codes.OK},
+		{"range", &peerflixpb.ReadFileRequest{Index: index(0), Offset: 50, Length: 100}, first[50:150], codes.OK},
+		{"range past the end", &peerflixpb.ReadFileRequest{Offset: int64(len(second)) - 10, Length: 100}, second[len(second)-10:], codes.OK},
+		{"negative index", &peerflixpb.ReadFileRequest{Index: index(-1)}, nil, codes.InvalidArgument},
+		{"index past the files", &peerflixpb.ReadFileRequest{Index: index(2)}, nil, codes.InvalidArgument},
+		{"offset past the end", &peerflixpb.ReadFileRequest{Offset: int64(len(second)) + 1}, nil, codes.OutOfRange},
+	}
+
+	for _, test := range tests {
+		got, err := readGRPCFile(client, test.request)
+		if status.Code(err) != test.code {
+			t.Errorf("%s: ReadFile() = %v, want %s", test.name, err, test.code)
+			continue
+		}
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("%s: ReadFile() returned %d bytes, want %d", test.name, len(got), len(test.want))
+		}
+	}
+}
+
+func TestGRPCListFiles(t *testing.T) {
+	_, client, _ := newGRPCTestClient(t)
+
+	response, err := client.ListFiles(context.Background(), &peerflixpb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []FileInfo
+	for _, file := range response.Files {
+		got = append(got, FileInfo{Index: int(file.Index), Path: file.Path, Length: file.Length})
+	}
+	want := []FileInfo{
+		{Index: 0, Path: "S01E01.mkv", Length: testPieceLength + 100},
+		{Index: 1, Path: "S01E02.mkv", Length: 2*testPieceLength - 100},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestGRPCGetStatus(t *testing.T) {
+	_, client, data := newGRPCTestClient(t)
+
+	stats, err := client.GetStatus(context.Background(), &peerflixpb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Name != "Show" || !stats.InfoReady || stats.Length != int64(len(data)) || stats.FileLength != 2*testPieceLength-100 {
+		t.Errorf("GetStatus() = name %q, info ready %v, length %d, file length %d, want Show, true, %d, %d",
+			stats.Name, stats.InfoReady, stats.Length, stats.FileLength, len(data), 2*testPieceLength-100)
+	}
+	if stats.BytesCompleted != int64(len(data)) || stats.Percentage != 100 {
+		t.Errorf("GetStatus() completed %d bytes, %v%%, want %d, 100%%", stats.BytesCompleted, stats.Percentage, len(data))
+	}
+}
+
+func TestGRPCSelectFile(t *testing.T) {
+	c, client, _ := newGRPCTestClient(t)
+
+	tests := []struct {
+		request *peerflixpb.SelectFileRequest
+		code    codes.Code
+		want    int
+	}{
+		{&peerflixpb.SelectFileRequest{Index: 0}, codes.OK, 0},
+		{&peerflixpb.SelectFileRequest{Pattern: `E02`}, codes.OK, 1},
+		{&peerflixpb.SelectFileRequest{Index: 5}, codes.InvalidArgument, 1},
+		{&peerflixpb.SelectFileRequest{Pattern: `E03`}, codes.InvalidArgument, 1},
+	}
+
+	for _, test := range tests {
+		_, err := client.SelectFile(context.Background(), test.request)
+		if status.Code(err) != test.code {
+			t.Errorf("SelectFile(%v) = %v, want %s", test.request, err, test.code)
+		}
+		if got := c.selectedIndex(); got != test.want {
+			t.Errorf("file selected after SelectFile(%v) = %d, want %d", test.request, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// defaultIndexTemplate is the page served on /ui when no IndexTemplate is
+// configured.
+const defaultIndexTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { background: #111; color: #eee; font-family: sans-serif; margin: 2em; }
+video { width: 100%; max-height: 80vh; background: #000; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<video src="{{.StreamURL}}" controls autoplay></video>
+<p id="status"></p>
+<script>
+setInterval(function () {
+	fetch({{.StatusURL}}).then(function (response) {
+		return response.json();
+	}).then(function (stats) {
+		document.getElementById("status").textContent =
+			stats.Percentage.toFixed(2) + "% - " + stats.Speed;
+	});
+}, 1000);
+</script>
+</body>
+</html>
+`
+
+// indexData are the variables available to the index page template.
+type indexData struct {
+	Name      string
+	StreamURL string
+	StatusURL string
+}
+
+// parseIndexTemplate parses the configured index page template, or the
+// default one.
+func parseIndexTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return template.New("index").Parse(defaultIndexTemplate)
+	}
+
+	return template.ParseFiles(path)
+}
+
+// GetIndex is an http handler serving the web ui.
+func (c *Client) GetIndex(w http.ResponseWriter, r *http.Request) {
+	data := indexData{
+		Name:      c.Torrent.Name(),
+		StreamURL: "/",
+		StatusURL: "/status",
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := c.index.Execute(w, data); err != nil {
+		log.Printf("Error rendering index page: %s\n", err)
+	}
+}
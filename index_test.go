@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.html")
+	custom := `<title>Acme: {{.Name}}</title><video src="{{.StreamURL}}"></video><a href="{{.StatusURL}}">status</a>`
+	if err := os.WriteFile(path, []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"", []string{"<title>movie.mkv</title>", `<video src="/" controls autoplay>`, `fetch("/status")`}},
+		{path, []string{`<title>Acme: movie.mkv</title><video src="/"></video><a href="/status">status</a>`}},
+	}
+
+	c := newTestClient(t, 1)
+	for _, test := range tests {
+		index, err := parseIndexTemplate(test.path)
+		if err != nil {
+			t.Fatalf("parseIndexTemplate(%q): %s", test.path, err)
+		}
+		c.index = index
+
+		w := httptest.NewRecorder()
+		c.GetIndex(w, httptest.NewRequest("GET", "/ui", nil))
+		if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+			t.Errorf("template %q: Content-Type = %q, want text/html", test.path, got)
+		}
+		for _, want := range test.want {
+			if body := w.Body.String(); !strings.Contains(body, want) {
+				t.Errorf("template %q rendered %q, want it to contain %q", test.path, body, want)
+			}
+		}
+	}
+}
+
+func TestIndexTemplateInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(path, []byte("<title>{{.Name</title>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{path, filepath.Join(t.TempDir(), "missing.html")} {
+		if _, err := parseIndexTemplate(path); err == nil {
+			t.Errorf("parseIndexTemplate(%q) succeeded, want an error", path)
+		}
+	}
+}
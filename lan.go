@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/anacrolix/torrent"
+)
+
+// localNetworks are the private, loopback and link-local address ranges.
+var localNetworks = parseNetworks(
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"fc00::/7",
+	"fe80::/10",
+	"::1/128",
+)
+
+func parseNetworks(cidrs ...string) (networks []*net.IPNet) {
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return
+}
+
+// isLANAddress checks if an ip belongs to the local network.
+func isLANAddress(ip net.IP) bool {
+	for _, network := range localNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// seedToLAN waits for the download to complete before blocking public peers,
+// dropping the ones already connected, and enabling uploads.
+func (c *Client) seedToLAN() {
+	select {
+	case <-c.torrentCompleted:
+	case <-c.closing:
+		return
+	}
+
+	log.Println("Download complete, seeding to the local network only")
+	c.blocklist.lanOnly.Store(true)
+	c.dropPublicPeers()
+	if c.Config.SeedSchedule.Enabled() {
+		c.followSeedSchedule()
+		return
+	}
+	c.setUploading(true)
+}
+
+// dropPublicPeers closes the connections to peers outside the local network,
+// as the blocklist only keeps new ones out.
+func (c *Client) dropPublicPeers() {
+	for _, t := range c.Client.Torrents() {
+		for _, conn := range t.PeerConns() {
+			if !isLANAddress(addrIP(conn.RemoteAddr)) {
+				conn.Close()
+			}
+		}
+	}
+}
+
+// addrIP returns the ip of a peer address, or nil if it has none.
+func addrIP(addr fmt.Stringer) net.IP {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+// setUploading allows or disallows uploading for every torrent of the client.
+func (c *Client) setUploading(upload bool) {
+	c.mutex.Lock()
+	c.uploading = upload
+	c.mutex.Unlock()
+
+	for _, t := range c.Client.Torrents() {
+		c.applyUploading(t)
+	}
+}
+
+// applyUploading allows or disallows uploading a torrent, following
+// setUploading.
+func (c *Client) applyUploading(t *torrent.Torrent) {
+	c.mutex.Lock()
+	upload := c.uploading
+	c.mutex.Unlock()
+
+	if upload {
+		t.AllowDataUpload()
+	} else {
+		t.DisallowDataUpload()
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsLANAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.168.1.10", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.32.0.1", false},
+		{"127.0.0.1", true},
+		{"169.254.10.10", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::1", true},
+		{"8.8.8.8", false},
+		{"2001:db8::1", false},
+	}
+
+	for _, test := range tests {
+		if got := isLANAddress(net.ParseIP(test.ip)); got != test.want {
+			t.Errorf("isLANAddress(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+}
+
+func TestAddrIP(t *testing.T) {
+	tests := []struct {
+		addr net.Addr
+		want net.IP
+	}{
+		{&net.TCPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 6881}, net.IPv4(192, 168, 1, 10)},
+		{&net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 6881}, net.ParseIP("fe80::1")},
+		{&net.UnixAddr{Name: "/tmp/peer", Net: "unix"}, nil},
+	}
+
+	for _, test := range tests {
+		if got := addrIP(test.addr); !got.Equal(test.want) {
+			t.Errorf("addrIP(%s) = %s, want %s", test.addr, got, test.want)
+		}
+	}
+}
+
+func TestConnectionBlocklistLANOnly(t *testing.T) {
+	blocklist := &connectionBlocklist{}
+	public := net.ParseIP("8.8.8.8")
+	lan := net.ParseIP("192.168.1.10")
+
+	if _, blocked := blocklist.Lookup(public); blocked {
+		t.Error("public peer blocked before switching to the LAN")
+	}
+
+	blocklist.lanOnly.Store(true)
+	if _, blocked := blocklist.Lookup(public); !blocked {
+		t.Error("public peer allowed after switching to the LAN")
+	}
+	if _, blocked := blocklist.Lookup(lan); blocked {
+		t.Error("LAN peer blocked after switching to the LAN")
+	}
+}
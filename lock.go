@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// ErrDataDirLocked is returned when another process is downloading the same
+// torrent into the data directory.
+var ErrDataDirLocked = errors.New("torrent is in use by another process sharing the data directory")
+
+// lockData makes sure we're the only process writing the torrent's data.
+func (c *Client) lockData(infoHash metainfo.Hash) (err error) {
+	if err = os.MkdirAll(c.Config.DataDir, 0755); err != nil {
+		return
+	}
+
+	path := filepath.Join(c.Config.DataDir, ".peerflix-"+infoHash.HexString()+".lock")
+	c.lock, err = lockFile(path)
+	return
+}
+
+// unlockData releases the lock taken by lockData, if any.
+func (c *Client) unlockData() {
+	if c.lock == nil {
+		return
+	}
+	if err := unlockFile(c.lock); err != nil {
+		log.Printf("Error releasing data directory lock: %s\n", err)
+	}
+	c.lock = nil
+}
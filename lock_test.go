@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestLockData(t *testing.T) {
+	dir := t.TempDir()
+	infoHash := metainfo.NewHashFromHex("c9e15763f722f23e98a29decdfae341b98d53056")
+	first := &Client{Config: ClientConfig{DataDir: dir}}
+	second := &Client{Config: ClientConfig{DataDir: dir}}
+
+	if err := first.lockData(infoHash); err != nil {
+		t.Fatalf("first lock: %v", err)
+	}
+	if err := second.lockData(infoHash); err != ErrDataDirLocked {
+		t.Fatalf("second lock = %v, want %v", err, ErrDataDirLocked)
+	}
+	if err := second.lockData(metainfo.NewHashFromHex("0000000000000000000000000000000000000001")); err != nil {
+		t.Fatalf("lock of another torrent: %v", err)
+	}
+	second.unlockData()
+
+	first.unlockData()
+	first.unlockData()
+	if err := second.lockData(infoHash); err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	second.unlockData()
+}
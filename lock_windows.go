@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// lockFile creates the file exclusively. A lock left behind by a crash has to
+// be removed manually.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if os.IsExist(err) {
+		return nil, ErrDataDirLocked
+	}
+	return file, err
+}
+
+// unlockFile releases a lock taken with lockFile.
+func unlockFile(file *os.File) error {
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(file.Name())
+}
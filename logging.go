@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// logTimestamp matches the date and time the std log flags prefix lines with.
+var logTimestamp = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?(\d{2}:\d{2}:\d{2}(\.\d+)? )?`)
+
+// throttledWriter collapses a log line repeated within the interval, logging
+// it once followed by how many times it was repeated. Lines are compared
+// without their timestamp, so the log keeps its usual flags.
+type throttledWriter struct {
+	out      io.Writer
+	interval time.Duration
+
+	mutex   sync.Mutex
+	last    string
+	since   time.Time
+	repeats int
+	// timer reports the repeats once the interval is over, even if nothing
+	// else is logged.
+	timer *time.Timer
+}
+
+func newThrottledWriter(out io.Writer, interval time.Duration) *throttledWriter {
+	return &throttledWriter{
+		out:      out,
+		interval: interval,
+	}
+}
+
+// Write writes a log line, unless it's a repeat of the last one.
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := time.Now()
+	line := logTimestamp.ReplaceAllString(string(p), "")
+
+	if line == w.last {
+		w.repeats++
+		if elapsed := now.Sub(w.since); elapsed < w.interval {
+			if w.timer == nil {
+				w.timer = time.AfterFunc(w.interval-elapsed, w.flushRepeats)
+			}
+			return len(p), nil
+		}
+		return len(p), w.flush(now)
+	}
+
+	if err := w.flush(now); err != nil {
+		return 0, err
+	}
+
+	w.last = line
+	w.since = now
+	if _, err := w.out.Write(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close reports the repeats not logged yet, before exiting.
+func (w *throttledWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.flush(time.Now())
+}
+
+func (w *throttledWriter) flushRepeats() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.timer = nil
+	w.flush(time.Now())
+}
+
+// flush reports the repeats of the last line.
+func (w *throttledWriter) flush(now time.Time) error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.repeats == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w.out, "%sLast message repeated %d times\n", now.Format("2006/01/02 15:04:05 "), w.repeats)
+	w.repeats = 0
+	w.since = now
+
+	return err
+}
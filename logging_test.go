@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the flush timer.
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func TestThrottledWriter(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  []string
+	}{
+		{
+			[]string{"2024/01/02 10:00:00 Error: a\n", "2024/01/02 10:00:01 Error: b\n"},
+			[]string{"2024/01/02 10:00:00 Error: a", "2024/01/02 10:00:01 Error: b"},
+		},
+		{
+			[]string{"2024/01/02 10:00:00 Error: a\n", "2024/01/02 10:00:01 Error: a\n", "2024/01/02 10:00:02 Error: a\n", "2024/01/02 10:00:03 Error: b\n"},
+			[]string{"2024/01/02 10:00:00 Error: a", "Last message repeated 2 times", "2024/01/02 10:00:03 Error: b"},
+		},
+		{
+			[]string{"10:00:00.123456 Error: a\n", "10:00:00.654321 Error: a\n"},
+			[]string{"10:00:00.123456 Error: a", "Last message repeated 1 times"},
+		},
+	}
+
+	for _, test := range tests {
+		var out syncBuffer
+		w := newThrottledWriter(&out, time.Hour)
+		for _, line := range test.lines {
+			if _, err := w.Write([]byte(line)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if len(got) != len(test.want) {
+			t.Errorf("wrote %q, want %q", got, test.want)
+			continue
+		}
+		for i := range got {
+			if !strings.HasSuffix(got[i], test.want[i]) {
+				t.Errorf("line %d = %q, want %q", i, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestThrottledWriterFlushesOnTimer(t *testing.T) {
+	var out syncBuffer
+	w := newThrottledWriter(&out, 20*time.Millisecond)
+	w.Write([]byte("2024/01/02 10:00:00 Error: a\n"))
+	w.Write([]byte("2024/01/02 10:00:00 Error: a\n"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Last message repeated 1 times") {
+		if time.Now().After(deadline) {
+			t.Fatalf("repeats never reported, wrote %q", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// logSubscriberBuffer is how many lines a slow /logs subscriber can fall
+// behind before lines are dropped for it.
+const logSubscriberBuffer = 256
+
+// logBroadcaster writes the log to out, and feeds every line to the
+// subscribers of /logs.
+type logBroadcaster struct {
+	out io.Writer
+
+	mutex       sync.Mutex
+	subscribers map[chan string]struct{}
+}
+
+// logs broadcasts the log of the program, once main routes it through.
+var logs *logBroadcaster
+
+func newLogBroadcaster(out io.Writer) *logBroadcaster {
+	return &logBroadcaster{
+		out:         out,
+		subscribers: make(map[chan string]struct{}),
+	}
+}
+
+// Write writes a log line, sending it to the subscribers that keep up.
+func (b *logBroadcaster) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+
+	b.mutex.Lock()
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- line:
+		default:
+		}
+	}
+	b.mutex.Unlock()
+
+	return b.out.Write(p)
+}
+
+func (b *logBroadcaster) subscribe() chan string {
+	subscriber := make(chan string, logSubscriberBuffer)
+
+	b.mutex.Lock()
+	b.subscribers[subscriber] = struct{}{}
+	b.mutex.Unlock()
+
+	return subscriber
+}
+
+func (b *logBroadcaster) unsubscribe(subscriber chan string) {
+	b.mutex.Lock()
+	delete(b.subscribers, subscriber)
+	b.mutex.Unlock()
+}
+
+// authorized checks the request carries the AuthToken, as a bearer token or
+// a token parameter. Without an AuthToken, every request is.
+func (c *Client) authorized(r *http.Request) bool {
+	if c.Config.AuthToken == "" {
+		return true
+	}
+
+	token := r.FormValue("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.Config.AuthToken)) == 1
+}
+
+// authorizedToChange checks a request changing the state of the client is
+// allowed. Without an AuthToken, only requests from the same host are.
+func (c *Client) authorizedToChange(r *http.Request) bool {
+	if c.Config.AuthToken != "" {
+		return c.authorized(r)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// GetLogs is an http handler streaming the log as server-sent events.
+func (c *Client) GetLogs(w http.ResponseWriter, r *http.Request) {
+	if !c.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if logs == nil || !ok {
+		http.Error(w, "log streaming isn't available", http.StatusNotImplemented)
+		return
+	}
+
+	subscriber := logs.subscribe()
+	defer logs.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	for {
+		select {
+		case line := <-subscriber:
+			for _, data := range strings.Split(line, "\n") {
+				fmt.Fprintf(w, "data: %s\n", data)
+			}
+			fmt.Fprint(w, "\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetLogs(t *testing.T) {
+	var out syncBuffer
+	logs = newLogBroadcaster(&out)
+	defer func() { logs = nil }()
+	c := &Client{Config: ClientConfig{AuthToken: "secret"}}
+	server := httptest.NewServer(http.HandlerFunc(c.GetLogs))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /logs without the token = %d, want %d", response.StatusCode, http.StatusUnauthorized)
+	}
+
+	request, err := http.NewRequest("GET", server.URL+"/logs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if got := response.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+
+	// The headers are sent once subscribed.
+	logger := log.New(logs, "", 0)
+	logger.Printf("Error downloading piece: %s\n", "timeout")
+	logger.Print("first\nsecond")
+
+	want := []string{"data: Error downloading piece: timeout", "", "data: first", "data: second", ""}
+	lines := bufio.NewScanner(response.Body)
+	for _, want := range want {
+		if !lines.Scan() {
+			t.Fatalf("event stream ended: %v", lines.Err())
+		}
+		if got := lines.Text(); got != want {
+			t.Errorf("event line %q, want %q", got, want)
+		}
+	}
+	if got := out.String(); !strings.Contains(got, "Error downloading piece: timeout\n") {
+		t.Errorf("log written %q, want the lines too", got)
+	}
+}
+
+func TestLogBroadcasterDropsForSlowSubscribers(t *testing.T) {
+	var out syncBuffer
+	b := newLogBroadcaster(&out)
+	subscriber := b.subscribe()
+
+	// Nobody reads, yet writing doesn't block.
+	for i := 0; i < logSubscriberBuffer+10; i++ {
+		fmt.Fprintf(b, "line %d\n", i)
+	}
+	if len(subscriber) != logSubscriberBuffer {
+		t.Errorf("%d lines buffered for the subscriber, want %d", len(subscriber), logSubscriberBuffer)
+	}
+	if first := <-subscriber; first != "line 0" {
+		t.Errorf("first line %q, want %q", first, "line 0")
+	}
+
+	b.unsubscribe(subscriber)
+	fmt.Fprintln(b, "after")
+	if len(subscriber) != logSubscriberBuffer-1 {
+		t.Error("line sent to an unsubscribed subscriber")
+	}
+}
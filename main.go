@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"hash/fnv"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -11,6 +15,8 @@ import (
 	"strconv"
 	"syscall"
 	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
 )
 
 // Exit statuses.
@@ -22,30 +28,177 @@ const (
 
 func main() {
 	// Parse flags.
-	var port int
 	var vlc *bool
-	var seed *bool
+	var logThrottle *time.Duration
+	cfg := NewClientConfig()
 
 	vlc = flag.Bool("vlc", false, "Open vlc to play the file")
-	flag.IntVar(&port, "port", 8080, "Port to stream the video on")
-	seed = flag.Bool("seed", false, "Seed after finished downloading")
+	logThrottle = flag.Duration("log-throttle", time.Minute, "Collapse repeated log messages within this interval (0 disables it)")
+	flag.BoolVar(&cfg.QRCode, "qr", cfg.QRCode, "Read the magnet link or url from a QR code image")
+	flag.IntVar(&cfg.Port, "port", cfg.Port, "Port to stream the video on")
+	flag.IntVar(&cfg.PortRange, "port-range", cfg.PortRange, "Number of following ports to try if the port is taken")
+	flag.BoolVar(&cfg.PortFromInfoHash, "port-from-infohash", cfg.PortFromInfoHash, "Pick a stable port within the port range based on the infohash")
+	flag.BoolVar(&cfg.Seed, "seed", cfg.Seed, "Seed after finished downloading")
+	flag.Int64Var(&cfg.UploadRateLimit, "upload-rate", cfg.UploadRateLimit, "Maximum upload speed in bytes per second (0 is unlimited)")
+	flag.Int64Var(&cfg.DownloadRateLimit, "download-rate", cfg.DownloadRateLimit, "Maximum download speed in bytes per second (0 is unlimited)")
+	flag.BoolVar(&cfg.LANOnlySeed, "lan-only-seed", cfg.LANOnlySeed, "Only seed to peers on the local network")
+	flag.StringVar(&cfg.ExpectedInfoHash, "infohash", cfg.ExpectedInfoHash, "Refuse the torrent unless it has this hex infohash")
+	flag.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "Directory to store the downloaded data in")
+	flag.BoolVar(&cfg.PrivateMode, "private", cfg.PrivateMode, "Only find peers through the trackers, required for private torrents")
+	flag.BoolVar(&cfg.ForceRecheck, "recheck", cfg.ForceRecheck, "Hash the existing data again instead of trusting the previous run")
+	flag.Var(&cfg.SeedSchedule, "seed-schedule", "Only upload within this time of day when seeding, like 01:00-07:00")
+	flag.BoolVar(&cfg.PersistPriorities, "persist-priorities", cfg.PersistPriorities, "Resume downloading where the previous run left off")
+	flag.BoolVar(&cfg.ServeWhileChecking, "serve-while-checking", cfg.ServeWhileChecking, "Stream the existing data while -recheck hashes it")
+	flag.Var(cfg.StorageRoutes, "storage-route", "Store files with an extension elsewhere, as .ext=directory (repeatable)")
+	flag.DurationVar(&cfg.DataTTL, "data-ttl", cfg.DataTTL, "Remove the data after it's been complete and idle for this long (0 keeps it)")
+	flag.BoolVar(&cfg.AutoPauseWhenIdle, "auto-pause", cfg.AutoPauseWhenIdle, "Stop downloading while nothing is streamed, resuming when a stream starts")
+	flag.DurationVar(&cfg.AutoPauseGrace, "auto-pause-grace", cfg.AutoPauseGrace, "How long nothing is streamed before -auto-pause stops downloading")
+	flag.DurationVar(&cfg.ShutdownDrain, "shutdown-drain", cfg.ShutdownDrain, "How long the streams can finish after a shutdown is requested on /shutdown")
+	flag.DurationVar(&cfg.MaxRuntime, "max-runtime", cfg.MaxRuntime, "Exit after running for this long (0 runs forever)")
+	flag.BoolVar(&cfg.AggressiveMetadata, "aggressive-metadata", cfg.AggressiveMetadata, "Look harder for the metadata of magnets, on public trackers and the DHT")
+	flag.DurationVar(&cfg.MetadataTimeout, "metadata-timeout", cfg.MetadataTimeout, "Give up if the torrent metadata isn't received in time (0 waits forever)")
+	flag.BoolVar(&cfg.VerifyReads, "verify-reads", cfg.VerifyReads, "Only stream data from pieces that passed their hash check")
+	flag.StringVar(&cfg.AudioLanguage, "audio-language", cfg.AudioLanguage, "Audio language to keep when transcoding, like eng")
+	flag.StringVar(&cfg.SubtitleLanguage, "subtitle-language", cfg.SubtitleLanguage, "Language of the subtitles in the torrent to serve, like eng")
+	flag.StringVar(&cfg.DefaultExtension, "default-extension", cfg.DefaultExtension, "Extension to serve files without one as, like .mp4")
+	flag.BoolVar(&cfg.Transcode, "transcode", cfg.Transcode, "Serve the file remuxed with ffmpeg on /transcode (needs ffmpeg)")
+	flag.IntVar(&cfg.MaxTranscodes, "max-transcodes", cfg.MaxTranscodes, "Maximum number of ffmpeg processes running at once (0 is unlimited)")
+	flag.BoolVar(&cfg.Preview, "preview", cfg.Preview, "Serve a low bitrate transcode on /preview while the file buffers (needs ffmpeg)")
+	flag.BoolVar(&cfg.AudioOnly, "audio", cfg.AudioOnly, "Serve only the audio of the file on /audio (needs ffmpeg)")
+	flag.IntVar(&cfg.PreviewBitrate, "preview-bitrate", cfg.PreviewBitrate, "Video bitrate of the preview in kbit/s")
+	flag.IntVar(&cfg.PreviewHeight, "preview-height", cfg.PreviewHeight, "Maximum height of the preview in lines")
+	flag.BoolVar(&cfg.Fallback, "fallback", cfg.Fallback, "Serve an image with the error instead of the file when the torrent failed")
+	flag.StringVar(&cfg.FallbackAsset, "fallback-asset", cfg.FallbackAsset, "Video or image to serve instead of the built-in fallback")
+	flag.BoolVar(&cfg.ProgressiveDownload, "progressive", cfg.ProgressiveDownload, "Read further ahead for requests of the whole file")
+	flag.BoolVar(&cfg.DirectCompleted, "direct", cfg.DirectCompleted, "Serve completed files straight from disk")
+	flag.IntVar(&cfg.ResponseBufferSize, "response-buffer", cfg.ResponseBufferSize, "Size in bytes of the buffer used to send the file")
+	flag.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "Show more details about the torrent")
+	flag.StringVar(&cfg.ExcludePattern, "exclude", cfg.ExcludePattern, "Never download files whose path matches this regular expression")
+	flag.BoolVar(&cfg.PreferFastPeers, "prefer-fast-peers", cfg.PreferFastPeers, "Drop the slowest peers while playback is buffering")
+	flag.StringVar(&cfg.IndexTemplate, "index-template", cfg.IndexTemplate, "HTML template file replacing the web ui on /ui")
+	flag.StringVar(&cfg.TMDbAPIKey, "tmdb-api-key", cfg.TMDbAPIKey, "The Movie Database api key, to show the poster and synopsis on /metadata")
+	flag.BoolVar(&cfg.SpeedInBits, "bits", cfg.SpeedInBits, "Show speeds in bits per second instead of bytes")
+	flag.BoolVar(&cfg.Bell, "bell", cfg.Bell, "Ring the terminal bell when the stream is ready")
+	flag.BoolVar(&cfg.RenderOnChange, "render-on-change", cfg.RenderOnChange, "Only redraw the cli when what it shows changed")
+	flag.StringVar(&cfg.OnCompleteWebhook, "on-complete-webhook", cfg.OnCompleteWebhook, "Url to post the name, infohash, size and path of the file to once it's downloaded")
+	flag.StringVar(&cfg.BellCommand, "bell-command", cfg.BellCommand, "Command to run when the stream is ready, like one playing a sound")
+	flag.IntVar(&cfg.RenderWidth, "width", cfg.RenderWidth, "Width of the cli output (0 detects the terminal width)")
+	flag.IntVar(&cfg.MaxPiecesAhead, "max-pieces-ahead", cfg.MaxPiecesAhead, "Only request this many pieces past the playback position (0 downloads everything)")
+	flag.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "Address to serve line protocol metrics on, like :2003")
+	flag.DurationVar(&cfg.MetricsInterval, "metrics-interval", cfg.MetricsInterval, "Interval between metrics")
+	flag.DurationVar(&cfg.WebSocketInterval, "ws-interval", cfg.WebSocketInterval, "Interval between the stats pushed on /ws")
+	flag.Int64Var(&cfg.Readahead, "readahead", cfg.Readahead, "Bytes to read ahead of the stream (0 reads ahead a share of the file)")
+	flag.Float64Var(&cfg.ReadyPercentage, "ready-percentage", cfg.ReadyPercentage, "Percentage of the torrent downloaded before it's ready for playback")
+	flag.BoolVar(&cfg.BackgroundRarest, "background-rarest", cfg.BackgroundRarest, "Download the rarest pieces past -max-pieces-ahead while the stream is buffered")
+	flag.Uint64Var(&cfg.MemoryLimit, "memory-limit", cfg.MemoryLimit, "Reduce the readahead when using more than this many bytes of memory (0 disables it)")
+	flag.BoolVar(&cfg.PerFileMetrics, "per-file-metrics", cfg.PerFileMetrics, "Add the progress of every file to the prometheus metrics on /metrics")
+	flag.DurationVar(&cfg.PriorityDebounce, "priority-debounce", cfg.PriorityDebounce, "Coalesce piece priority updates while seeking within this interval")
+	flag.Int64Var(&cfg.DropBehindBytes, "drop-behind", cfg.DropBehindBytes, "Release pieces further than this many bytes behind the playback position (0 keeps them)")
+	flag.StringVar(&cfg.FTPAddr, "ftp-addr", cfg.FTPAddr, "Address to serve the file over FTP on, like :2121")
+	flag.StringVar(&cfg.FTPUser, "ftp-user", cfg.FTPUser, "FTP user name (anonymous when empty)")
+	flag.StringVar(&cfg.FTPPassword, "ftp-password", cfg.FTPPassword, "FTP password")
+	flag.IntVar(&cfg.FileIndex, "file", cfg.FileIndex, "Index of the file to stream (negative picks the largest)")
+	flag.BoolVar(&cfg.CloseOnFileSwitch, "close-on-file-switch", cfg.CloseOnFileSwitch, "Close the streams of the previous file when another file is selected")
+	flag.BoolVar(&cfg.PrefetchNext, "prefetch-next", cfg.PrefetchNext, "Download the start of the next video while the streamed one is buffered")
+	flag.Int64Var(&cfg.PrefetchBytes, "prefetch-bytes", cfg.PrefetchBytes, "Bytes of the next video downloaded by -prefetch-next")
+	flag.BoolVar(&cfg.PlaylistAllFiles, "playlist-all", cfg.PlaylistAllFiles, "List every video of the torrent on /playlist.m3u")
+	flag.StringVar(&cfg.AdvertisedHost, "advertised-host", cfg.AdvertisedHost, "host:port other devices reach the stream on, for session links")
+	flag.StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "Address to serve the grpc service on, like :9090")
+	flag.IntVar(&cfg.MaxTorrents, "max-torrents", cfg.MaxTorrents, "Most torrents the client has, including the streamed one (0 doesn't limit them)")
+	flag.BoolVar(&cfg.EvictTorrents, "evict-torrents", cfg.EvictTorrents, "Remove the least recently streamed torrent when -max-torrents is reached, instead of refusing new ones")
+	flag.StringVar(&cfg.CORSOrigin, "cors-origin", cfg.CORSOrigin, "Origin allowed to use the http api from a browser, * allows any")
+	flag.StringVar(&cfg.AuthToken, "auth-token", cfg.AuthToken, "Token required by the protected endpoints, like /logs")
+	flag.BoolVar(&cfg.DLNA, "dlna", cfg.DLNA, "Advertise the stream to DLNA/UPnP devices on the network")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [magnet url|infohash|torrent path|torrent url|session url]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 	if len(flag.Args()) == 0 {
 		flag.Usage()
 		os.Exit(exitNoTorrentProvided)
 	}
+	cfg.TorrentPath = flag.Arg(0)
+
+	// Stream the log on /logs, and keep repeated errors from flooding it.
+	logs = newLogBroadcaster(os.Stderr)
+	log.SetOutput(logs)
+	var throttled *throttledWriter
+	if *logThrottle > 0 {
+		throttled = newThrottledWriter(logs, *logThrottle)
+		log.SetOutput(throttled)
+	}
 
 	// Start up the torrent client.
-	client, err := NewClient(flag.Arg(0), port, *seed)
+	client, err := NewClient(cfg)
 	if err != nil {
-		log.Fatalf(err.Error())
+		log.Fatal(err)
 		os.Exit(exitErrorInClient)
 	}
 
+	// Bind the http port, moving up the range if it's taken.
+	var start int
+	if cfg.PortFromInfoHash {
+		start = infoHashPortOffset(client.Torrent.InfoHash(), cfg.PortRange)
+	}
+	listener, err := listen(cfg.Port, cfg.PortRange, start)
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(exitErrorInClient)
+	}
+	client.Port = listener.Addr().(*net.TCPAddr).Port
+
+	// Serve metrics.
+	if cfg.MetricsAddr != "" {
+		metricsListener, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			log.Fatal(ClientError{Type: "binding metrics address", Origin: err})
+			os.Exit(exitErrorInClient)
+		}
+		go client.ServeMetrics(metricsListener)
+	}
+
+	// Serve the file over ftp.
+	if cfg.FTPAddr != "" {
+		ftpListener, err := net.Listen("tcp", cfg.FTPAddr)
+		if err != nil {
+			log.Fatal(ClientError{Type: "binding ftp address", Origin: err})
+			os.Exit(exitErrorInClient)
+		}
+		go client.ServeFTP(ftpListener)
+	}
+
+	// Serve the grpc service.
+	if cfg.GRPCAddr != "" {
+		grpcListener, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			log.Fatal(ClientError{Type: "binding grpc address", Origin: err})
+			os.Exit(exitErrorInClient)
+		}
+		go func() {
+			if err := client.ServeGRPC(grpcListener); err != nil {
+				log.Printf("Error serving grpc: %s\n", err)
+			}
+		}()
+	}
+
+	// Advertise to DLNA devices.
+	var dlna *DLNAServer
+	if cfg.DLNA {
+		if dlna, err = NewDLNAServer(client); err != nil {
+			log.Fatal(err)
+			os.Exit(exitErrorInClient)
+		}
+		go dlna.Advertise()
+	}
+
 	// Http handler.
+	server := &http.Server{Handler: client.Handler(http.DefaultServeMux)}
 	go func() {
-		http.HandleFunc("/", client.GetFile)
-		log.Fatal(http.ListenAndServe(":"+strconv.Itoa(port), nil))
+		client.RegisterRoutes(http.DefaultServeMux)
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
 	}()
 
 	// Open vlc to play.
@@ -54,7 +207,7 @@ func main() {
 			for !client.ReadyForPlayback() {
 				time.Sleep(time.Second)
 			}
-			playInVlc(port)
+			playInVlc(client.Port)
 		}()
 	}
 
@@ -66,14 +219,38 @@ func main() {
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
+	exit := func(reason string) {
+		log.Printf("Exiting: %s\n", reason)
+		if dlna != nil {
+			dlna.Close()
+		}
+		client.Close()
+		if throttled != nil {
+			throttled.Close()
+		}
+		os.Exit(0)
+	}
 	go func(interruptChannel chan os.Signal) {
-		for range interruptChannel {
-			log.Println("Exiting...")
-			client.Close()
-			os.Exit(0)
+		for sig := range interruptChannel {
+			exit("received " + sig.String())
 		}
 	}(interruptChannel)
 
+	// Stop accepting connections on /shutdown, and exit once the streams
+	// are done.
+	go func() {
+		<-client.ShutdownRequested()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownDrain)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Error draining streams: %s\n", err)
+		}
+		exit("shutdown requested")
+	}()
+
+	// Stop after the maximum run time.
+	exitAfter(cfg.MaxRuntime, exit)
+
 	// Cli render loop.
 	for {
 		client.Render()
@@ -94,3 +271,35 @@ func playInVlc(port int) {
 		log.Printf("Error opening vlc: %s\n", err)
 	}
 }
+
+// exitAfter exits once the maximum run time is reached, unless it's zero.
+func exitAfter(maxRuntime time.Duration, exit func(reason string)) *time.Timer {
+	if maxRuntime <= 0 {
+		return nil
+	}
+
+	return time.AfterFunc(maxRuntime, func() {
+		exit("maximum run time of " + maxRuntime.String() + " reached")
+	})
+}
+
+// listen binds the first free port between port and port+portRange, starting
+// the search at port+start and wrapping around.
+func listen(port, portRange, start int) (listener net.Listener, err error) {
+	for i := 0; i <= portRange; i++ {
+		offset := (start + i) % (portRange + 1)
+		if listener, err = net.Listen("tcp", ":"+strconv.Itoa(port+offset)); err == nil {
+			return listener, nil
+		}
+	}
+
+	return nil, ClientError{Type: "binding http port", Origin: err}
+}
+
+// infoHashPortOffset derives a stable offset within the port range from the
+// infohash, so a torrent is always served on the same port.
+func infoHashPortOffset(infoHash metainfo.Hash, portRange int) int {
+	hash := fnv.New32a()
+	hash.Write(infoHash[:])
+	return int(hash.Sum32() % uint32(portRange+1))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// takenPort returns a bound port, released when the test ends.
+func takenPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestListenMovesUpTheRange(t *testing.T) {
+	port := takenPort(t)
+
+	if listener, err := listen(port, 0, 0); err == nil {
+		listener.Close()
+		t.Errorf("listen(%d) without a range succeeded on a taken port", port)
+	}
+
+	listener, err := listen(port, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if got := listener.Addr().(*net.TCPAddr).Port; got <= port || got > port+3 {
+		t.Errorf("listen(%d) in a range of 3 bound %d, want one of the following ports", port, got)
+	}
+}
+
+func TestInfoHashPortOffset(t *testing.T) {
+	first := metainfo.NewHashFromHex("0123456789abcdef0123456789abcdef01234567")
+	second := metainfo.NewHashFromHex("76543210fedcba9876543210fedcba9876543210")
+
+	offset := infoHashPortOffset(first, 100)
+	if offset < 0 || offset > 100 {
+		t.Errorf("infoHashPortOffset() = %d, want within the range of 100", offset)
+	}
+	if again := infoHashPortOffset(first, 100); again != offset {
+		t.Errorf("infoHashPortOffset() of the same infohash = %d, then %d", offset, again)
+	}
+	if other := infoHashPortOffset(second, 100); other == offset {
+		t.Errorf("infoHashPortOffset() of both infohashes = %d, want them spread", offset)
+	}
+	if got := infoHashPortOffset(first, 0); got != 0 {
+		t.Errorf("infoHashPortOffset() without a range = %d, want 0", got)
+	}
+}
+
+func TestListenProbesForwardFromTheOffset(t *testing.T) {
+	port := takenPort(t)
+
+	// The offset's port is taken, so the next one is bound, wrapping around
+	// past the end of the range.
+	listener, err := listen(port-2, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if got := listener.Addr().(*net.TCPAddr).Port; got != port-2 {
+		t.Errorf("listen() from the taken offset bound %d, want %d", got, port-2)
+	}
+}
+
+func TestExitAfterMaxRuntime(t *testing.T) {
+	if timer := exitAfter(0, func(string) { t.Error("exited without a maximum run time") }); timer != nil {
+		t.Error("exitAfter(0) started a timer")
+	}
+
+	c := newTestClient(t, 1)
+	reasons := make(chan string, 1)
+	start := time.Now()
+	exitAfter(50*time.Millisecond, func(reason string) {
+		c.Close()
+		reasons <- reason
+	})
+
+	select {
+	case reason := <-reasons:
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("exited after %s, want 50ms", elapsed)
+		}
+		if want := "maximum run time of 50ms reached"; reason != want {
+			t.Errorf("exit reason = %q, want %q", reason, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("didn't exit after the maximum run time")
+	}
+
+	select {
+	case <-c.closing:
+	default:
+		t.Error("client not closed on exit")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	// memoryPollInterval is how often the memory usage is checked.
+	memoryPollInterval = 5 * time.Second
+	// memoryPressureFactor is how much the readahead and the pieces ahead
+	// are divided by under memory pressure.
+	memoryPressureFactor = 4
+	// memoryRestoreFraction is the share of MemoryLimit the usage has to
+	// drop below before the readahead is restored, so it doesn't flap.
+	memoryRestoreFraction = 0.8
+)
+
+// memoryUsage returns the memory obtained from the system that's still in use.
+func memoryUsage() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys - stats.HeapReleased
+}
+
+// watchMemory reduces the readahead while the memory usage is over the limit.
+func (c *Client) watchMemory() {
+	if !c.waitInfo() {
+		return
+	}
+
+	for c.sleep(memoryPollInterval) {
+		c.observeMemory(memoryUsage())
+	}
+}
+
+// observeMemory switches the memory pressure on and off from a memory usage.
+func (c *Client) observeMemory(usage uint64) {
+	c.mutex.Lock()
+	pressure := c.memoryPressure
+	c.mutex.Unlock()
+
+	limit := c.Config.MemoryLimit
+	switch {
+	case !pressure && usage > limit:
+		log.Printf("Memory usage of %s is over %s, reducing readahead\n", humanize.Bytes(usage), humanize.Bytes(limit))
+		c.setMemoryPressure(true)
+	case pressure && float64(usage) < float64(limit)*memoryRestoreFraction:
+		log.Printf("Memory usage is back to %s, restoring readahead\n", humanize.Bytes(usage))
+		c.setMemoryPressure(false)
+	}
+}
+
+// setMemoryPressure applies the memory pressure to the readahead of the open
+// readers and to the pieces ahead.
+func (c *Client) setMemoryPressure(pressure bool) {
+	c.mutex.Lock()
+	c.memoryPressure = pressure
+	c.mutex.Unlock()
+
+	c.updateReadahead()
+	c.prioritize()
+}
+
+// readahead returns the readahead to use instead of the normal one, reduced
+// under memory pressure.
+func (c *Client) readahead(normal int64) int64 {
+	c.mutex.Lock()
+	pressure := c.memoryPressure
+	c.mutex.Unlock()
+
+	if !pressure {
+		return normal
+	}
+
+	reduced := normal / memoryPressureFactor
+	if pieceLength := c.Torrent.Info().PieceLength; reduced < pieceLength {
+		reduced = pieceLength
+	}
+	if reduced > normal {
+		return normal
+	}
+	return reduced
+}
+
+// maxPiecesAhead returns MaxPiecesAhead, reduced under memory pressure.
+func (c *Client) maxPiecesAhead() int {
+	c.mutex.Lock()
+	pressure := c.memoryPressure
+	c.mutex.Unlock()
+
+	maxPiecesAhead := c.StreamingConfig().MaxPiecesAhead
+	if !pressure || maxPiecesAhead <= memoryPressureFactor {
+		return maxPiecesAhead
+	}
+	return maxPiecesAhead / memoryPressureFactor
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestMemoryPressure(t *testing.T) {
+	c := newTestClient(t, 16)
+	c.Config.MaxPiecesAhead = 8
+	c.Config.MemoryLimit = 1000
+	waitHashed(t, c)
+
+	wanted := func() int {
+		n := 0
+		for i := 0; i < c.Torrent.NumPieces(); i++ {
+			if c.Torrent.PieceState(i).Priority != torrent.PiecePriorityNone {
+				n++
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		usage     uint64
+		readahead int64
+		wanted    int
+	}{
+		{2000, 1 << 18, 3},
+		{900, 1 << 18, 3},
+		{700, 1 << 20, 9},
+	}
+
+	for _, test := range tests {
+		c.observeMemory(test.usage)
+		if got := c.readahead(1 << 20); got != test.readahead {
+			t.Errorf("readahead at a usage of %d = %d, want %d", test.usage, got, test.readahead)
+		}
+		if got := wanted(); got != test.wanted {
+			t.Errorf("pieces wanted at a usage of %d = %d, want %d", test.usage, got, test.wanted)
+		}
+	}
+}
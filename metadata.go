@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tmdbAPIURL   = "https://api.themoviedb.org/3"
+	tmdbImageURL = "https://image.tmdb.org/t/p/"
+	// metadataRetryInterval is how long a failed lookup is remembered before
+	// trying again.
+	metadataRetryInterval = time.Minute
+)
+
+// ErrNoMetadata is returned when the provider doesn't know the torrent.
+var ErrNoMetadata = errors.New("no metadata found")
+
+// MediaMetadata describes the movie or show in the torrent.
+type MediaMetadata struct {
+	Title    string
+	Year     int
+	Overview string
+	Poster   string
+	Fanart   string
+}
+
+// metadataProvider looks up a movie or show by title and year. A zero year
+// is unknown.
+type metadataProvider interface {
+	Lookup(title string, year int) (MediaMetadata, error)
+}
+
+// tmdbProvider looks up the metadata on The Movie Database.
+type tmdbProvider struct {
+	apiKey string
+	url    string
+	client *http.Client
+}
+
+func newTMDbProvider(apiKey string) *tmdbProvider {
+	return &tmdbProvider{
+		apiKey: apiKey,
+		url:    tmdbAPIURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Lookup searches movies and shows, returning the best match.
+func (p *tmdbProvider) Lookup(title string, year int) (metadata MediaMetadata, err error) {
+	query := url.Values{"api_key": {p.apiKey}, "query": {title}}
+	if year > 0 {
+		query.Set("year", strconv.Itoa(year))
+	}
+
+	response, err := p.client.Get(p.url + "/search/multi?" + query.Encode())
+	if err != nil {
+		// The url holds the api key, which mustn't end up in the log.
+		if urlError, ok := err.(*url.Error); ok {
+			urlError.URL = p.url + "/search/multi"
+		}
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return metadata, fmt.Errorf("tmdb returned %s", response.Status)
+	}
+
+	var search struct {
+		Results []struct {
+			MediaType    string `json:"media_type"`
+			Title        string `json:"title"`
+			Name         string `json:"name"`
+			Overview     string `json:"overview"`
+			PosterPath   string `json:"poster_path"`
+			BackdropPath string `json:"backdrop_path"`
+			ReleaseDate  string `json:"release_date"`
+			FirstAirDate string `json:"first_air_date"`
+		} `json:"results"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&search); err != nil {
+		return
+	}
+
+	for _, result := range search.Results {
+		if result.MediaType != "movie" && result.MediaType != "tv" {
+			continue
+		}
+
+		metadata = MediaMetadata{
+			Title:    result.Title + result.Name,
+			Overview: result.Overview,
+		}
+		if date := result.ReleaseDate + result.FirstAirDate; len(date) >= 4 {
+			metadata.Year, _ = strconv.Atoi(date[:4])
+		}
+		if result.PosterPath != "" {
+			metadata.Poster = tmdbImageURL + "w500" + result.PosterPath
+		}
+		if result.BackdropPath != "" {
+			metadata.Fanart = tmdbImageURL + "original" + result.BackdropPath
+		}
+		return metadata, nil
+	}
+
+	return metadata, ErrNoMetadata
+}
+
+// releaseTags start the part of a release name after the title.
+var releaseTags = regexp.MustCompile(`(?i)\b((19|20)\d{2}|s\d{1,2}e\d{1,3}|s\d{1,2}|\d{3,4}p|bluray|brrip|bdrip|web-?dl|webrip|hdtv|dvdrip|x26[45]|h\.?26[45]|xvid|hevc)\b`)
+
+// releaseYear matches a year in a release name.
+var releaseYear = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+
+// parseReleaseName extracts the title and year from a release name, like
+// The.Movie.2019.1080p.BluRay.x264.mkv.
+func parseReleaseName(name string) (title string, year int) {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.NewReplacer(".", " ", "_", " ").Replace(name)
+
+	if found := releaseYear.FindString(name); found != "" {
+		year, _ = strconv.Atoi(found)
+	}
+	if tag := releaseTags.FindStringIndex(name); tag != nil && tag[0] > 0 {
+		name = name[:tag[0]]
+	}
+
+	title = strings.TrimSpace(strings.Trim(name, " -[]()"))
+	return title, year
+}
+
+// metadataCache remembers the lookup of the torrent.
+type metadataCache struct {
+	metadata MediaMetadata
+	err      error
+	fetched  time.Time
+}
+
+// Metadata looks up the movie or show in the torrent with the configured
+// provider. Results are cached, failures for metadataRetryInterval.
+func (c *Client) Metadata() (MediaMetadata, error) {
+	if c.metadataProvider == nil {
+		return MediaMetadata{}, ErrNoMetadata
+	}
+
+	c.mutex.Lock()
+	cached := c.metadata
+	c.mutex.Unlock()
+	if cached != nil && (cached.err == nil || time.Since(cached.fetched) < metadataRetryInterval) {
+		return cached.metadata, cached.err
+	}
+
+	title, year := parseReleaseName(c.Torrent.Name())
+	metadata, err := c.metadataProvider.Lookup(title, year)
+	if err != nil {
+		log.Printf("Error looking up metadata for %q: %s\n", title, err)
+	}
+
+	c.mutex.Lock()
+	c.metadata = &metadataCache{metadata: metadata, err: err, fetched: time.Now()}
+	c.mutex.Unlock()
+
+	return metadata, err
+}
+
+// GetMetadata is an http handler returning the poster, fanart and synopsis of
+// the torrent.
+func (c *Client) GetMetadata(w http.ResponseWriter, r *http.Request) {
+	if c.metadataProvider == nil {
+		http.Error(w, "no metadata provider configured", http.StatusNotFound)
+		return
+	}
+
+	metadata, err := c.Metadata()
+	if err == ErrNoMetadata {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "metadata lookup failed", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metadata); err != nil {
+		log.Printf("Error encoding metadata: %s\n", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// isInfoHash matches a bare hex infohash, which is added as a magnet.
+var isInfoHash = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// publicTrackers are well known open trackers added to magnets when fetching
+// their metadata aggressively.
+var publicTrackers = []string{
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://open.stealth.si:80/announce",
+	"udp://tracker.torrent.eu.org:451/announce",
+	"udp://exodus.desync.com:6969/announce",
+	"udp://open.demonii.com:1337/announce",
+}
+
+// dhtAnnounceInterval is how often the DHT is queried for peers while the
+// metadata is missing.
+const dhtAnnounceInterval = 15 * time.Second
+
+// isMagnet checks if a torrent is added from a magnet or a bare infohash.
+func isMagnet(torrentPath string) bool {
+	return strings.HasPrefix(torrentPath, "magnet:") || isInfoHash.MatchString(torrentPath)
+}
+
+// fetchMetadataAggressively looks for peers with the metadata everywhere we
+// can until it's received: on public trackers, next to the magnet's own
+// ones, and by querying the DHT repeatedly rather than just on announce.
+func (c *Client) fetchMetadataAggressively() {
+	t := c.Torrent
+	t.AddTrackers([][]string{publicTrackers})
+
+	var stops []func()
+	stopAnnounces := func() {
+		for _, stop := range stops {
+			stop()
+		}
+		stops = nil
+	}
+	defer stopAnnounces()
+
+	ticker := time.NewTicker(dhtAnnounceInterval)
+	defer ticker.Stop()
+
+	for {
+		stopAnnounces()
+		for _, server := range c.Client.DhtServers() {
+			_, stop, err := t.AnnounceToDht(server)
+			if err != nil {
+				log.Printf("Error querying the DHT: %s\n", err)
+				continue
+			}
+			stops = append(stops, stop)
+		}
+
+		select {
+		case <-t.GotInfo():
+			return
+		case <-c.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
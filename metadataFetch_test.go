@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// fakeDHTServer records the infohashes it's asked for peers of, without
+// finding any.
+type fakeDHTServer struct {
+	torrent.DhtServer
+	announced chan [20]byte
+}
+
+func (s *fakeDHTServer) Stats() interface{}    { return nil }
+func (s *fakeDHTServer) ID() (id [20]byte)     { return }
+func (s *fakeDHTServer) Addr() net.Addr        { return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (s *fakeDHTServer) Ping(*net.UDPAddr)     {}
+func (s *fakeDHTServer) WriteStatus(io.Writer) {}
+
+func (s *fakeDHTServer) Announce(hash [20]byte, port int, impliedPort bool) (torrent.DhtAnnounce, error) {
+	select {
+	case s.announced <- hash:
+	default:
+	}
+	return nil, errors.New("no nodes")
+}
+
+func TestFetchMetadataAggressively(t *testing.T) {
+	c := newTestClient(t, 1)
+	c.Config.MetadataTimeout = 5 * time.Second
+
+	// Nobody has the info of this magnet.
+	spec, _, err := torrentSpec("0123456789abcdef0123456789abcdef01234567", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Torrent, _, err = addTorrentSpec(c.Client, spec); err != nil {
+		t.Fatal(err)
+	}
+	dht := &fakeDHTServer{announced: make(chan [20]byte, 1)}
+	c.Client.AddDhtServer(dht)
+
+	done := make(chan struct{})
+	go func() {
+		c.fetchMetadataAggressively()
+		close(done)
+	}()
+
+	select {
+	case hash := <-dht.announced:
+		if hash != c.Torrent.InfoHash() {
+			t.Errorf("DHT queried for %x, want %s", hash, c.Torrent.InfoHash())
+		}
+	case <-time.After(c.Config.MetadataTimeout):
+		t.Fatal("DHT not queried within the metadata timeout")
+	}
+
+	// Trackers only lists them once the info is received.
+	announceList := c.Torrent.Metainfo().AnnounceList
+	trackers := make(map[string]bool)
+	for _, tier := range announceList {
+		for _, tracker := range tier {
+			trackers[tracker] = true
+		}
+	}
+	for _, tracker := range publicTrackers {
+		if !trackers[tracker] {
+			t.Errorf("trackers %v, want the public tracker %s", announceList, tracker)
+		}
+	}
+
+	close(c.closing)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetchMetadataAggressively() didn't stop with the client")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReleaseName(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		year  int
+	}{
+		{"The.Movie.2019.1080p.BluRay.x264.mkv", "The Movie", 2019},
+		{"Some_Show_S02E05_720p_HDTV.mkv", "Some Show", 0},
+		{"Another Movie (1999) [BDRip].avi", "Another Movie", 1999},
+		{"Show.Name.S01.WEB-DL", "Show Name", 0},
+		{"Blade.Runner.2049.2017.2160p.mkv", "Blade Runner", 2049},
+		{"Plain Title.mp4", "Plain Title", 0},
+	}
+
+	for _, test := range tests {
+		title, year := parseReleaseName(test.name)
+		if title != test.title || year != test.year {
+			t.Errorf("parseReleaseName(%q) = %q, %d, want %q, %d", test.name, title, year, test.title, test.year)
+		}
+	}
+}
+
+func TestTMDbLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"results": [
+			{"media_type": "person", "name": "Someone"},
+			{"media_type": "movie", "title": "The Movie", "overview": "A movie.", "poster_path": "/poster.jpg", "release_date": "2019-05-01"}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider := newTMDbProvider("secret")
+	provider.url = server.URL
+	metadata, err := provider.Lookup("The Movie", 2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MediaMetadata{Title: "The Movie", Year: 2019, Overview: "A movie.", Poster: tmdbImageURL + "w500/poster.jpg"}
+	if metadata != want {
+		t.Errorf("Lookup() = %+v, want %+v", metadata, want)
+	}
+}
+
+func TestTMDbLookupErrorHidesKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	provider := newTMDbProvider("secret")
+	provider.url = server.URL
+	provider.client.Timeout = time.Second
+	_, err := provider.Lookup("The Movie", 2019)
+	if err == nil {
+		t.Fatal("Lookup() on a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("Lookup() error %q contains the api key", err)
+	}
+}
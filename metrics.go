@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// ServeMetrics sends newline-delimited "metric value timestamp" lines, in the
+// graphite plaintext format, to every connection on the listener.
+func (c *Client) ServeMetrics(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("Error accepting metrics connection: %s\n", err)
+			return
+		}
+
+		go c.streamMetrics(conn)
+	}
+}
+
+func (c *Client) streamMetrics(conn net.Conn) {
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("Error closing metrics connection: %s\n", err)
+		}
+	}()
+
+	interval := c.Config.MetricsInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := writeMetrics(conn, c.Stats(), time.Now()); err != nil {
+			return
+		}
+		<-ticker.C
+	}
+}
+
+func writeMetrics(w io.Writer, stats Stats, now time.Time) error {
+	metrics := []struct {
+		name  string
+		value interface{}
+	}{
+		{"download_rate", stats.DownloadSpeed},
+		{"bytes_completed", stats.BytesCompleted},
+		{"length", stats.Length},
+		{"percentage", fmt.Sprintf("%.2f", stats.Percentage)},
+		{"connections", stats.Connections},
+		{"average_piece_seconds", fmt.Sprintf("%.3f", stats.AveragePieceTime.Seconds())},
+	}
+
+	for _, metric := range metrics {
+		if _, err := fmt.Fprintf(w, "peerflix.%s %v %d\n", metric.name, metric.value, now.Unix()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
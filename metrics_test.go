@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	stats := Stats{
+		DownloadSpeed:    2048,
+		BytesCompleted:   1000,
+		Length:           4000,
+		Percentage:       25,
+		Connections:      3,
+		AveragePieceTime: 1500 * time.Millisecond,
+	}
+	out := &bytes.Buffer{}
+	if err := writeMetrics(out, stats, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "peerflix.download_rate 2048 1700000000\n" +
+		"peerflix.bytes_completed 1000 1700000000\n" +
+		"peerflix.length 4000 1700000000\n" +
+		"peerflix.percentage 25.00 1700000000\n" +
+		"peerflix.connections 3 1700000000\n" +
+		"peerflix.average_piece_seconds 1.500 1700000000\n"
+	if got := out.String(); got != want {
+		t.Errorf("writeMetrics() = %q, want %q", got, want)
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	c := newTestClient(t, 4)
+	c.Config.MetricsInterval = 10 * time.Millisecond
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go c.ServeMetrics(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two rounds of metrics, one per interval.
+	lines := bufio.NewScanner(conn)
+	for i := 0; i < 12; i++ {
+		if !lines.Scan() {
+			t.Fatalf("read %d lines, want 12: %v", i, lines.Err())
+		}
+		fields := strings.Fields(lines.Text())
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], "peerflix.") {
+			t.Errorf("line %q, want \"metric value timestamp\"", lines.Text())
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps the handlers of the http api, for concerns like logging,
+// rate limiting or tracing.
+type Middleware func(http.Handler) http.Handler
+
+// Handler wraps the handler of the http api in the configured middlewares,
+// the first one running first. CORS, when enabled, runs before all of them
+// so preflight requests are answered directly.
+func (c *Client) Handler(handler http.Handler) http.Handler {
+	for i := len(c.Config.Middlewares) - 1; i >= 0; i-- {
+		handler = c.Config.Middlewares[i](handler)
+	}
+
+	if c.Config.CORSOrigin != "" {
+		handler = corsMiddleware(c.Config.CORSOrigin)(handler)
+	}
+
+	return handler
+}
+
+// corsMiddleware lets browser ui on origin, or any origin for "*", use the
+// http api.
+func corsMiddleware(origin string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			header.Set("Access-Control-Allow-Origin", origin)
+			if origin != "*" {
+				header.Add("Vary", "Origin")
+			}
+			header.Set("Access-Control-Expose-Headers", "Accept-Ranges, Content-Length, Content-Range")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				header.Set("Access-Control-Allow-Methods", strings.Join([]string{
+					http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodOptions,
+				}, ", "))
+				header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Range")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMiddlewaresRunInOrder(t *testing.T) {
+	var ran []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ran = append(ran, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	c := &Client{Config: ClientConfig{Middlewares: []Middleware{record("logging"), record("tracing")}}}
+	handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = append(ran, "handler")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/status", nil))
+	if want := []string{"logging", "tracing", "handler"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	c := &Client{Config: ClientConfig{CORSOrigin: "https://ui.example.com"}}
+	served := 0
+	handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+
+	preflight := httptest.NewRequest("OPTIONS", "/", nil)
+	preflight.Header.Set("Origin", "https://ui.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, preflight)
+	if w.Code != http.StatusNoContent || served != 0 {
+		t.Errorf("preflight = %d, served %d times, want %d answered by the middleware", w.Code, served, http.StatusNoContent)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type, Range" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want the Range header allowed", got)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if served != 1 {
+		t.Errorf("GET served %d times, want once", served)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://ui.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "https://ui.example.com")
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Vary = %q, want Origin", got)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+)
+
+// Errors returned for the patterns compilePattern rejects.
+var (
+	ErrPatternTooLong    = errors.New("pattern is too long")
+	ErrPatternTooComplex = errors.New("pattern is too complex")
+)
+
+// compilePattern compiles a user supplied regular expression, rejecting the
+// ones longer than MaxPatternLength or compiling to more than
+// MaxPatternComplexity instructions, which would be slow to match.
+func (c *Client) compilePattern(pattern string) (*regexp.Regexp, error) {
+	if limit := c.Config.MaxPatternLength; limit > 0 && len(pattern) > limit {
+		return nil, fmt.Errorf("%w, %d characters is over %d", ErrPatternTooLong, len(pattern), limit)
+	}
+
+	if limit := c.Config.MaxPatternComplexity; limit > 0 {
+		parsed, err := syntax.Parse(pattern, syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		program, err := syntax.Compile(parsed.Simplify())
+		if err != nil {
+			return nil, err
+		}
+		if len(program.Inst) > limit {
+			return nil, fmt.Errorf("%w, %d instructions is over %d", ErrPatternTooComplex, len(program.Inst), limit)
+		}
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// SelectFileByPattern switches the streamed file to the first one whose path
+// matches the regular expression, like SetSelectedFile.
+func (c *Client) SelectFileByPattern(pattern string) error {
+	if !c.infoReady() {
+		return ClientError{Type: "selecting file", Origin: ErrMetadataNotReady}
+	}
+
+	re, err := c.compilePattern(pattern)
+	if err != nil {
+		return ClientError{Type: "parsing selection pattern", Origin: err}
+	}
+
+	for i, f := range c.files() {
+		if re.MatchString(f.Path()) {
+			return c.SetSelectedFile(i)
+		}
+	}
+	return ClientError{Type: "selecting file", Origin: fmt.Errorf("no file matches %q", pattern)}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		length     int
+		complexity int
+		want       error
+	}{
+		{`S01E0[1-3]`, 0, 0, nil},
+		{strings.Repeat("a", 100), 0, 0, nil},
+		{strings.Repeat("a", 100), 100, 0, nil},
+		{strings.Repeat("a", 101), 100, 0, ErrPatternTooLong},
+		{`S01E0[1-3]`, 0, 100, nil},
+		{`(a|b|c|d){50}`, 0, 100, ErrPatternTooComplex},
+		{`(a|b|c|d){50}`, 10, 100, ErrPatternTooLong},
+	}
+
+	for _, test := range tests {
+		c := &Client{}
+		c.Config.MaxPatternLength = test.length
+		c.Config.MaxPatternComplexity = test.complexity
+		if _, err := c.compilePattern(test.pattern); !errors.Is(err, test.want) {
+			t.Errorf("compilePattern(%q) with limits %d and %d = %v, want %v", test.pattern, test.length, test.complexity, err, test.want)
+		}
+	}
+
+	c := &Client{}
+	if _, err := c.compilePattern(`(`); err == nil {
+		t.Error("compilePattern(\"(\") succeeded, want a syntax error")
+	}
+}
@@ -0,0 +1,939 @@
+// The Peerflix service, served with -grpc-addr. The go stubs in peerflixpb are
+// generated from this file, see grpc.go. With an -auth-token, calls send it as
+// a bearer token in the authorization metadata.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: peerflix.proto
+
+package peerflixpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_peerflix_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{0}
+}
+
+type AddTorrentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Torrent       string                 `protobuf:"bytes,1,opt,name=torrent,proto3" json:"torrent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddTorrentRequest) Reset() {
+	*x = AddTorrentRequest{}
+	mi := &file_peerflix_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddTorrentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddTorrentRequest) ProtoMessage() {}
+
+func (x *AddTorrentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddTorrentRequest.ProtoReflect.Descriptor instead.
+func (*AddTorrentRequest) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AddTorrentRequest) GetTorrent() string {
+	if x != nil {
+		return x.Torrent
+	}
+	return ""
+}
+
+type AddTorrentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InfoHash      string                 `protobuf:"bytes,1,opt,name=info_hash,json=infoHash,proto3" json:"info_hash,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddTorrentResponse) Reset() {
+	*x = AddTorrentResponse{}
+	mi := &file_peerflix_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddTorrentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddTorrentResponse) ProtoMessage() {}
+
+func (x *AddTorrentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddTorrentResponse.ProtoReflect.Descriptor instead.
+func (*AddTorrentResponse) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AddTorrentResponse) GetInfoHash() string {
+	if x != nil {
+		return x.InfoHash
+	}
+	return ""
+}
+
+func (x *AddTorrentResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int64                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_peerflix_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FileInfo) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *FileInfo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileInfo) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type ListFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesResponse) Reset() {
+	*x = ListFilesResponse{}
+	mi := &file_peerflix_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesResponse) ProtoMessage() {}
+
+func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListFilesResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+type SelectFileRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Index int64                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// Selects the first file whose path matches this regular expression
+	// instead, when set.
+	Pattern       string `protobuf:"bytes,2,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SelectFileRequest) Reset() {
+	*x = SelectFileRequest{}
+	mi := &file_peerflix_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SelectFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SelectFileRequest) ProtoMessage() {}
+
+func (x *SelectFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SelectFileRequest.ProtoReflect.Descriptor instead.
+func (*SelectFileRequest) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SelectFileRequest) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *SelectFileRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+type ReadFileRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The file to read, the streamed file when unset.
+	Index  *int32 `protobuf:"varint,1,opt,name=index,proto3,oneof" json:"index,omitempty"`
+	Offset int64  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// How many bytes to read, zero reads to the end.
+	Length        int64 `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadFileRequest) Reset() {
+	*x = ReadFileRequest{}
+	mi := &file_peerflix_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadFileRequest) ProtoMessage() {}
+
+func (x *ReadFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadFileRequest.ProtoReflect.Descriptor instead.
+func (*ReadFileRequest) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReadFileRequest) GetIndex() int32 {
+	if x != nil && x.Index != nil {
+		return *x.Index
+	}
+	return 0
+}
+
+func (x *ReadFileRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadFileRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type Chunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	mi := &file_peerflix_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Chunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Chunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// Stats are the Stats of the client, see stats.go.
+type Stats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	BytesCompleted int64                  `protobuf:"varint,2,opt,name=bytes_completed,json=bytesCompleted,proto3" json:"bytes_completed,omitempty"`
+	// -1 until info_ready.
+	Length           int64   `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	Percentage       float64 `protobuf:"fixed64,4,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	DownloadSpeed    int64   `protobuf:"varint,5,opt,name=download_speed,json=downloadSpeed,proto3" json:"download_speed,omitempty"`
+	Speed            string  `protobuf:"bytes,6,opt,name=speed,proto3" json:"speed,omitempty"`
+	Connections      int64   `protobuf:"varint,7,opt,name=connections,proto3" json:"connections,omitempty"`
+	ReadyForPlayback bool    `protobuf:"varint,8,opt,name=ready_for_playback,json=readyForPlayback,proto3" json:"ready_for_playback,omitempty"`
+	Buffering        bool    `protobuf:"varint,9,opt,name=buffering,proto3" json:"buffering,omitempty"`
+	DirectPlay       bool    `protobuf:"varint,10,opt,name=direct_play,json=directPlay,proto3" json:"direct_play,omitempty"`
+	BufferedStart    int64   `protobuf:"varint,11,opt,name=buffered_start,json=bufferedStart,proto3" json:"buffered_start,omitempty"`
+	BufferedEnd      int64   `protobuf:"varint,12,opt,name=buffered_end,json=bufferedEnd,proto3" json:"buffered_end,omitempty"`
+	Error            string  `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+	// Nanoseconds.
+	AveragePieceTime int64            `protobuf:"varint,14,opt,name=average_piece_time,json=averagePieceTime,proto3" json:"average_piece_time,omitempty"`
+	Peers            *ConnectionStats `protobuf:"bytes,15,opt,name=peers,proto3" json:"peers,omitempty"`
+	Swarm            *SwarmHealth     `protobuf:"bytes,16,opt,name=swarm,proto3" json:"swarm,omitempty"`
+	Checking         bool             `protobuf:"varint,17,opt,name=checking,proto3" json:"checking,omitempty"`
+	CheckingProgress float64          `protobuf:"fixed64,18,opt,name=checking_progress,json=checkingProgress,proto3" json:"checking_progress,omitempty"`
+	InfoReady        bool             `protobuf:"varint,19,opt,name=info_ready,json=infoReady,proto3" json:"info_ready,omitempty"`
+	// -1 until info_ready.
+	FileLength    int64 `protobuf:"varint,20,opt,name=file_length,json=fileLength,proto3" json:"file_length,omitempty"`
+	DirectReads   int64 `protobuf:"varint,21,opt,name=direct_reads,json=directReads,proto3" json:"direct_reads,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_peerflix_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Stats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Stats) GetBytesCompleted() int64 {
+	if x != nil {
+		return x.BytesCompleted
+	}
+	return 0
+}
+
+func (x *Stats) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *Stats) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+func (x *Stats) GetDownloadSpeed() int64 {
+	if x != nil {
+		return x.DownloadSpeed
+	}
+	return 0
+}
+
+func (x *Stats) GetSpeed() string {
+	if x != nil {
+		return x.Speed
+	}
+	return ""
+}
+
+func (x *Stats) GetConnections() int64 {
+	if x != nil {
+		return x.Connections
+	}
+	return 0
+}
+
+func (x *Stats) GetReadyForPlayback() bool {
+	if x != nil {
+		return x.ReadyForPlayback
+	}
+	return false
+}
+
+func (x *Stats) GetBuffering() bool {
+	if x != nil {
+		return x.Buffering
+	}
+	return false
+}
+
+func (x *Stats) GetDirectPlay() bool {
+	if x != nil {
+		return x.DirectPlay
+	}
+	return false
+}
+
+func (x *Stats) GetBufferedStart() int64 {
+	if x != nil {
+		return x.BufferedStart
+	}
+	return 0
+}
+
+func (x *Stats) GetBufferedEnd() int64 {
+	if x != nil {
+		return x.BufferedEnd
+	}
+	return 0
+}
+
+func (x *Stats) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Stats) GetAveragePieceTime() int64 {
+	if x != nil {
+		return x.AveragePieceTime
+	}
+	return 0
+}
+
+func (x *Stats) GetPeers() *ConnectionStats {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *Stats) GetSwarm() *SwarmHealth {
+	if x != nil {
+		return x.Swarm
+	}
+	return nil
+}
+
+func (x *Stats) GetChecking() bool {
+	if x != nil {
+		return x.Checking
+	}
+	return false
+}
+
+func (x *Stats) GetCheckingProgress() float64 {
+	if x != nil {
+		return x.CheckingProgress
+	}
+	return 0
+}
+
+func (x *Stats) GetInfoReady() bool {
+	if x != nil {
+		return x.InfoReady
+	}
+	return false
+}
+
+func (x *Stats) GetFileLength() int64 {
+	if x != nil {
+		return x.FileLength
+	}
+	return 0
+}
+
+func (x *Stats) GetDirectReads() int64 {
+	if x != nil {
+		return x.DirectReads
+	}
+	return 0
+}
+
+type SwarmHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seeders       int64                  `protobuf:"varint,1,opt,name=seeders,proto3" json:"seeders,omitempty"`
+	Leechers      int64                  `protobuf:"varint,2,opt,name=leechers,proto3" json:"leechers,omitempty"`
+	Completed     int64                  `protobuf:"varint,3,opt,name=completed,proto3" json:"completed,omitempty"`
+	Trackers      int64                  `protobuf:"varint,4,opt,name=trackers,proto3" json:"trackers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SwarmHealth) Reset() {
+	*x = SwarmHealth{}
+	mi := &file_peerflix_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SwarmHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwarmHealth) ProtoMessage() {}
+
+func (x *SwarmHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwarmHealth.ProtoReflect.Descriptor instead.
+func (*SwarmHealth) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SwarmHealth) GetSeeders() int64 {
+	if x != nil {
+		return x.Seeders
+	}
+	return 0
+}
+
+func (x *SwarmHealth) GetLeechers() int64 {
+	if x != nil {
+		return x.Leechers
+	}
+	return 0
+}
+
+func (x *SwarmHealth) GetCompleted() int64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *SwarmHealth) GetTrackers() int64 {
+	if x != nil {
+		return x.Trackers
+	}
+	return 0
+}
+
+type ConnectionStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int64                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Seeds         int64                  `protobuf:"varint,2,opt,name=seeds,proto3" json:"seeds,omitempty"`
+	Leechers      int64                  `protobuf:"varint,3,opt,name=leechers,proto3" json:"leechers,omitempty"`
+	HalfOpen      int64                  `protobuf:"varint,4,opt,name=half_open,json=halfOpen,proto3" json:"half_open,omitempty"`
+	Incoming      int64                  `protobuf:"varint,5,opt,name=incoming,proto3" json:"incoming,omitempty"`
+	Outgoing      int64                  `protobuf:"varint,6,opt,name=outgoing,proto3" json:"outgoing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectionStats) Reset() {
+	*x = ConnectionStats{}
+	mi := &file_peerflix_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionStats) ProtoMessage() {}
+
+func (x *ConnectionStats) ProtoReflect() protoreflect.Message {
+	mi := &file_peerflix_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionStats.ProtoReflect.Descriptor instead.
+func (*ConnectionStats) Descriptor() ([]byte, []int) {
+	return file_peerflix_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ConnectionStats) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetSeeds() int64 {
+	if x != nil {
+		return x.Seeds
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetLeechers() int64 {
+	if x != nil {
+		return x.Leechers
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetHalfOpen() int64 {
+	if x != nil {
+		return x.HalfOpen
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetIncoming() int64 {
+	if x != nil {
+		return x.Incoming
+	}
+	return 0
+}
+
+func (x *ConnectionStats) GetOutgoing() int64 {
+	if x != nil {
+		return x.Outgoing
+	}
+	return 0
+}
+
+var File_peerflix_proto protoreflect.FileDescriptor
+
+const file_peerflix_proto_rawDesc = "" +
+	"\n" +
+	"\x0epeerflix.proto\x12\bpeerflix\"\a\n" +
+	"\x05Empty\"-\n" +
+	"\x11AddTorrentRequest\x12\x18\n" +
+	"\atorrent\x18\x01 \x01(\tR\atorrent\"E\n" +
+	"\x12AddTorrentResponse\x12\x1b\n" +
+	"\tinfo_hash\x18\x01 \x01(\tR\binfoHash\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"L\n" +
+	"\bFileInfo\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\"=\n" +
+	"\x11ListFilesResponse\x12(\n" +
+	"\x05files\x18\x01 \x03(\v2\x12.peerflix.FileInfoR\x05files\"C\n" +
+	"\x11SelectFileRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12\x18\n" +
+	"\apattern\x18\x02 \x01(\tR\apattern\"f\n" +
+	"\x0fReadFileRequest\x12\x19\n" +
+	"\x05index\x18\x01 \x01(\x05H\x00R\x05index\x88\x01\x01\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06lengthB\b\n" +
+	"\x06_index\"3\n" +
+	"\x05Chunk\x12\x16\n" +
+	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\xe0\x05\n" +
+	"\x05Stats\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12'\n" +
+	"\x0fbytes_completed\x18\x02 \x01(\x03R\x0ebytesCompleted\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x04 \x01(\x01R\n" +
+	"percentage\x12%\n" +
+	"\x0edownload_speed\x18\x05 \x01(\x03R\rdownloadSpeed\x12\x14\n" +
+	"\x05speed\x18\x06 \x01(\tR\x05speed\x12 \n" +
+	"\vconnections\x18\a \x01(\x03R\vconnections\x12,\n" +
+	"\x12ready_for_playback\x18\b \x01(\bR\x10readyForPlayback\x12\x1c\n" +
+	"\tbuffering\x18\t \x01(\bR\tbuffering\x12\x1f\n" +
+	"\vdirect_play\x18\n" +
+	" \x01(\bR\n" +
+	"directPlay\x12%\n" +
+	"\x0ebuffered_start\x18\v \x01(\x03R\rbufferedStart\x12!\n" +
+	"\fbuffered_end\x18\f \x01(\x03R\vbufferedEnd\x12\x14\n" +
+	"\x05error\x18\r \x01(\tR\x05error\x12,\n" +
+	"\x12average_piece_time\x18\x0e \x01(\x03R\x10averagePieceTime\x12/\n" +
+	"\x05peers\x18\x0f \x01(\v2\x19.peerflix.ConnectionStatsR\x05peers\x12+\n" +
+	"\x05swarm\x18\x10 \x01(\v2\x15.peerflix.SwarmHealthR\x05swarm\x12\x1a\n" +
+	"\bchecking\x18\x11 \x01(\bR\bchecking\x12+\n" +
+	"\x11checking_progress\x18\x12 \x01(\x01R\x10checkingProgress\x12\x1d\n" +
+	"\n" +
+	"info_ready\x18\x13 \x01(\bR\tinfoReady\x12\x1f\n" +
+	"\vfile_length\x18\x14 \x01(\x03R\n" +
+	"fileLength\x12!\n" +
+	"\fdirect_reads\x18\x15 \x01(\x03R\vdirectReads\"}\n" +
+	"\vSwarmHealth\x12\x18\n" +
+	"\aseeders\x18\x01 \x01(\x03R\aseeders\x12\x1a\n" +
+	"\bleechers\x18\x02 \x01(\x03R\bleechers\x12\x1c\n" +
+	"\tcompleted\x18\x03 \x01(\x03R\tcompleted\x12\x1a\n" +
+	"\btrackers\x18\x04 \x01(\x03R\btrackers\"\xae\x01\n" +
+	"\x0fConnectionStats\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x03R\x05total\x12\x14\n" +
+	"\x05seeds\x18\x02 \x01(\x03R\x05seeds\x12\x1a\n" +
+	"\bleechers\x18\x03 \x01(\x03R\bleechers\x12\x1b\n" +
+	"\thalf_open\x18\x04 \x01(\x03R\bhalfOpen\x12\x1a\n" +
+	"\bincoming\x18\x05 \x01(\x03R\bincoming\x12\x1a\n" +
+	"\boutgoing\x18\x06 \x01(\x03R\boutgoing2\xb3\x02\n" +
+	"\bPeerflix\x12G\n" +
+	"\n" +
+	"AddTorrent\x12\x1b.peerflix.AddTorrentRequest\x1a\x1c.peerflix.AddTorrentResponse\x12-\n" +
+	"\tGetStatus\x12\x0f.peerflix.Empty\x1a\x0f.peerflix.Stats\x129\n" +
+	"\tListFiles\x12\x0f.peerflix.Empty\x1a\x1b.peerflix.ListFilesResponse\x12:\n" +
+	"\n" +
+	"SelectFile\x12\x1b.peerflix.SelectFileRequest\x1a\x0f.peerflix.Empty\x128\n" +
+	"\bReadFile\x12\x19.peerflix.ReadFileRequest\x1a\x0f.peerflix.Chunk0\x01B/Z-github.com/Sioro-Neoku/go-peerflix/peerflixpbb\x06proto3"
+
+var (
+	file_peerflix_proto_rawDescOnce sync.Once
+	file_peerflix_proto_rawDescData []byte
+)
+
+func file_peerflix_proto_rawDescGZIP() []byte {
+	file_peerflix_proto_rawDescOnce.Do(func() {
+		file_peerflix_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_peerflix_proto_rawDesc), len(file_peerflix_proto_rawDesc)))
+	})
+	return file_peerflix_proto_rawDescData
+}
+
+var file_peerflix_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_peerflix_proto_goTypes = []any{
+	(*Empty)(nil),              // 0: peerflix.Empty
+	(*AddTorrentRequest)(nil),  // 1: peerflix.AddTorrentRequest
+	(*AddTorrentResponse)(nil), // 2: peerflix.AddTorrentResponse
+	(*FileInfo)(nil),           // 3: peerflix.FileInfo
+	(*ListFilesResponse)(nil),  // 4: peerflix.ListFilesResponse
+	(*SelectFileRequest)(nil),  // 5: peerflix.SelectFileRequest
+	(*ReadFileRequest)(nil),    // 6: peerflix.ReadFileRequest
+	(*Chunk)(nil),              // 7: peerflix.Chunk
+	(*Stats)(nil),              // 8: peerflix.Stats
+	(*SwarmHealth)(nil),        // 9: peerflix.SwarmHealth
+	(*ConnectionStats)(nil),    // 10: peerflix.ConnectionStats
+}
+var file_peerflix_proto_depIdxs = []int32{
+	3,  // 0: peerflix.ListFilesResponse.files:type_name -> peerflix.FileInfo
+	10, // 1: peerflix.Stats.peers:type_name -> peerflix.ConnectionStats
+	9,  // 2: peerflix.Stats.swarm:type_name -> peerflix.SwarmHealth
+	1,  // 3: peerflix.Peerflix.AddTorrent:input_type -> peerflix.AddTorrentRequest
+	0,  // 4: peerflix.Peerflix.GetStatus:input_type -> peerflix.Empty
+	0,  // 5: peerflix.Peerflix.ListFiles:input_type -> peerflix.Empty
+	5,  // 6: peerflix.Peerflix.SelectFile:input_type -> peerflix.SelectFileRequest
+	6,  // 7: peerflix.Peerflix.ReadFile:input_type -> peerflix.ReadFileRequest
+	2,  // 8: peerflix.Peerflix.AddTorrent:output_type -> peerflix.AddTorrentResponse
+	8,  // 9: peerflix.Peerflix.GetStatus:output_type -> peerflix.Stats
+	4,  // 10: peerflix.Peerflix.ListFiles:output_type -> peerflix.ListFilesResponse
+	0,  // 11: peerflix.Peerflix.SelectFile:output_type -> peerflix.Empty
+	7,  // 12: peerflix.Peerflix.ReadFile:output_type -> peerflix.Chunk
+	8,  // [8:13] is the sub-list for method output_type
+	3,  // [3:8] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_peerflix_proto_init() }
+func file_peerflix_proto_init() {
+	if File_peerflix_proto != nil {
+		return
+	}
+	file_peerflix_proto_msgTypes[6].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_peerflix_proto_rawDesc), len(file_peerflix_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_peerflix_proto_goTypes,
+		DependencyIndexes: file_peerflix_proto_depIdxs,
+		MessageInfos:      file_peerflix_proto_msgTypes,
+	}.Build()
+	File_peerflix_proto = out.File
+	file_peerflix_proto_goTypes = nil
+	file_peerflix_proto_depIdxs = nil
+}
@@ -0,0 +1,293 @@
+// The Peerflix service, served with -grpc-addr. The go stubs in peerflixpb are
+// generated from this file, see grpc.go. With an -auth-token, calls send it as
+// a bearer token in the authorization metadata.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: peerflix.proto
+
+package peerflixpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Peerflix_AddTorrent_FullMethodName = "/peerflix.Peerflix/AddTorrent"
+	Peerflix_GetStatus_FullMethodName  = "/peerflix.Peerflix/GetStatus"
+	Peerflix_ListFiles_FullMethodName  = "/peerflix.Peerflix/ListFiles"
+	Peerflix_SelectFile_FullMethodName = "/peerflix.Peerflix/SelectFile"
+	Peerflix_ReadFile_FullMethodName   = "/peerflix.Peerflix/ReadFile"
+)
+
+// PeerflixClient is the client API for Peerflix service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PeerflixClient interface {
+	// AddTorrent adds a magnet url, infohash or torrent url next to the
+	// streamed torrent.
+	AddTorrent(ctx context.Context, in *AddTorrentRequest, opts ...grpc.CallOption) (*AddTorrentResponse, error)
+	// GetStatus returns the same stats as /status.
+	GetStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Stats, error)
+	// ListFiles returns the files of the streamed torrent.
+	ListFiles(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	// SelectFile switches the streamed file.
+	SelectFile(ctx context.Context, in *SelectFileRequest, opts ...grpc.CallOption) (*Empty, error)
+	// ReadFile streams a range of a file in chunks.
+	ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error)
+}
+
+type peerflixClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPeerflixClient(cc grpc.ClientConnInterface) PeerflixClient {
+	return &peerflixClient{cc}
+}
+
+func (c *peerflixClient) AddTorrent(ctx context.Context, in *AddTorrentRequest, opts ...grpc.CallOption) (*AddTorrentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddTorrentResponse)
+	err := c.cc.Invoke(ctx, Peerflix_AddTorrent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerflixClient) GetStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Stats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, Peerflix_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerflixClient) ListFiles(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFilesResponse)
+	err := c.cc.Invoke(ctx, Peerflix_ListFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerflixClient) SelectFile(ctx context.Context, in *SelectFileRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Peerflix_SelectFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerflixClient) ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Chunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Peerflix_ServiceDesc.Streams[0], Peerflix_ReadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReadFileRequest, Chunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Peerflix_ReadFileClient = grpc.ServerStreamingClient[Chunk]
+
+// PeerflixServer is the server API for Peerflix service.
+// All implementations must embed UnimplementedPeerflixServer
+// for forward compatibility.
+type PeerflixServer interface {
+	// AddTorrent adds a magnet url, infohash or torrent url next to the
+	// streamed torrent.
+	AddTorrent(context.Context, *AddTorrentRequest) (*AddTorrentResponse, error)
+	// GetStatus returns the same stats as /status.
+	GetStatus(context.Context, *Empty) (*Stats, error)
+	// ListFiles returns the files of the streamed torrent.
+	ListFiles(context.Context, *Empty) (*ListFilesResponse, error)
+	// SelectFile switches the streamed file.
+	SelectFile(context.Context, *SelectFileRequest) (*Empty, error)
+	// ReadFile streams a range of a file in chunks.
+	ReadFile(*ReadFileRequest, grpc.ServerStreamingServer[Chunk]) error
+	mustEmbedUnimplementedPeerflixServer()
+}
+
+// UnimplementedPeerflixServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPeerflixServer struct{}
+
+func (UnimplementedPeerflixServer) AddTorrent(context.Context, *AddTorrentRequest) (*AddTorrentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddTorrent not implemented")
+}
+func (UnimplementedPeerflixServer) GetStatus(context.Context, *Empty) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedPeerflixServer) ListFiles(context.Context, *Empty) (*ListFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedPeerflixServer) SelectFile(context.Context, *SelectFileRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SelectFile not implemented")
+}
+func (UnimplementedPeerflixServer) ReadFile(*ReadFileRequest, grpc.ServerStreamingServer[Chunk]) error {
+	return status.Errorf(codes.Unimplemented, "method ReadFile not implemented")
+}
+func (UnimplementedPeerflixServer) mustEmbedUnimplementedPeerflixServer() {}
+func (UnimplementedPeerflixServer) testEmbeddedByValue()                  {}
+
+// UnsafePeerflixServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PeerflixServer will
+// result in compilation errors.
+type UnsafePeerflixServer interface {
+	mustEmbedUnimplementedPeerflixServer()
+}
+
+func RegisterPeerflixServer(s grpc.ServiceRegistrar, srv PeerflixServer) {
+	// If the following call pancis, it indicates UnimplementedPeerflixServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Peerflix_ServiceDesc, srv)
+}
+
+func _Peerflix_AddTorrent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddTorrentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerflixServer).AddTorrent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Peerflix_AddTorrent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerflixServer).AddTorrent(ctx, req.(*AddTorrentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Peerflix_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerflixServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Peerflix_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerflixServer).GetStatus(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Peerflix_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerflixServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Peerflix_ListFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerflixServer).ListFiles(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Peerflix_SelectFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SelectFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerflixServer).SelectFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Peerflix_SelectFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerflixServer).SelectFile(ctx, req.(*SelectFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Peerflix_ReadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReadFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PeerflixServer).ReadFile(m, &grpc.GenericServerStream[ReadFileRequest, Chunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Peerflix_ReadFileServer = grpc.ServerStreamingServer[Chunk]
+
+// Peerflix_ServiceDesc is the grpc.ServiceDesc for Peerflix service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Peerflix_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "peerflix.Peerflix",
+	HandlerType: (*PeerflixServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddTorrent",
+			Handler:    _Peerflix_AddTorrent_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Peerflix_GetStatus_Handler,
+		},
+		{
+			MethodName: "ListFiles",
+			Handler:    _Peerflix_ListFiles_Handler,
+		},
+		{
+			MethodName: "SelectFile",
+			Handler:    _Peerflix_SelectFile_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ReadFile",
+			Handler:       _Peerflix_ReadFile_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "peerflix.proto",
+}
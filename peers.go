@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+const (
+	// fastPeersKept is how many of the fastest peers are never dropped.
+	fastPeersKept = 8
+	// slowPeerFraction is the share of the fastest peer's rate below which
+	// a peer is considered slow.
+	slowPeerFraction = 0.1
+	// slowPeerInterval is how often the peers are ranked while buffering.
+	slowPeerInterval = 5 * time.Second
+	// slowPeerGrace is how long a new peer has to get up to speed before it
+	// can be dropped.
+	slowPeerGrace = 30 * time.Second
+)
+
+// rankPeers orders the peers by download rate, fastest first.
+func rankPeers(rates []float64) []int {
+	ranked := make([]int, len(rates))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return rates[ranked[i]] > rates[ranked[j]]
+	})
+
+	return ranked
+}
+
+// slowPeers picks the peers outside the fastest kept ones that download at a
+// fraction of the fastest peer's rate. Peers connected for less than the
+// slowPeerGrace, whose rate isn't known yet, are never picked.
+func slowPeers(rates []float64, connected []time.Duration, kept int) []int {
+	ranked := rankPeers(rates)
+	if len(ranked) <= kept {
+		return nil
+	}
+
+	threshold := rates[ranked[0]] * slowPeerFraction
+	var slow []int
+	for _, peer := range ranked[kept:] {
+		if rates[peer] < threshold && connected[peer] >= slowPeerGrace {
+			slow = append(slow, peer)
+		}
+	}
+
+	return slow
+}
+
+// preferFastPeers drops the slowest peers while playback is buffering. The
+// torrent library doesn't let us pick which peer a piece is requested from,
+// so this frees the urgent readahead requests stuck on slow peers to go to
+// faster ones, and makes room for new peers.
+func (c *Client) preferFastPeers() {
+	ticker := time.NewTicker(slowPeerInterval)
+	defer ticker.Stop()
+
+	// The library doesn't tell when a peer connected, so it's from when we
+	// first saw it.
+	seen := make(map[*torrent.PeerConn]time.Time)
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		conns := c.Torrent.PeerConns()
+		current := make(map[*torrent.PeerConn]time.Time, len(conns))
+		for _, conn := range conns {
+			if since, ok := seen[conn]; ok {
+				current[conn] = since
+			} else {
+				current[conn] = now
+			}
+		}
+		seen = current
+
+		c.mutex.Lock()
+		buffering := c.buffering > 0
+		c.mutex.Unlock()
+		if !buffering {
+			continue
+		}
+
+		rates := make([]float64, len(conns))
+		connected := make([]time.Duration, len(conns))
+		for i, conn := range conns {
+			rates[i] = conn.DownloadRate()
+			connected[i] = now.Sub(seen[conn])
+		}
+
+		slow := slowPeers(rates, connected, fastPeersKept)
+		for _, peer := range slow {
+			conns[peer].Close()
+		}
+		if len(slow) > 0 {
+			log.Printf("Dropped %d slow peers while buffering\n", len(slow))
+		}
+	}
+}
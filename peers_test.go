@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSlowPeers(t *testing.T) {
+	old := time.Minute
+	tests := []struct {
+		name      string
+		rates     []float64
+		connected []time.Duration
+		kept      int
+		want      []int
+	}{
+		{"fewer than kept", []float64{100, 1}, []time.Duration{old, old}, 2, nil},
+		{"slow peer", []float64{100, 50, 1}, []time.Duration{old, old, old}, 1, []int{2}},
+		{"slowest first out of the kept", []float64{1, 100, 5, 80}, []time.Duration{old, old, old, old}, 2, []int{2, 0}},
+		{"new peer spared", []float64{100, 50, 0}, []time.Duration{old, old, time.Second}, 1, nil},
+		{"new and old slow peers", []float64{0, 100, 0}, []time.Duration{time.Second, old, old}, 1, []int{2}},
+		{"nobody downloading", []float64{0, 0, 0}, []time.Duration{old, old, old}, 1, nil},
+	}
+
+	for _, test := range tests {
+		if got := slowPeers(test.rates, test.connected, test.kept); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: slowPeers(%v) = %v, want %v", test.name, test.rates, got, test.want)
+		}
+	}
+}
+
+func TestPreferFastPeersStopsOnClose(t *testing.T) {
+	c := &Client{closing: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		c.preferFastPeers()
+		close(done)
+	}()
+
+	close(c.closing)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("preferFastPeers still running after close")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// pieceTimer measures how long pieces take from receiving their first data
+// until they're verified.
+type pieceTimer struct {
+	started map[int]time.Time
+	total   time.Duration
+	count   int
+}
+
+func newPieceTimer() *pieceTimer {
+	return &pieceTimer{started: make(map[int]time.Time)}
+}
+
+// observe records a piece state change.
+func (p *pieceTimer) observe(change torrent.PieceStateChange, now time.Time) {
+	started, ok := p.started[change.Index]
+
+	switch {
+	case change.Complete && ok:
+		p.total += now.Sub(started)
+		p.count++
+		delete(p.started, change.Index)
+	case change.Partial && !ok:
+		p.started[change.Index] = now
+	}
+}
+
+// average returns the mean download time of the completed pieces.
+func (p *pieceTimer) average() time.Duration {
+	if p.count == 0 {
+		return 0
+	}
+	return p.total / time.Duration(p.count)
+}
+
+// watchPieceTimes times the pieces as their state changes.
+func (c *Client) watchPieceTimes() {
+	subscription := c.Torrent.SubscribePieceStateChanges()
+	defer subscription.Close()
+
+	for {
+		select {
+		case change, ok := <-subscription.Values:
+			if !ok {
+				return
+			}
+			c.mutex.Lock()
+			c.pieceTimes.observe(change, time.Now())
+			c.mutex.Unlock()
+		case <-c.closing:
+			return
+		}
+	}
+}
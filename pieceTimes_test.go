@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/storage"
+)
+
+// pieceChange returns a state change of the piece.
+func pieceChange(index int, partial, complete bool) torrent.PieceStateChange {
+	change := torrent.PieceStateChange{Index: index}
+	change.Partial = partial
+	change.Completion = storage.Completion{Complete: complete, Ok: true}
+	return change
+}
+
+func TestPieceTimes(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	changes := []struct {
+		change torrent.PieceStateChange
+		after  time.Duration
+	}{
+		{pieceChange(0, true, false), 0},
+		{pieceChange(1, true, false), time.Second},
+		// More data doesn't restart the timer.
+		{pieceChange(0, true, false), 2 * time.Second},
+		{pieceChange(0, false, true), 4 * time.Second},
+		{pieceChange(1, false, true), 9 * time.Second},
+		// Pieces verified from disk weren't downloaded.
+		{pieceChange(2, false, true), 10 * time.Second},
+	}
+
+	c := newTestClient(t, 4)
+	if got := c.Stats().AveragePieceTime; got != 0 {
+		t.Errorf("AveragePieceTime without pieces = %s, want 0", got)
+	}
+	for _, change := range changes {
+		c.pieceTimes.observe(change.change, start.Add(change.after))
+	}
+
+	// Piece 0 took 4s, and piece 1 8s.
+	if got, want := c.Stats().AveragePieceTime, 6*time.Second; got != want {
+		t.Errorf("AveragePieceTime = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// videoExtensions are the extensions of the files listed in playlists.
+var videoExtensions = map[string]bool{
+	".avi":  true,
+	".flv":  true,
+	".m4v":  true,
+	".mkv":  true,
+	".mov":  true,
+	".mp4":  true,
+	".mpeg": true,
+	".mpg":  true,
+	".ts":   true,
+	".webm": true,
+	".wmv":  true,
+}
+
+// filesPath is where the files of the torrent are served by index, as
+// /files/<index>/<name>.
+const filesPath = "/files/"
+
+// GetFileByIndex is an http handler serving any file of the torrent by its
+// index.
+func (c *Client) GetFileByIndex(w http.ResponseWriter, r *http.Request) {
+	if !c.infoReady() {
+		http.Error(w, ErrMetadataNotReady.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	index, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(r.URL.Path, filesPath), "/", 2)[0])
+	files := c.files()
+	if err != nil || index < 0 || index >= len(files) {
+		http.NotFound(w, r)
+		return
+	}
+
+	c.serveFile(w, r, files[index])
+}
+
+// playlist builds an m3u playlist of the files, streamed from baseURL.
+func playlist(baseURL string, files []FileInfo) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("#EXTM3U\n")
+
+	for _, file := range files {
+		name := path.Base(filepath.ToSlash(file.Path))
+		fmt.Fprintf(&buffer, "#EXTINF:-1,%s\n%s%s%d/%s\n", name, baseURL, filesPath, file.Index, url.PathEscape(name))
+	}
+
+	return buffer.Bytes()
+}
+
+// playlistFiles returns the files to list in the playlist: the streamed
+// file, or all the videos in order when PlaylistAllFiles is set.
+func (c *Client) playlistFiles() []FileInfo {
+	files := c.ListFiles()
+	if !c.Config.PlaylistAllFiles {
+		if index := c.selectedIndex(); index >= 0 {
+			return files[index : index+1]
+		}
+		return nil
+	}
+
+	return videoFiles(files)
+}
+
+// videoFiles returns the videos among files, in path order, which is the
+// order of the episodes in packs.
+func videoFiles(files []FileInfo) []FileInfo {
+	var videos []FileInfo
+	for _, file := range files {
+		if videoExtensions[strings.ToLower(filepath.Ext(file.Path))] {
+			videos = append(videos, file)
+		}
+	}
+	sort.SliceStable(videos, func(i, j int) bool {
+		return videos[i].Path < videos[j].Path
+	})
+
+	return videos
+}
+
+// GetPlaylist is an http handler serving an m3u playlist for external
+// players.
+func (c *Client) GetPlaylist(w http.ResponseWriter, r *http.Request) {
+	if !c.infoReady() {
+		http.Error(w, ErrMetadataNotReady.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	w.Write(playlist("http://"+c.advertisedHost(r), c.playlistFiles()))
+}
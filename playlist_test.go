@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlaylist(t *testing.T) {
+	files := []FileInfo{
+		{Index: 0, Path: "Show/S01E01.mkv"},
+		{Index: 2, Path: "Show/S01E02 final.mp4"},
+	}
+	want := "#EXTM3U\n" +
+		"#EXTINF:-1,S01E01.mkv\nhttp://localhost:8080" + filesPath + "0/S01E01.mkv\n" +
+		"#EXTINF:-1,S01E02 final.mp4\nhttp://localhost:8080" + filesPath + "2/S01E02%20final.mp4\n"
+
+	if got := string(playlist("http://localhost:8080", files)); got != want {
+		t.Errorf("playlist() = %q, want %q", got, want)
+	}
+}
+
+func TestVideoFiles(t *testing.T) {
+	tests := []struct {
+		files []FileInfo
+		want  []FileInfo
+	}{
+		{nil, nil},
+		{
+			[]FileInfo{{Index: 0, Path: "readme.txt"}, {Index: 1, Path: "sample.srt"}},
+			nil,
+		},
+		{
+			[]FileInfo{{Index: 0, Path: "S01E02.MKV"}, {Index: 1, Path: "S01E01.mp4"}, {Index: 2, Path: "cover.jpg"}},
+			[]FileInfo{{Index: 1, Path: "S01E01.mp4"}, {Index: 0, Path: "S01E02.MKV"}},
+		},
+	}
+
+	for _, test := range tests {
+		if got := videoFiles(test.files); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("videoFiles(%v) = %v, want %v", test.files, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// prefetchInterval is how often the stream is checked for being buffered
+// enough to prefetch the next file.
+const prefetchInterval = time.Second
+
+// nextFile returns the video after the streamed one, like the next episode
+// of a pack, or nil for the last one.
+func (c *Client) nextFile() *torrent.File {
+	index := c.selectedIndex()
+	videos := videoFiles(c.ListFiles())
+	for i, video := range videos {
+		if video.Index == index && i+1 < len(videos) {
+			files := c.files()
+			return files[videos[i+1].Index]
+		}
+	}
+	return nil
+}
+
+// watchPrefetch prefetches the start of the next file while the stream is
+// buffered far enough ahead, and stops as soon as it isn't.
+func (c *Client) watchPrefetch() {
+	if !c.waitInfo() {
+		return
+	}
+
+	for c.sleep(prefetchInterval) {
+		buffered := c.streamBuffered()
+
+		c.mutex.Lock()
+		changed := c.prefetching != buffered
+		c.prefetching = buffered
+		c.mutex.Unlock()
+
+		if changed {
+			c.prioritizeTorrent()
+		}
+	}
+}
+
+// streamBuffered checks if the streamed file is downloaded the progressive
+// readahead past the read position, or to its end, with nothing buffering.
+func (c *Client) streamBuffered() bool {
+	file := c.selectedFile()
+
+	c.mutex.Lock()
+	position := c.playhead - file.Offset()
+	buffering := c.buffering > 0
+	c.mutex.Unlock()
+
+	if buffering {
+		return false
+	}
+
+	_, end := c.BufferedRange()
+	return end == file.Length() || end-position >= file.Length()*progressiveReadahead/100
+}
+
+// applyPrefetchPriority requests the first PrefetchBytes of the next file
+// while prefetching. They get the background priority, strictly below the
+// window of the stream, and nothing is prefetched while the torrent is
+// paused.
+func (c *Client) applyPrefetchPriority() {
+	c.mutex.Lock()
+	prefetching := c.prefetching
+	c.mutex.Unlock()
+
+	bulk := c.bulkPriority()
+	if !prefetching || bulk == torrent.PiecePriorityNone {
+		return
+	}
+
+	next := c.nextFile()
+	if next == nil || next.Length() == 0 {
+		return
+	}
+
+	length := c.Config.PrefetchBytes
+	if length <= 0 || length > next.Length() {
+		length = next.Length()
+	}
+
+	_, priority := backgroundPriorities(bulk)
+	pieceLength := c.Torrent.Info().PieceLength
+	first := int(next.Offset() / pieceLength)
+	last := int((next.Offset() + length - 1) / pieceLength)
+	for i := first; i <= last; i++ {
+		if c.Torrent.PieceState(i).Priority == torrent.PiecePriorityNone {
+			c.Torrent.Piece(i).SetPriority(priority)
+		}
+	}
+}
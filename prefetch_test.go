@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestPrefetchPriority(t *testing.T) {
+	const (
+		none   = torrent.PiecePriorityNone
+		normal = torrent.PiecePriorityNormal
+		high   = torrent.PiecePriorityHigh
+	)
+	tests := []struct {
+		name        string
+		prefetching bool
+		idlePaused  bool
+		priority    TorrentPriority
+		want        []torrent.PiecePriority
+	}{
+		{"not prefetching", false, false, TorrentPriorityNormal, []torrent.PiecePriority{normal, normal, none, none, none, none, none, none}},
+		{"prefetching", true, false, TorrentPriorityNormal, []torrent.PiecePriority{high, high, none, none, normal, normal, none, none}},
+		{"prefetching a high torrent", true, false, TorrentPriorityHigh, []torrent.PiecePriority{high, high, none, none, normal, normal, none, none}},
+		{"idle", true, true, TorrentPriorityNormal, []torrent.PiecePriority{none, none, none, none, none, none, none, none}},
+		{"paused", true, false, TorrentPriorityPaused, []torrent.PiecePriority{none, none, none, none, none, none, none, none}},
+	}
+
+	for _, test := range tests {
+		c := startTestClient(t, metainfo.Info{
+			Name:        "Show",
+			PieceLength: testPieceLength,
+			Pieces:      make([]byte, 20*8),
+			Files: []metainfo.FileInfo{
+				{Path: []string{"S01E01.mkv"}, Length: 4 * testPieceLength},
+				{Path: []string{"S01E02.mkv"}, Length: 4 * testPieceLength},
+			},
+		}, t.TempDir())
+		waitHashed(t, c)
+		c.Config.FileIndex = 0
+		c.Config.MaxPiecesAhead = 1
+		c.Config.PrefetchBytes = 2 * testPieceLength
+		c.prefetching = test.prefetching
+		c.idlePaused = test.idlePaused
+		c.torrentPriority = test.priority
+
+		c.prioritize()
+
+		got := make([]torrent.PiecePriority, c.Torrent.NumPieces())
+		for i := range got {
+			got[i] = c.Torrent.PieceState(i).Priority
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: piece priorities = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
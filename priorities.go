@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/anacrolix/torrent"
+)
+
+// priorityNames are how piece priorities are exposed over http.
+var priorityNames = map[torrent.PiecePriority]string{
+	torrent.PiecePriorityNone:      "none",
+	torrent.PiecePriorityNormal:    "normal",
+	torrent.PiecePriorityHigh:      "high",
+	torrent.PiecePriorityReadahead: "readahead",
+	torrent.PiecePriorityNext:      "next",
+	torrent.PiecePriorityNow:       "now",
+}
+
+func parsePriority(name string) (torrent.PiecePriority, error) {
+	for priority, priorityName := range priorityNames {
+		if priorityName == name {
+			return priority, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q", name)
+}
+
+// GetPriorities is an http handler listing the priority of every piece on
+// GET, and setting the priority of a piece or a range of pieces on POST.
+//
+// POST takes either piece, or begin and end (exclusive), and priority, and
+// needs the auth token. The priorities set stay until they're set again.
+func (c *Client) GetPriorities(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" && !c.authorizedToChange(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !c.infoReady() {
+		http.Error(w, "torrent info not received yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if err := c.setPriorities(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	priorities := make([]string, c.Torrent.NumPieces())
+	for i := 0; i < c.Torrent.NumPieces(); i++ {
+		priorities[i] = priorityNames[c.Torrent.PieceState(i).Priority]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(priorities); err != nil {
+		log.Printf("Error encoding priorities: %s\n", err)
+	}
+}
+
+func (c *Client) setPriorities(r *http.Request) error {
+	priority, err := parsePriority(r.FormValue("priority"))
+	if err != nil {
+		return err
+	}
+
+	var begin, end int
+	if piece := r.FormValue("piece"); piece != "" {
+		if begin, err = strconv.Atoi(piece); err != nil {
+			return fmt.Errorf("invalid piece %q", piece)
+		}
+		end = begin + 1
+	} else {
+		if begin, err = strconv.Atoi(r.FormValue("begin")); err != nil {
+			return fmt.Errorf("invalid begin %q", r.FormValue("begin"))
+		}
+		if end, err = strconv.Atoi(r.FormValue("end")); err != nil {
+			return fmt.Errorf("invalid end %q", r.FormValue("end"))
+		}
+	}
+
+	if begin < 0 || end > c.Torrent.NumPieces() || begin >= end {
+		return fmt.Errorf("invalid piece range %d-%d, the torrent has %d pieces", begin, end, c.Torrent.NumPieces())
+	}
+
+	// The overrides are kept, so they survive the priorities set around the
+	// playhead.
+	c.mutex.Lock()
+	for i := begin; i < end; i++ {
+		c.overrides[i] = priority
+	}
+	c.mutex.Unlock()
+
+	for i := begin; i < end; i++ {
+		c.Torrent.Piece(i).SetPriority(priority)
+	}
+
+	return nil
+}
+
+// applyPriorityOverrides sets the pieces back to the priorities set over
+// http, after they've been reprioritized.
+func (c *Client) applyPriorityOverrides() {
+	c.mutex.Lock()
+	overrides := make(map[int]torrent.PiecePriority, len(c.overrides))
+	for i, priority := range c.overrides {
+		overrides[i] = priority
+	}
+	c.mutex.Unlock()
+
+	for i, priority := range overrides {
+		c.Torrent.Piece(i).SetPriority(priority)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    torrent.PiecePriority
+		wantErr bool
+	}{
+		{"none", torrent.PiecePriorityNone, false},
+		{"normal", torrent.PiecePriorityNormal, false},
+		{"readahead", torrent.PiecePriorityReadahead, false},
+		{"now", torrent.PiecePriorityNow, false},
+		{"urgent", 0, true},
+		{"", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parsePriority(test.name)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("parsePriority(%q) = %v, %v, want %v, error %v", test.name, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestPostPrioritiesNeedsAuth(t *testing.T) {
+	c := &Client{}
+	r := httptest.NewRequest("POST", "/priorities?piece=0&priority=now", nil)
+	r.RemoteAddr = "203.0.113.5:4000"
+	w := httptest.NewRecorder()
+
+	c.GetPriorities(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("POST /priorities from another host = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPriorityOverrideSurvivesSeek(t *testing.T) {
+	c := newTestClient(t, 16)
+	c.Config.MaxPiecesAhead = 2
+	waitHashed(t, c)
+	c.prioritize()
+
+	r := httptest.NewRequest("POST", "/priorities?piece=10&priority=now", nil)
+	r.RemoteAddr = "127.0.0.1:4000"
+	w := httptest.NewRecorder()
+	c.GetPriorities(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /priorities = %d %s, want %d", w.Code, w.Body, http.StatusOK)
+	}
+
+	c.setPlayhead(5 * testPieceLength)
+
+	tests := []struct {
+		piece int
+		want  torrent.PiecePriority
+	}{
+		{0, torrent.PiecePriorityNone},
+		{5, torrent.PiecePriorityNormal},
+		{10, torrent.PiecePriorityNow},
+		{11, torrent.PiecePriorityNone},
+	}
+	for _, test := range tests {
+		if got := c.Torrent.PieceState(test.piece).Priority; got != test.want {
+			t.Errorf("priority of piece %d after seeking = %v, want %v", test.piece, got, test.want)
+		}
+	}
+}
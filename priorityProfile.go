@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent"
+)
+
+// priorityProfile is the piece priorities and read position of a torrent, as
+// saved between runs.
+type priorityProfile struct {
+	InfoHash   string
+	Playhead   int64
+	Priorities []torrent.PiecePriority
+}
+
+func (c *Client) priorityProfilePath() string {
+	return filepath.Join(c.Config.stateDir(), c.Torrent.InfoHash().HexString()+".priorities.json")
+}
+
+// savePriorities saves the piece priorities, so the next run picks up where
+// this one left off.
+func (c *Client) savePriorities() {
+	if !c.infoReady() {
+		return
+	}
+
+	c.mutex.Lock()
+	profile := priorityProfile{
+		InfoHash: c.Torrent.InfoHash().HexString(),
+		Playhead: c.playhead,
+	}
+	c.mutex.Unlock()
+	for i := 0; i < c.Torrent.NumPieces(); i++ {
+		profile.Priorities = append(profile.Priorities, c.Torrent.PieceState(i).Priority)
+	}
+
+	data, err := json.Marshal(profile)
+	if err != nil {
+		log.Printf("Error encoding piece priorities: %s\n", err)
+		return
+	}
+	if err := os.MkdirAll(c.Config.stateDir(), 0755); err != nil {
+		log.Printf("Error creating state directory: %s\n", err)
+		return
+	}
+	if err := ioutil.WriteFile(c.priorityProfilePath(), data, 0644); err != nil {
+		log.Printf("Error writing piece priorities: %s\n", err)
+	}
+}
+
+// restorePriorities restores the piece priorities saved by the previous run,
+// instead of starting from the beginning of the file. It returns false when
+// there's nothing to restore, or the profile doesn't match the torrent.
+func (c *Client) restorePriorities() bool {
+	data, err := ioutil.ReadFile(c.priorityProfilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading piece priorities: %s\n", err)
+		}
+		return false
+	}
+
+	var profile priorityProfile
+	if err := json.Unmarshal(data, &profile); err != nil ||
+		profile.InfoHash != c.Torrent.InfoHash().HexString() ||
+		len(profile.Priorities) != c.Torrent.NumPieces() {
+		log.Printf("Ignoring invalid piece priorities %s\n", c.priorityProfilePath())
+		return false
+	}
+
+	c.mutex.Lock()
+	c.playhead = profile.Playhead
+	c.mutex.Unlock()
+	for i, priority := range profile.Priorities {
+		c.Torrent.Piece(i).SetPriority(priority)
+	}
+	c.applyOverrides()
+
+	return true
+}
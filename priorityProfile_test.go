@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// piecePriorities returns the priority of every piece of the torrent of c.
+func piecePriorities(c *Client) []torrent.PiecePriority {
+	priorities := make([]torrent.PiecePriority, c.Torrent.NumPieces())
+	for i := range priorities {
+		priorities[i] = c.Torrent.PieceState(i).Priority
+	}
+	return priorities
+}
+
+func TestPriorityProfileRoundTrips(t *testing.T) {
+	info := metainfo.Info{Name: "movie.mkv", PieceLength: testPieceLength, Length: 6 * testPieceLength, Pieces: make([]byte, 20*6)}
+	stateDir := t.TempDir()
+	first := startTestClient(t, info, t.TempDir())
+	waitHashed(t, first)
+	first.Config.DataDir = stateDir
+	first.Config.MaxPiecesAhead = 1
+	first.setPlayhead(3*testPieceLength + 10)
+	want := piecePriorities(first)
+	first.savePriorities()
+
+	second := startTestClient(t, info, t.TempDir())
+	waitHashed(t, second)
+	second.Config.DataDir = stateDir
+	if got := piecePriorities(second); reflect.DeepEqual(got, want) {
+		t.Fatalf("piece priorities on a second start = %v before restoring, want them to differ", got)
+	}
+
+	if !second.restorePriorities() {
+		t.Fatal("restorePriorities() = false, want the saved profile restored")
+	}
+	if got := piecePriorities(second); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored piece priorities = %v, want %v", got, want)
+	}
+	if second.playhead != 3*testPieceLength+10 {
+		t.Errorf("restored playhead = %d, want %d", second.playhead, 3*testPieceLength+10)
+	}
+
+	// Another torrent doesn't pick up the profile.
+	other := newTestClient(t, 2)
+	other.Config.DataDir = stateDir
+	data, err := os.ReadFile(first.priorityProfilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other.priorityProfilePath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if other.restorePriorities() {
+		t.Error("restorePriorities() of another torrent's profile = true, want false")
+	}
+}
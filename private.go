@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// ErrPrivateTorrent is returned for private torrents outside of private mode.
+var ErrPrivateTorrent = errors.New("the torrent is private, enable private mode to only find peers through its trackers")
+
+// isPrivate checks the private flag of a torrent info.
+func isPrivate(info *metainfo.Info) bool {
+	return info.Private != nil && *info.Private
+}
+
+// checkPrivate refuses private torrents outside of private mode, so they
+// aren't leaked to the DHT and peer exchange, which can get users banned
+// from private trackers.
+func (c *Client) checkPrivate(info *metainfo.Info) error {
+	if c.Config.PrivateMode || !isPrivate(info) {
+		return nil
+	}
+
+	return ClientError{Type: "private torrent requires private mode", Origin: ErrPrivateTorrent}
+}
+
+// checkInfo refuses torrents with nothing to stream, and private torrents
+// outside of private mode.
+func (c *Client) checkInfo(info *metainfo.Info) error {
+	if err := validateInfo(info); err != nil {
+		return err
+	}
+	return c.checkPrivate(info)
+}
+
+// checkSpec runs checkInfo before a torrent is added, when its info is
+// already known from a torrent file. Magnets are checked once their info is
+// received.
+func (c *Client) checkSpec(spec *torrent.TorrentSpec) error {
+	if len(spec.InfoBytes) == 0 {
+		return nil
+	}
+
+	var info metainfo.Info
+	if err := bencode.Unmarshal(spec.InfoBytes, &info); err != nil {
+		return ClientError{Type: "parsing torrent info", Origin: err}
+	}
+	return c.checkInfo(&info)
+}
+
+// checkReceivedInfo runs checkInfo on an added torrent once its info is
+// received, and drops the torrent if it fails, as magnets can't be checked by
+// checkSpec.
+func (c *Client) checkReceivedInfo(t *torrent.Torrent) {
+	select {
+	case <-t.GotInfo():
+	case <-t.Closed():
+		return
+	case <-c.closing:
+		return
+	}
+
+	if err := c.checkInfo(t.Info()); err != nil {
+		log.Printf("Error dropping %s: %s\n", t.Name(), err)
+		t.Drop()
+	}
+}
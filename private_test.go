@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestCheckSpec(t *testing.T) {
+	private := true
+	public := false
+	infoBytes := func(info metainfo.Info) []byte {
+		info.Name = "movie.mkv"
+		info.PieceLength = 16384
+		info.Pieces = make([]byte, 20)
+		data, err := bencode.Marshal(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name        string
+		privateMode bool
+		infoBytes   []byte
+		want        error
+	}{
+		{"magnet", false, nil, nil},
+		{"public", false, infoBytes(metainfo.Info{Length: 1000, Private: &public}), nil},
+		{"unflagged", false, infoBytes(metainfo.Info{Length: 1000}), nil},
+		{"private outside of private mode", false, infoBytes(metainfo.Info{Length: 1000, Private: &private}), ErrPrivateTorrent},
+		{"private in private mode", true, infoBytes(metainfo.Info{Length: 1000, Private: &private}), nil},
+		{"empty", false, infoBytes(metainfo.Info{}), ErrEmptyTorrent},
+		{"empty files", false, infoBytes(metainfo.Info{Files: []metainfo.FileInfo{{Path: []string{"a"}}, {Path: []string{"b"}}}}), ErrEmptyTorrent},
+	}
+
+	for _, test := range tests {
+		c := &Client{Config: ClientConfig{PrivateMode: test.privateMode}}
+		spec := &torrent.TorrentSpec{}
+		spec.InfoBytes = test.infoBytes
+		err := c.checkSpec(spec)
+
+		var clientError ClientError
+		if errors.As(err, &clientError) {
+			err = clientError.Origin
+		}
+		if err != test.want {
+			t.Errorf("%s: checkSpec() = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestCheckReceivedInfo(t *testing.T) {
+	private := true
+	tests := []struct {
+		privateMode bool
+		dropped     bool
+	}{
+		{false, true},
+		{true, false},
+	}
+
+	for _, test := range tests {
+		c := newTestClient(t, 1)
+		c.Config.PrivateMode = test.privateMode
+
+		infoBytes, err := bencode.Marshal(metainfo.Info{
+			Name:        "private.mkv",
+			PieceLength: 16384,
+			Length:      1000,
+			Pieces:      make([]byte, 20),
+			Private:     &private,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec := &torrent.TorrentSpec{}
+		spec.InfoHash = metainfo.HashBytes(infoBytes)
+		spec.InfoBytes = infoBytes
+		tor, _, err := c.Client.AddTorrentSpec(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			c.checkReceivedInfo(tor)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("checkReceivedInfo didn't return")
+		}
+
+		dropped := false
+		select {
+		case <-tor.Closed():
+			dropped = true
+		default:
+		}
+		if dropped != test.dropped {
+			t.Errorf("private torrent received with private mode %v dropped = %v, want %v", test.privateMode, dropped, test.dropped)
+		}
+	}
+}
+
+func TestWarmStartRefusesLocalFiles(t *testing.T) {
+	c := newTestClient(t, 1)
+
+	result := c.warmStart("/etc/passwd", false)
+
+	var clientError ClientError
+	if !errors.As(result.Error, &clientError) || clientError.Origin != ErrNotRemote {
+		t.Errorf("warmStart(local file) error = %v, want %v", result.Error, ErrNotRemote)
+	}
+}
+
+func TestEmptyTorrentStats(t *testing.T) {
+	c := startTestClient(t, metainfo.Info{
+		Name:        "Empty",
+		PieceLength: testPieceLength,
+		Files:       []metainfo.FileInfo{{Path: []string{"a.mkv"}}, {Path: []string{"b.mkv"}}},
+	}, t.TempDir())
+
+	err := c.checkInfo(c.Torrent.Info())
+	if clientError, ok := err.(ClientError); !ok || clientError.Type != "empty torrent" || clientError.Origin != ErrEmptyTorrent {
+		t.Fatalf("checkInfo() = %v, want %v", err, ClientError{Type: "empty torrent", Origin: ErrEmptyTorrent})
+	}
+	c.setErr(err)
+
+	stats := c.Stats()
+	if math.IsNaN(stats.Percentage) || math.IsNaN(c.checkingProgress()) {
+		t.Errorf("Stats() of an empty torrent = %+v, want no NaN", stats)
+	}
+	if stats.Error != err.Error() {
+		t.Errorf("Stats().Error = %q, want %q", stats.Error, err)
+	}
+	if c.ReadyForPlayback() {
+		t.Error("empty torrent ready for playback")
+	}
+
+	// A NaN can't be encoded.
+	w := httptest.NewRecorder()
+	c.GetStatus(w, httptest.NewRequest("GET", "/status", nil))
+	var got Stats
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Errorf("GET /status of an empty torrent: %s", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxFileMetrics bounds how many files get their own metrics, to keep the
+// label cardinality in check on huge packs.
+const maxFileMetrics = 500
+
+var warnFileMetrics sync.Once
+
+// labelEscaper escapes prometheus label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// fileMetrics are the per file gauges.
+type fileMetrics struct {
+	Index          int
+	Path           string
+	BytesCompleted int64
+	Ready          bool
+}
+
+// fileBytesCompleted returns how many bytes of a file are in complete pieces.
+func (c *Client) fileBytesCompleted(offset, length int64) (completed int64) {
+	pieceLength := c.Torrent.Info().PieceLength
+	end := offset + length
+	for i := int(offset / pieceLength); int64(i)*pieceLength < end; i++ {
+		if !c.Torrent.PieceState(i).Complete {
+			continue
+		}
+
+		begin, finish := int64(i)*pieceLength, int64(i+1)*pieceLength
+		if begin < offset {
+			begin = offset
+		}
+		if finish > end {
+			finish = end
+		}
+		completed += finish - begin
+	}
+
+	return
+}
+
+// FileMetrics returns the progress of each file, up to maxFileMetrics files.
+func (c *Client) FileMetrics() []fileMetrics {
+	files := c.files()
+	if len(files) > maxFileMetrics {
+		warnFileMetrics.Do(func() {
+			log.Printf("The torrent has %d files, only the first %d get metrics\n", len(files), maxFileMetrics)
+		})
+		files = files[:maxFileMetrics]
+	}
+
+	metrics := make([]fileMetrics, len(files))
+	for i := range files {
+		completed := c.fileBytesCompleted(files[i].Offset(), files[i].Length())
+		metrics[i] = fileMetrics{
+			Index:          i,
+			Path:           torrentFileName(c.Torrent, files[i]),
+			BytesCompleted: completed,
+			// Same as ReadyForPlayback, for the file alone.
+			Ready: c.readyFor(completed, files[i].Length()),
+		}
+	}
+
+	return metrics
+}
+
+// writePrometheusMetrics writes the stats in the prometheus text format.
+func writePrometheusMetrics(w io.Writer, stats Stats, files []fileMetrics) error {
+	gauges := []struct {
+		name  string
+		help  string
+		value interface{}
+	}{
+		{"peerflix_download_rate_bytes", "Download speed in bytes per second.", stats.DownloadSpeed},
+		{"peerflix_bytes_completed", "Bytes of the torrent downloaded.", stats.BytesCompleted},
+		{"peerflix_length_bytes", "Size of the torrent.", stats.Length},
+		{"peerflix_connections", "Connected peers.", stats.Connections},
+		{"peerflix_average_piece_seconds", "Average time to download a piece.", stats.AveragePieceTime.Seconds()},
+	}
+
+	for _, gauge := range gauges {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", gauge.name, gauge.help, gauge.name, gauge.name, gauge.value); err != nil {
+			return err
+		}
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprint(w, "# HELP peerflix_file_bytes_completed Bytes of the file downloaded.\n# TYPE peerflix_file_bytes_completed gauge\n"); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, err := fmt.Fprintf(w, "peerflix_file_bytes_completed{index=\"%d\",path=\"%s\"} %d\n", file.Index, labelEscaper.Replace(file.Path), file.BytesCompleted); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprint(w, "# HELP peerflix_file_ready Whether enough of the file is downloaded to play it.\n# TYPE peerflix_file_ready gauge\n"); err != nil {
+		return err
+	}
+	for _, file := range files {
+		ready := 0
+		if file.Ready {
+			ready = 1
+		}
+		if _, err := fmt.Fprintf(w, "peerflix_file_ready{index=\"%d\",path=\"%s\"} %d\n", file.Index, labelEscaper.Replace(file.Path), ready); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMetrics is an http handler serving the metrics to prometheus, with
+// per file gauges when PerFileMetrics is set.
+func (c *Client) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	var files []fileMetrics
+	if c.Config.PerFileMetrics && c.infoReady() {
+		files = c.FileMetrics()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := writePrometheusMetrics(w, c.Stats(), files); err != nil {
+		log.Printf("Error writing metrics: %s\n", err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestReadyFor(t *testing.T) {
+	tests := []struct {
+		completed int64
+		length    int64
+		ready     float64
+		want      bool
+	}{
+		{0, 0, 5, false},
+		{0, 100, 5, false},
+		{5, 100, 5, false},
+		{6, 100, 5, true},
+		{6, 100, 10, false},
+		{11, 100, 10, true},
+		{1, 100, 0, true},
+	}
+
+	for _, test := range tests {
+		c := &Client{}
+		c.Config.ReadyPercentage = test.ready
+		if got := c.readyFor(test.completed, test.length); got != test.want {
+			t.Errorf("readyFor(%d, %d) at %v%% = %v, want %v", test.completed, test.length, test.ready, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"image"
+	// Register the formats screenshots usually come in.
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// ErrInvalidQRCode is returned when a QR code doesn't hold a magnet or url.
+var ErrInvalidQRCode = errors.New("qr code doesn't contain a magnet link or url")
+
+// decodeQRCode reads the magnet link or torrent url from a QR code image.
+func decodeQRCode(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing qr code image: %s\n", err)
+		}
+	}()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", err
+	}
+
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, nil)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSpace(result.GetText())
+	if !strings.HasPrefix(text, "magnet:") && !isHTTP.MatchString(text) {
+		return "", ErrInvalidQRCode
+	}
+
+	return text, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// writeQRCode writes a png image of a QR code holding the text.
+func writeQRCode(t *testing.T, text string) string {
+	t.Helper()
+
+	matrix, err := qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, 256, 256, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePNG(t, matrix)
+}
+
+func writePNG(t *testing.T, img image.Image) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "code.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDecodeQRCode(t *testing.T) {
+	const magnet = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=movie"
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"magnet", writeQRCode(t, magnet), magnet, false},
+		{"url", writeQRCode(t, "https://example.com/movie.torrent"), "https://example.com/movie.torrent", false},
+		{"text", writeQRCode(t, "hello"), "", true},
+		{"no qr code", writePNG(t, image.NewGray(image.Rect(0, 0, 64, 64))), "", true},
+		{"missing image", filepath.Join(t.TempDir(), "missing.png"), "", true},
+	}
+
+	for _, test := range tests {
+		got, err := decodeQRCode(test.path)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("%s: decodeQRCode() = %q, %v, want %q, error %v", test.name, got, err, test.want, test.wantErr)
+		}
+	}
+
+	if _, err := decodeQRCode(writeQRCode(t, "hello")); err != ErrInvalidQRCode {
+		t.Errorf("decodeQRCode() of text = %v, want %v", err, ErrInvalidQRCode)
+	}
+}
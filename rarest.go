@@ -0,0 +1,167 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+const (
+	// rarestInterval is how often the background pieces are picked again.
+	rarestInterval = 2 * time.Second
+	// rarestBatch is how many rare pieces are downloaded in the background
+	// at once, so they don't compete with the stream for long.
+	rarestBatch = 4
+)
+
+// downloadRarest keeps requesting the rarest pieces outside the streaming
+// window while the window is downloaded, so the torrent still completes and
+// the pieces the swarm lacks are shared. They are requested below the window,
+// and released as soon as it's missing pieces again.
+func (c *Client) downloadRarest() {
+	if !c.waitInfo() {
+		return
+	}
+
+	ticker := time.NewTicker(rarestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.torrentCompleted:
+			return
+		case <-c.closing:
+			return
+		case <-ticker.C:
+		}
+
+		// Without a window, every piece ahead is already requested.
+		windowReady := c.maxPiecesAhead() > 0 && c.streamingWindowReady()
+		if c.followsPlayhead() && c.updateBackgroundPieces(windowReady) {
+			c.prioritize()
+		}
+	}
+}
+
+// backgroundPriorities returns the priority of the streaming window while
+// pieces are downloaded in the background, and the strictly lower one of the
+// background pieces.
+func backgroundPriorities(bulk torrent.PiecePriority) (window, background torrent.PiecePriority) {
+	window = bulk
+	if window < torrent.PiecePriorityHigh {
+		window = torrent.PiecePriorityHigh
+	}
+	return window, torrent.PiecePriorityNormal
+}
+
+// updateBackgroundPieces picks the pieces downloaded in the background, or
+// releases them when the stream needs the bandwidth. It reports if they
+// changed.
+func (c *Client) updateBackgroundPieces(windowReady bool) bool {
+	var rarest []int
+	if windowReady {
+		rarest = c.rarestPieces()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	changed := false
+	for i := range c.backgroundPieces {
+		if !windowReady || c.Torrent.PieceState(i).Complete {
+			delete(c.backgroundPieces, i)
+			changed = true
+		}
+	}
+	if !windowReady || len(c.backgroundPieces) >= rarestBatch {
+		return changed
+	}
+
+	for _, i := range rarest {
+		if len(c.backgroundPieces) >= rarestBatch {
+			break
+		}
+		if _, ok := c.backgroundPieces[i]; !ok {
+			c.backgroundPieces[i] = struct{}{}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rarestPieces returns the missing pieces outside the streaming window that
+// some peer has, the ones the fewest peers have first.
+func (c *Client) rarestPieces() []int {
+	t := c.Torrent
+	pieceLength := t.Info().PieceLength
+	piecesAhead := c.maxPiecesAhead()
+
+	c.mutex.Lock()
+	playhead := c.playhead
+	excluded := c.excluded
+	c.mutex.Unlock()
+
+	current := int(playhead / pieceLength)
+	dropBefore := c.dropBefore(playhead, pieceLength)
+
+	availability := make(map[int]int)
+	conns := t.PeerConns()
+	for i := 0; i < t.NumPieces(); i++ {
+		inWindow := i >= current && (piecesAhead <= 0 || i <= current+piecesAhead)
+		isExcluded := i < len(excluded) && excluded[i]
+		if inWindow || isExcluded || i < dropBefore || t.PieceState(i).Complete {
+			continue
+		}
+
+		for _, conn := range conns {
+			if pieces := conn.PeerPieces(); pieces != nil && pieces.Contains(uint32(i)) {
+				availability[i]++
+			}
+		}
+	}
+
+	pieces := make([]int, 0, len(availability))
+	for i := range availability {
+		pieces = append(pieces, i)
+	}
+	sort.Slice(pieces, func(a, b int) bool {
+		if availability[pieces[a]] != availability[pieces[b]] {
+			return availability[pieces[a]] < availability[pieces[b]]
+		}
+		return pieces[a] < pieces[b]
+	})
+	return pieces
+}
+
+// streamingWindowReady checks if every piece of the streaming window is
+// downloaded and nothing is buffering, so there's bandwidth to spare.
+func (c *Client) streamingWindowReady() bool {
+	if c.bulkPriority() == torrent.PiecePriorityNone {
+		return false
+	}
+
+	t := c.Torrent
+	pieceLength := t.Info().PieceLength
+	piecesAhead := c.maxPiecesAhead()
+
+	c.mutex.Lock()
+	current := int(c.playhead / pieceLength)
+	buffering := c.buffering > 0
+	c.mutex.Unlock()
+
+	if buffering {
+		return false
+	}
+
+	end := t.NumPieces() - 1
+	if piecesAhead > 0 && current+piecesAhead < end {
+		end = current + piecesAhead
+	}
+	for i := current; i <= end; i++ {
+		if !t.PieceState(i).Complete {
+			return false
+		}
+	}
+	return true
+}
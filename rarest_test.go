@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestBackgroundPriorities(t *testing.T) {
+	tests := []struct {
+		bulk       torrent.PiecePriority
+		window     torrent.PiecePriority
+		background torrent.PiecePriority
+	}{
+		{torrent.PiecePriorityNormal, torrent.PiecePriorityHigh, torrent.PiecePriorityNormal},
+		{torrent.PiecePriorityHigh, torrent.PiecePriorityHigh, torrent.PiecePriorityNormal},
+	}
+
+	for _, test := range tests {
+		window, background := backgroundPriorities(test.bulk)
+		if window != test.window || background != test.background {
+			t.Errorf("backgroundPriorities(%v) = %v, %v, want %v, %v", test.bulk, window, background, test.window, test.background)
+		}
+		if background >= window || background == torrent.PiecePriorityNone {
+			t.Errorf("backgroundPriorities(%v): background %v isn't wanted below the window %v", test.bulk, background, window)
+		}
+	}
+}
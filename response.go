@@ -0,0 +1,19 @@
+package main
+
+import (
+	"io"
+	"net/http"
+)
+
+// bufferedResponseWriter makes http.ServeContent copy the content into the
+// response using a buffer of the configured size.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	size int
+}
+
+// ReadFrom copies from the reader into the response through the buffer.
+func (w bufferedResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	// Hide the ResponseWriter's own ReadFrom, so our buffer gets used.
+	return io.CopyBuffer(struct{ io.Writer }{w.ResponseWriter}, r, make([]byte, w.size))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// writeSizesRecorder records the size of every write to the response.
+type writeSizesRecorder struct {
+	*httptest.ResponseRecorder
+	sizes []int
+}
+
+func (w *writeSizesRecorder) Write(p []byte) (int, error) {
+	w.sizes = append(w.sizes, len(p))
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestResponseBufferSize(t *testing.T) {
+	data := make([]byte, 3*testPieceLength)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	c := newSeededTestClient(t, data)
+
+	for _, size := range []int{1024, 4096} {
+		c.Config.ResponseBufferSize = size
+		w := &writeSizesRecorder{ResponseRecorder: httptest.NewRecorder()}
+		c.GetFile(w, httptest.NewRequest("GET", "/", nil))
+
+		if !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("GET / with a buffer of %d returned %d bytes, want the %d of the file", size, w.Body.Len(), len(data))
+		}
+		largest := 0
+		for _, written := range w.sizes {
+			if written > largest {
+				largest = written
+			}
+		}
+		if largest != size {
+			t.Errorf("largest write with a buffer of %d = %d bytes, want %d", size, largest, size)
+		}
+	}
+}
+
+func BenchmarkResponseBufferSize(b *testing.B) {
+	data := make([]byte, 16<<20)
+	for _, size := range []int{4 << 10, 32 << 10, 256 << 10, 1 << 20} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				w := bufferedResponseWriter{ResponseWriter: discardResponseWriter{}, size: size}
+				http.ServeContent(w, httptest.NewRequest("GET", "/", nil), "movie.mkv", time.Time{}, bytes.NewReader(data))
+			}
+		})
+	}
+}
+
+// discardResponseWriter is a response writer discarding the body.
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header         { return http.Header{} }
+func (discardResponseWriter) Write(p []byte) (int, error) { return io.Discard.Write(p) }
+func (discardResponseWriter) WriteHeader(int)             {}
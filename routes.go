@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Route is an endpoint of the http api. The routes are both registered on
+// the server and described in /openapi.json from Routes, so they can't drift
+// apart.
+type Route struct {
+	Path    string
+	Methods []string
+	Summary string
+	// ContentType is the type of the successful responses, if they have a
+	// body.
+	ContentType string
+	// SpecPath documents routes matching a prefix, like /files/{index}.
+	SpecPath string
+	Handler  http.HandlerFunc
+}
+
+// Routes returns the endpoints of the http api.
+func (c *Client) Routes() []Route {
+	get := []string{http.MethodGet}
+
+	routes := []Route{
+		{Path: "/", Methods: get, Summary: "Stream the selected file", ContentType: "application/octet-stream", Handler: c.GetFile},
+		{Path: "/status", Methods: get, Summary: "Stats of the client", ContentType: "application/json", Handler: c.GetStatus},
+		{Path: "/ws", Methods: get, Summary: "Websocket pushing the stats and the buffering and peer events, needs the auth token", Handler: c.GetWebSocket},
+		{Path: "/ui", Methods: get, Summary: "Web ui", ContentType: "text/html", Handler: c.GetIndex},
+		{Path: "/metadata", Methods: get, Summary: "Poster and synopsis of the movie or show", ContentType: "application/json", Handler: c.GetMetadata},
+		{Path: "/metrics", Methods: get, Summary: "Prometheus metrics", ContentType: "text/plain", Handler: c.GetMetrics},
+		{Path: "/chapters.vtt", Methods: get, Summary: "Chapters of the selected file", ContentType: "text/vtt", Handler: c.GetChapters},
+		{Path: "/subtitles.vtt", Methods: get, Summary: "Subtitles in the torrent matching the selected file", ContentType: "text/vtt", Handler: c.GetSubtitles},
+		{Path: "/playlist.m3u", Methods: get, Summary: "Playlist for external players", ContentType: "audio/x-mpegurl", Handler: c.GetPlaylist},
+		{Path: filesPath, Methods: get, Summary: "Stream a file of the torrent by index", ContentType: "application/octet-stream", SpecPath: filesPath + "{index}/{name}", Handler: c.GetFileByIndex},
+		{Path: "/transcode", Methods: get, Summary: "Remux the selected file with ffmpeg, when enabled", ContentType: "video/x-matroska", Handler: c.GetTranscode},
+		{Path: "/audio", Methods: get, Summary: "Only the audio of the selected file, when enabled", ContentType: "audio/aac", Handler: c.GetAudio},
+		{Path: "/preview", Methods: get, Summary: "Low bitrate preview while the file buffers", ContentType: "video/x-matroska", Handler: c.GetPreview},
+		{Path: "/trackers", Methods: get, Summary: "Trackers of the torrent", ContentType: "application/json", Handler: c.GetTrackers},
+		{Path: "/magnet", Methods: get, Summary: "Magnet link of the torrent", ContentType: "text/plain", Handler: c.GetMagnet},
+		{Path: "/priorities", Methods: []string{http.MethodGet, http.MethodPost}, Summary: "Piece priorities, set with priority and piece or begin and end, which needs the auth token", ContentType: "application/json", Handler: c.GetPriorities},
+		{Path: "/add", Methods: []string{http.MethodPost}, Summary: "Add the torrent parameter next to the streamed one, needs the auth token", ContentType: "application/json", Handler: c.PostAdd},
+		{Path: "/shutdown", Methods: []string{http.MethodPost}, Summary: "Exit once the streams finish or the drain period ends, needs the auth token", Handler: c.PostShutdown},
+		{Path: "/config", Methods: []string{http.MethodGet, http.MethodPatch}, Summary: "Streaming parameters, changed with a json body, needs the auth token", ContentType: "application/json", Handler: c.GetConfig},
+		{Path: "/logs", Methods: get, Summary: "Live log as server-sent events, needs the auth token", ContentType: "text/event-stream", Handler: c.GetLogs},
+		{Path: "/openapi.json", Methods: get, Summary: "This OpenAPI description", ContentType: "application/json", Handler: c.GetOpenAPI},
+	}
+	if c.dlna != nil {
+		routes = append(routes, c.dlna.Routes()...)
+	}
+
+	return routes
+}
+
+// RegisterRoutes registers the http api on a mux.
+func (c *Client) RegisterRoutes(mux *http.ServeMux) {
+	for _, route := range c.Routes() {
+		mux.HandleFunc(route.Path, route.Handler)
+	}
+}
+
+// openAPISpec describes the routes as an OpenAPI 3 document.
+func openAPISpec(routes []Route) map[string]interface{} {
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		path := route.Path
+		if route.SpecPath != "" {
+			path = route.SpecPath
+		}
+
+		var parameters []interface{}
+		for _, segment := range strings.Split(path, "/") {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     strings.Trim(segment, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]string{"type": "string"},
+				})
+			}
+		}
+
+		operations := make(map[string]interface{})
+		for _, method := range route.Methods {
+			response := map[string]interface{}{"description": "OK"}
+			if route.ContentType != "" {
+				response["content"] = map[string]interface{}{route.ContentType: map[string]interface{}{}}
+			}
+			operation := map[string]interface{}{
+				"summary":   route.Summary,
+				"responses": map[string]interface{}{"200": response},
+			}
+			if parameters != nil {
+				operation["parameters"] = parameters
+			}
+			operations[openAPIMethod(method)] = operation
+		}
+		paths[path] = operations
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   "go-peerflix",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	}
+}
+
+// openAPIMethods are the http methods OpenAPI has operations for.
+var openAPIMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+}
+
+// openAPIMethod returns the key of a method's operation. Others, like the
+// SUBSCRIBE of UPnP, are documented as extensions.
+func openAPIMethod(method string) string {
+	if openAPIMethods[method] {
+		return strings.ToLower(method)
+	}
+	return "x-" + strings.ToLower(method)
+}
+
+// GetOpenAPI is an http handler describing the http api.
+func (c *Client) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(openAPISpec(c.Routes())); err != nil {
+		log.Printf("Error encoding the OpenAPI description: %s\n", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestOpenAPIMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"GET", "get"},
+		{"PATCH", "patch"},
+		{"SUBSCRIBE", "x-subscribe"},
+	}
+
+	for _, test := range tests {
+		if got := openAPIMethod(test.method); got != test.want {
+			t.Errorf("openAPIMethod(%q) = %q, want %q", test.method, got, test.want)
+		}
+	}
+}
+
+func TestOpenAPISpecListsDLNA(t *testing.T) {
+	tests := []struct {
+		dlna bool
+		path string
+		want bool
+	}{
+		{false, "/status", true},
+		{false, dlnaDevicePath, false},
+		{true, "/status", true},
+		{true, dlnaDevicePath, true},
+		{true, dlnaControlPath + "{service}", true},
+		{true, dlnaEventPath + "{service}", true},
+	}
+
+	for _, test := range tests {
+		c := &Client{}
+		if test.dlna {
+			c.dlna = &DLNAServer{client: c}
+		}
+		paths := openAPISpec(c.Routes())["paths"].(map[string]interface{})
+		if _, got := paths[test.path]; got != test.want {
+			t.Errorf("%s listed with DLNA %v = %v, want %v", test.path, test.dlna, got, test.want)
+		}
+	}
+}
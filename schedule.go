@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// scheduleInterval is how often the seeding schedule is checked.
+const scheduleInterval = time.Minute
+
+// SeedSchedule is the time of day uploading is allowed in, as offsets from
+// midnight. The window wraps around midnight when End is before Start. The
+// zero value has no schedule.
+type SeedSchedule struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Enabled checks if there's a schedule.
+func (s SeedSchedule) Enabled() bool {
+	return s.Start != s.End
+}
+
+// Contains checks if a time falls within the window, on the local clock.
+func (s SeedSchedule) Contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if s.Start <= s.End {
+		return offset >= s.Start && offset < s.End
+	}
+	return offset >= s.Start || offset < s.End
+}
+
+// String formats the schedule as a flag value.
+func (s *SeedSchedule) String() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return formatTimeOfDay(s.Start) + "-" + formatTimeOfDay(s.End)
+}
+
+// Set parses a schedule of the form "01:00-07:00".
+func (s *SeedSchedule) Set(value string) error {
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid seed schedule %q, expected hh:mm-hh:mm", value)
+	}
+
+	start, err := parseTimeOfDay(parts[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseTimeOfDay(parts[1])
+	if err != nil {
+		return err
+	}
+
+	s.Start, s.End = start, end
+	return nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected hh:mm", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// followSeedSchedule only allows uploading within the seeding schedule.
+func (c *Client) followSeedSchedule() {
+	for {
+		c.applySeedSchedule()
+		if !c.sleep(scheduleInterval) {
+			return
+		}
+	}
+}
+
+// applySeedSchedule enables or disables uploading for the current time.
+func (c *Client) applySeedSchedule() {
+	upload := c.Config.SeedSchedule.Contains(c.now())
+	c.mutex.Lock()
+	uploading := c.uploading
+	c.mutex.Unlock()
+	if uploading == upload {
+		return
+	}
+
+	if upload {
+		log.Println("Within the seeding schedule, uploading")
+	} else {
+		log.Println("Outside the seeding schedule, not uploading")
+	}
+	c.setUploading(upload)
+}
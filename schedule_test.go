@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+func TestSeedScheduleContains(t *testing.T) {
+	night := SeedSchedule{Start: 23 * time.Hour, End: 7 * time.Hour}
+	day := SeedSchedule{Start: 9 * time.Hour, End: 17 * time.Hour}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2020, 1, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		schedule SeedSchedule
+		time     time.Time
+		want     bool
+	}{
+		{day, at(9, 0), true},
+		{day, at(12, 30), true},
+		{day, at(17, 0), false},
+		{day, at(8, 59), false},
+		{night, at(23, 0), true},
+		{night, at(2, 0), true},
+		{night, at(7, 0), false},
+		{night, at(12, 0), false},
+	}
+
+	for _, test := range tests {
+		if got := test.schedule.Contains(test.time); got != test.want {
+			t.Errorf("%s contains %s = %v, want %v", test.schedule.String(), test.time.Format("15:04"), got, test.want)
+		}
+	}
+}
+
+func TestSeedScheduleSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SeedSchedule
+		wantErr bool
+	}{
+		{"01:00-07:00", SeedSchedule{Start: time.Hour, End: 7 * time.Hour}, false},
+		{"23:30 - 06:15", SeedSchedule{Start: 23*time.Hour + 30*time.Minute, End: 6*time.Hour + 15*time.Minute}, false},
+		{"01:00", SeedSchedule{}, true},
+		{"25:00-07:00", SeedSchedule{}, true},
+		{"night-day", SeedSchedule{}, true},
+	}
+
+	for _, test := range tests {
+		var got SeedSchedule
+		err := got.Set(test.value)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("Set(%q) = %+v, %v, want %+v, error %v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestApplySeedScheduleTogglesUploads(t *testing.T) {
+	config := torrent.NewDefaultClientConfig()
+	config.DataDir = t.TempDir()
+	config.ListenPort = 0
+	config.NoDHT = true
+	config.DisableTrackers = true
+	config.Seed = true
+	cl, err := torrent.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	if _, err := cl.AddMagnet("magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	c := &Client{
+		Client:    cl,
+		Config:    ClientConfig{Seed: true, SeedSchedule: SeedSchedule{Start: 23 * time.Hour, End: 7 * time.Hour}},
+		uploading: true,
+		now:       func() time.Time { return now },
+	}
+
+	c.applySeedSchedule()
+	if c.uploading {
+		t.Error("uploading outside of the seeding schedule")
+	}
+
+	now = time.Date(2020, 1, 2, 1, 0, 0, 0, time.Local)
+	c.applySeedSchedule()
+	if !c.uploading {
+		t.Error("not uploading within the seeding schedule")
+	}
+}
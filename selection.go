@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/anacrolix/torrent"
+)
+
+// SetSelectedFile switches the streamed file to the one at index. The streams
+// already reading the previous file keep reading it, or are closed when
+// CloseOnFileSwitch is set. A reader is never moved to another file.
+func (c *Client) SetSelectedFile(index int) error {
+	if !c.infoReady() {
+		return ClientError{Type: "selecting file", Origin: ErrMetadataNotReady}
+	}
+	files := c.files()
+	if index < 0 || index >= len(files) {
+		return ClientError{Type: "selecting file", Origin: fmt.Errorf("invalid file index %d, the torrent has %d files", index, len(files))}
+	}
+
+	previous := c.selectedFile()
+	c.mutex.Lock()
+	c.Config.FileIndex = index
+	c.mutex.Unlock()
+
+	selected := c.selectedFile()
+	if selected == previous {
+		return nil
+	}
+	log.Printf("Streaming %s\n", selected.Path())
+
+	// The probes were of the previous file.
+	c.mutex.Lock()
+	c.chapters = nil
+	c.audioLanguages = nil
+	c.mutex.Unlock()
+
+	if c.Config.CloseOnFileSwitch {
+		c.closeReaders(previous)
+	}
+
+	c.setPlayhead(selected.Offset())
+	c.prioritizeTorrent()
+
+	return nil
+}
+
+// closeReaders stops the open readers of a file, ending their responses. The
+// readers are closed by their own streams once they notice.
+func (c *Client) closeReaders(file *torrent.File) {
+	c.mutex.Lock()
+	var readers []*FileEntry
+	for reader := range c.readers {
+		if reader.File == file {
+			readers = append(readers, reader)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, reader := range readers {
+		reader.stop()
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// sessionScheme is the scheme of session links, as in
+// peerflix://host:port/infohash/fileIndex.
+const sessionScheme = "peerflix"
+
+// sessionMagnetTimeout is how long we wait on the sharing client for the
+// magnet link of a session.
+const sessionMagnetTimeout = 10 * time.Second
+
+// sessionMagnetLimit caps the size of the magnet link read from the sharing
+// client.
+const sessionMagnetLimit = 64 * 1024
+
+// ErrInvalidSessionURL is returned when a session link can't be parsed.
+var ErrInvalidSessionURL = errors.New("invalid session url, expected " + sessionScheme + "://host:port/infohash/fileIndex")
+
+// sessionLink is a stream shared by another client.
+type sessionLink struct {
+	Host      string
+	InfoHash  metainfo.Hash
+	FileIndex int
+}
+
+// String formats the session as a link.
+func (s sessionLink) String() string {
+	return fmt.Sprintf("%s://%s/%s/%d", sessionScheme, s.Host, s.InfoHash.HexString(), s.FileIndex)
+}
+
+// magnet returns the magnet link of the session. It's asked from the sharing
+// client to get its trackers, falling back to a bare infohash.
+func (s sessionLink) magnet() string {
+	client := http.Client{Timeout: sessionMagnetTimeout}
+	response, err := client.Get("http://" + s.Host + "/magnet")
+	if err == nil {
+		defer response.Body.Close()
+		var body []byte
+		if body, err = ioutil.ReadAll(io.LimitReader(response.Body, sessionMagnetLimit)); err == nil && response.StatusCode == http.StatusOK {
+			var magnet string
+			if magnet, err = s.sharedMagnet(string(body)); err == nil {
+				return magnet
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Error fetching the session's magnet link: %s\n", err)
+	}
+
+	return metainfo.Magnet{InfoHash: s.InfoHash}.String()
+}
+
+// sharedMagnet parses the magnet link answered by the sharing client. It
+// must be for the session's infohash, and only its trackers and name are
+// kept, as the sharing client isn't trusted any more than the link.
+func (s sessionLink) sharedMagnet(body string) (string, error) {
+	magnet, err := metainfo.ParseMagnetUri(strings.TrimSpace(body))
+	if err != nil {
+		return "", err
+	}
+	if magnet.InfoHash != s.InfoHash {
+		return "", fmt.Errorf("magnet link is for %s, expected %s", magnet.InfoHash.HexString(), s.InfoHash.HexString())
+	}
+
+	return metainfo.Magnet{
+		InfoHash:    magnet.InfoHash,
+		Trackers:    magnet.Trackers,
+		DisplayName: magnet.DisplayName,
+	}.String(), nil
+}
+
+// parseSessionURL parses a link made by SessionURL.
+func parseSessionURL(link string) (session sessionLink, err error) {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != sessionScheme || u.Host == "" {
+		return session, ErrInvalidSessionURL
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 {
+		return session, ErrInvalidSessionURL
+	}
+	if err = session.InfoHash.FromHexString(parts[0]); err != nil {
+		return session, ErrInvalidSessionURL
+	}
+	if session.FileIndex, err = strconv.Atoi(parts[1]); err != nil {
+		return session, ErrInvalidSessionURL
+	}
+	session.Host = u.Host
+
+	return session, nil
+}
+
+// advertisedHost returns the host:port other devices reach the stream on:
+// the AdvertisedHost, or else the host r was sent to, or localhost and the
+// stream port without a request.
+func (c *Client) advertisedHost(r *http.Request) string {
+	switch {
+	case c.Config.AdvertisedHost != "":
+		return c.Config.AdvertisedHost
+	case r != nil && r.Host != "":
+		return r.Host
+	default:
+		return net.JoinHostPort("localhost", strconv.Itoa(c.Port))
+	}
+}
+
+// SessionURL returns a link another client can open to stream the same file
+// of the same torrent. Before the torrent info is known, the file index is -1,
+// which picks the largest file.
+func (c *Client) SessionURL() string {
+	return sessionLink{
+		Host:      c.advertisedHost(nil),
+		InfoHash:  c.Torrent.InfoHash(),
+		FileIndex: c.selectedIndex(),
+	}.String()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestParseSessionURL(t *testing.T) {
+	tests := []struct {
+		link    string
+		want    sessionLink
+		wantErr bool
+	}{
+		{
+			link: "peerflix://192.168.1.10:8080/c9e15763f722f23e98a29decdfae341b98d53056/2",
+			want: sessionLink{Host: "192.168.1.10:8080", InfoHash: metainfo.NewHashFromHex("c9e15763f722f23e98a29decdfae341b98d53056"), FileIndex: 2},
+		},
+		{link: "peerflix://host:8080/c9e15763f722f23e98a29decdfae341b98d53056/-1", want: sessionLink{Host: "host:8080", InfoHash: metainfo.NewHashFromHex("c9e15763f722f23e98a29decdfae341b98d53056"), FileIndex: -1}},
+		{link: "http://host:8080/c9e15763f722f23e98a29decdfae341b98d53056/2", wantErr: true},
+		{link: "peerflix://host:8080/nothex/2", wantErr: true},
+		{link: "peerflix://host:8080/c9e15763f722f23e98a29decdfae341b98d53056", wantErr: true},
+		{link: "peerflix:///c9e15763f722f23e98a29decdfae341b98d53056/2", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseSessionURL(test.link)
+		if (err != nil) != test.wantErr || (!test.wantErr && got != test.want) {
+			t.Errorf("parseSessionURL(%q) = %+v, %v, want %+v, error %v", test.link, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestSharedMagnet(t *testing.T) {
+	session := sessionLink{Host: "host:8080", InfoHash: metainfo.NewHashFromHex("c9e15763f722f23e98a29decdfae341b98d53056")}
+
+	tests := []struct {
+		body    string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{
+			body: "magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056&dn=movie&tr=udp%3A%2F%2Ftracker.example.com%3A80\n",
+			want: []string{"urn:btih:c9e15763f722f23e98a29decdfae341b98d53056", "dn=movie", "tr=udp"},
+		},
+		{
+			body:    "magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056&xs=http%3A%2F%2F10.0.0.1%2Fadmin",
+			want:    []string{"urn:btih:c9e15763f722f23e98a29decdfae341b98d53056"},
+			notWant: []string{"xs=", "10.0.0.1"},
+		},
+		{body: "magnet:?xt=urn:btih:0000000000000000000000000000000000000000", wantErr: true},
+		{body: "/etc/passwd", wantErr: true},
+		{body: "http://10.0.0.1/movie.torrent", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := session.sharedMagnet(test.body)
+		if (err != nil) != test.wantErr {
+			t.Errorf("sharedMagnet(%q) error = %v, want error %v", test.body, err, test.wantErr)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("sharedMagnet(%q) = %q, missing %q", test.body, got, want)
+			}
+		}
+		for _, notWant := range test.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("sharedMagnet(%q) = %q, shouldn't contain %q", test.body, got, notWant)
+			}
+		}
+	}
+}
+
+func TestAdvertisedHost(t *testing.T) {
+	tests := []struct {
+		advertised string
+		host       string
+		want       string
+	}{
+		{"", "", "localhost:8080"},
+		{"", "192.168.1.10:8080", "192.168.1.10:8080"},
+		{"tv.lan:9000", "", "tv.lan:9000"},
+		{"tv.lan:9000", "192.168.1.10:8080", "tv.lan:9000"},
+	}
+
+	for _, test := range tests {
+		c := &Client{Port: 8080}
+		c.Config.AdvertisedHost = test.advertised
+		var r *http.Request
+		if test.host != "" {
+			r = httptest.NewRequest(http.MethodGet, "/playlist.m3u", nil)
+			r.Host = test.host
+		}
+		if got := c.advertisedHost(r); got != test.want {
+			t.Errorf("advertisedHost() with %q advertised and host %q = %q, want %q", test.advertised, test.host, got, test.want)
+		}
+	}
+}
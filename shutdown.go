@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// ShutdownRequested is closed once /shutdown is requested. The server should
+// then stop accepting connections, let the streams finish for up to the
+// ShutdownDrain, and close the client.
+func (c *Client) ShutdownRequested() <-chan struct{} {
+	return c.shutdown
+}
+
+// PostShutdown is an http handler requesting a graceful shutdown.
+func (c *Client) PostShutdown(w http.ResponseWriter, r *http.Request) {
+	if !c.authorizedToChange(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.shutdownOnce.Do(func() {
+		c.mutex.Lock()
+		streams := c.streams
+		c.mutex.Unlock()
+
+		log.Printf("Shutdown requested, draining %d streams for up to %s\n", streams, c.Config.ShutdownDrain)
+		close(c.shutdown)
+	})
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchersStopOnClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		watch func(c *Client)
+	}{
+		{"watchMemory", (*Client).watchMemory},
+		{"watchIdle", (*Client).watchIdle},
+		{"watchPrefetch", (*Client).watchPrefetch},
+		{"downloadRarest", (*Client).downloadRarest},
+		{"followSeedSchedule", (*Client).followSeedSchedule},
+		{"watchSwarm", (*Client).watchSwarm},
+		{"watchCompletion", (*Client).watchCompletion},
+		{"watchPieceTimes", (*Client).watchPieceTimes},
+		{"seedToLAN", (*Client).seedToLAN},
+		{"expireData", (*Client).expireData},
+		{"postCompleteWebhook", (*Client).postCompleteWebhook},
+		{"preferFastPeers", (*Client).preferFastPeers},
+	}
+
+	for _, test := range tests {
+		c := newTestClient(t, 1)
+		c.fileCompleted = make(chan struct{})
+		c.torrentCompleted = make(chan struct{})
+		c.backgroundPieces = make(map[int]struct{})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			test.watch(c)
+		}()
+		close(c.closing)
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s still running after the client closed", test.name)
+		}
+	}
+}
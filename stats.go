@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// UnknownLength is the Length of the torrent and file in the Stats until the
+// metadata is received.
+const UnknownLength int64 = -1
+
+// Stats describes the current state of the client.
+type Stats struct {
+	Name string
+	// InfoReady is set once the metadata is received. Until then, Length and
+	// FileLength are UnknownLength.
+	InfoReady      bool
+	BytesCompleted int64
+	Length         int64
+	// FileLength is the size of the streamed file.
+	FileLength    int64
+	Percentage    float64
+	DownloadSpeed int64
+	// Speed is DownloadSpeed formatted in the configured unit.
+	Speed            string
+	Connections      int
+	Peers            ConnectionStats
+	Swarm            SwarmHealth
+	ReadyForPlayback bool
+	Buffering        bool
+	// DirectPlay tells players on the preview to switch to the direct
+	// stream.
+	DirectPlay bool
+	// BufferedStart and BufferedEnd are the downloaded range around the
+	// read position, see BufferedRange.
+	BufferedStart int64
+	BufferedEnd   int64
+	// Checking is set while the existing data is hashed again, with the
+	// percentage of pieces checked.
+	Checking         bool
+	CheckingProgress float64
+	// DirectReads is how many requests were served from the completed file
+	// on disk.
+	DirectReads int
+	// Error is why the client stopped downloading, if it did.
+	Error string
+	// AveragePieceTime is how long pieces take to download on average.
+	AveragePieceTime time.Duration
+}
+
+// Stats returns a snapshot of the client's state.
+func (c *Client) Stats() Stats {
+	t := c.Torrent
+	stats := Stats{
+		Name:             t.Name(),
+		InfoReady:        c.infoReady(),
+		BytesCompleted:   t.BytesCompleted(),
+		Length:           UnknownLength,
+		FileLength:       UnknownLength,
+		Percentage:       c.percentage(),
+		Connections:      len(t.PeerConns()),
+		Peers:            c.ConnectionStats(),
+		Swarm:            c.SwarmHealth(),
+		ReadyForPlayback: c.ReadyForPlayback(),
+	}
+	if stats.InfoReady {
+		stats.Length = t.Length()
+		stats.FileLength = c.selectedFile().Length()
+	}
+	stats.BufferedStart, stats.BufferedEnd = c.BufferedRange()
+	stats.DirectPlay = c.directPlayReady()
+	if stats.Checking = c.isChecking(); stats.Checking {
+		stats.CheckingProgress = c.checkingProgress()
+	}
+	if err := c.Err(); err != nil {
+		stats.Error = err.Error()
+	}
+
+	c.mutex.Lock()
+	stats.DownloadSpeed = c.downloadSpeed
+	stats.Buffering = c.buffering > 0
+	stats.DirectReads = c.directReads
+	stats.AveragePieceTime = c.pieceTimes.average()
+	c.mutex.Unlock()
+	stats.Speed = c.formatSpeed(stats.DownloadSpeed)
+
+	return stats
+}
+
+// GetStatus is an http handler returning the client's stats.
+func (c *Client) GetStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Stats()); err != nil {
+		log.Printf("Error encoding status: %s\n", err)
+	}
+}
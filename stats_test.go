@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatsLengthBeforeInfo(t *testing.T) {
+	c := newTestClient(t, 2)
+	info := c.Stats()
+	if !info.InfoReady || info.Length != 2*testPieceLength || info.FileLength != 2*testPieceLength {
+		t.Errorf("Stats() with the info = ready %v, length %d, file length %d, want ready, %d and %d",
+			info.InfoReady, info.Length, info.FileLength, 2*testPieceLength, 2*testPieceLength)
+	}
+
+	// Nobody has the info of this magnet.
+	spec, _, err := torrentSpec("0123456789abcdef0123456789abcdef01234567", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Torrent, _, err = addTorrentSpec(c.Client, spec); err != nil {
+		t.Fatal(err)
+	}
+	stats := c.Stats()
+	if stats.InfoReady || stats.Length != UnknownLength || stats.FileLength != UnknownLength {
+		t.Errorf("Stats() before the info = ready %v, length %d, file length %d, want not ready and %d",
+			stats.InfoReady, stats.Length, stats.FileLength, UnknownLength)
+	}
+	if stats.BytesCompleted != 0 || stats.Percentage != 0 || stats.ReadyForPlayback {
+		t.Errorf("Stats() before the info = %+v, want nothing downloaded", stats)
+	}
+
+	w := httptest.NewRecorder()
+	c.GetStatus(w, httptest.NewRequest("GET", "/status", nil))
+	var status map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status["InfoReady"] != false || status["Length"] != float64(UnknownLength) || status["FileLength"] != float64(UnknownLength) {
+		t.Errorf("GET /status before the info = %v, want InfoReady false and the lengths %d", status, UnknownLength)
+	}
+}
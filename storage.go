@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+)
+
+// StorageRoutes maps file extensions to the directory their data is kept in.
+type StorageRoutes map[string]string
+
+// String formats the routes as a flag value.
+func (s StorageRoutes) String() string {
+	routes := make([]string, 0, len(s))
+	for extension, dir := range s {
+		routes = append(routes, extension+"="+dir)
+	}
+	sort.Strings(routes)
+
+	return strings.Join(routes, ",")
+}
+
+// Set parses a route of the form ".ext=directory".
+func (s StorageRoutes) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid storage route %q, expected .ext=directory", value)
+	}
+
+	s[normalizeExtension(parts[0])] = parts[1]
+	return nil
+}
+
+func normalizeExtension(extension string) string {
+	return "." + strings.ToLower(strings.TrimPrefix(extension, "."))
+}
+
+// dataDir returns the directory the data of a torrent is stored in, as
+// chosen by DataDirFunc.
+func (cfg ClientConfig) dataDir(infoHash string, info *metainfo.Info) string {
+	if cfg.DataDirFunc != nil {
+		if dir := cfg.DataDirFunc(infoHash, info); dir != "" {
+			return dir
+		}
+	}
+	return cfg.DataDir
+}
+
+// storageDir returns the directory a file within a torrent stored in dataDir
+// is kept in.
+func (cfg ClientConfig) storageDir(dataDir, path string) string {
+	if dir, ok := cfg.StorageRoutes[normalizeExtension(filepath.Ext(path))]; ok {
+		return dir
+	}
+	return dataDir
+}
+
+// storageDirs returns every directory the data of a torrent can be stored in.
+func (cfg ClientConfig) storageDirs(infoHash string, info *metainfo.Info) []string {
+	dirs := []string{cfg.dataDir(infoHash, info)}
+	for _, dir := range cfg.StorageRoutes {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// filePaths returns where the files of a torrent are stored on disk.
+func (cfg ClientConfig) filePaths(infoHash metainfo.Hash, info *metainfo.Info) []string {
+	dataDir := cfg.dataDir(infoHash.HexString(), info)
+	files := info.UpvertedFiles()
+	paths := make([]string, len(files))
+	for i, file := range files {
+		path := filepath.Join(append([]string{info.Name}, file.Path...)...)
+		paths[i] = filepath.Join(cfg.storageDir(dataDir, path), path)
+	}
+	return paths
+}
+
+// completedStorage checks the piece completion of each torrent opened still
+// matches its data files.
+type completedStorage struct {
+	storage.ClientImplCloser
+	cfg        ClientConfig
+	completion *bitfieldCompletion
+}
+
+func (s completedStorage) OpenTorrent(ctx context.Context, info *metainfo.Info, infoHash metainfo.Hash) (storage.TorrentImpl, error) {
+	s.completion.open(infoHash, s.cfg.filePaths(infoHash, info))
+	return s.ClientImplCloser.OpenTorrent(ctx, info, infoHash)
+}
+
+// newStorage stores the data as files, in the directory DataDirFunc picks for
+// each torrent, or the one matching their extension.
+func newStorage(cfg ClientConfig, completion *bitfieldCompletion) storage.ClientImplCloser {
+	opts := storage.NewFileClientOpts{
+		ClientBaseDir:   cfg.DataDir,
+		PieceCompletion: completion,
+	}
+
+	if cfg.DataDirFunc != nil {
+		opts.TorrentDirMaker = func(_ string, info *metainfo.Info, infoHash metainfo.Hash) string {
+			return cfg.dataDir(infoHash.HexString(), info)
+		}
+	}
+
+	if len(cfg.StorageRoutes) > 0 {
+		// The files must be within the torrent directory, so the torrent is
+		// stored from the root and the file paths made relative to it. Its
+		// data directory is remembered for the files that aren't routed
+		// elsewhere.
+		var mutex sync.Mutex
+		dataDirs := make(map[*metainfo.Info]string)
+
+		opts.TorrentDirMaker = func(_ string, info *metainfo.Info, infoHash metainfo.Hash) string {
+			mutex.Lock()
+			dataDirs[info] = cfg.dataDir(infoHash.HexString(), info)
+			mutex.Unlock()
+			return rootDir
+		}
+		opts.FilePathMaker = func(opts storage.FilePathMakerOpts) string {
+			mutex.Lock()
+			dataDir, ok := dataDirs[opts.Info]
+			mutex.Unlock()
+			if !ok {
+				dataDir = cfg.DataDir
+			}
+
+			path := filepath.Join(append([]string{opts.Info.Name}, opts.File.Path...)...)
+			return relativeToRoot(filepath.Join(cfg.storageDir(dataDir, path), path))
+		}
+	}
+
+	return completedStorage{ClientImplCloser: storage.NewFileOpts(opts), cfg: cfg, completion: completion}
+}
+
+// rootDir is the root of the filesystem.
+var rootDir = filepath.VolumeName(os.TempDir()) + string(filepath.Separator)
+
+// relativeToRoot returns the path relative to rootDir.
+func relativeToRoot(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if rel, err := filepath.Rel(rootDir, path); err == nil {
+		return rel
+	}
+	return path
+}
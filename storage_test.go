@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestStorageRoutesSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StorageRoutes
+		wantErr bool
+	}{
+		{".srt=/subs", StorageRoutes{".srt": "/subs"}, false},
+		{"SRT=/subs", StorageRoutes{".srt": "/subs"}, false},
+		{".srt=", StorageRoutes{}, true},
+		{"=/subs", StorageRoutes{}, true},
+		{"/subs", StorageRoutes{}, true},
+	}
+
+	for _, test := range tests {
+		routes := StorageRoutes{}
+		err := routes.Set(test.value)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(routes, test.want) {
+			t.Errorf("Set(%q) = %v, %v, want %v, error %v", test.value, routes, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestStorageRoutesData(t *testing.T) {
+	video := bytes.Repeat([]byte("v"), testPieceLength)
+	subtitles := bytes.Repeat([]byte("s"), testPieceLength)
+	info := metainfo.Info{
+		Name:        "Show",
+		PieceLength: testPieceLength,
+		Files: []metainfo.FileInfo{
+			{Path: []string{"movie.mkv"}, Length: testPieceLength},
+			{Path: []string{"movie.srt"}, Length: testPieceLength},
+		},
+	}
+	for _, data := range [][]byte{video, subtitles} {
+		hash := sha1.Sum(data)
+		info.Pieces = append(info.Pieces, hash[:]...)
+	}
+
+	// The subtitles are only found in their own directory.
+	dataDir, subtitlesDir := t.TempDir(), t.TempDir()
+	for path, data := range map[string][]byte{
+		filepath.Join(dataDir, "Show", "movie.mkv"):      video,
+		filepath.Join(subtitlesDir, "Show", "movie.srt"): subtitles,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := ClientConfig{DataDir: dataDir, StorageRoutes: StorageRoutes{".srt": subtitlesDir}}
+	tor := addStorageTestTorrent(t, newStorageTestClient(t, cfg), info)
+
+	for i := 0; i < tor.NumPieces(); i++ {
+		if !tor.PieceState(i).Complete {
+			t.Errorf("piece %d of %s isn't complete, want it read from its directory", i, info.Files[i].Path[0])
+		}
+	}
+}
+
+// newStorageTestClient returns a torrent client offline, storing the data as
+// configured by cfg.
+func newStorageTestClient(t *testing.T, cfg ClientConfig) *torrent.Client {
+	t.Helper()
+
+	config := torrent.NewDefaultClientConfig()
+	config.DataDir = cfg.DataDir
+	config.ListenPort = 0
+	config.NoDHT = true
+	config.DisableTrackers = true
+	config.DefaultStorage = newStorage(cfg, newBitfieldCompletion(t.TempDir()))
+	cl, err := torrent.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cl.Close() })
+	return cl
+}
+
+// addStorageTestTorrent adds the torrent with the info, once its data on disk
+// is hashed.
+func addStorageTestTorrent(t *testing.T, cl *torrent.Client, info metainfo.Info) *torrent.Torrent {
+	t.Helper()
+
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &torrent.TorrentSpec{}
+	spec.InfoHash = metainfo.HashBytes(infoBytes)
+	spec.InfoBytes = infoBytes
+	tor, _, err := cl.AddTorrentSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-tor.GotInfo():
+	case <-time.After(5 * time.Second):
+		t.Fatal("torrent info not loaded")
+	}
+	waitHashed(t, &Client{Torrent: tor})
+	return tor
+}
+
+func TestDataDirFunc(t *testing.T) {
+	movie := bytes.Repeat([]byte("m"), testPieceLength)
+	episode := bytes.Repeat([]byte("e"), testPieceLength)
+	movieHash, episodeHash := sha1.Sum(movie), sha1.Sum(episode)
+	movieInfo := metainfo.Info{Name: "movie.mkv", PieceLength: testPieceLength, Length: testPieceLength, Pieces: movieHash[:]}
+	showInfo := metainfo.Info{
+		Name:        "Show",
+		PieceLength: testPieceLength,
+		Pieces:      episodeHash[:],
+		Files:       []metainfo.FileInfo{{Path: []string{"S01E01.mkv"}, Length: testPieceLength}},
+	}
+
+	// Each torrent's data is only found on its own disk.
+	moviesDir, showsDir := t.TempDir(), t.TempDir()
+	for path, data := range map[string][]byte{
+		filepath.Join(moviesDir, "movie.mkv"):         movie,
+		filepath.Join(showsDir, "Show", "S01E01.mkv"): episode,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := ClientConfig{
+		DataDir: t.TempDir(),
+		DataDirFunc: func(infoHash string, info *metainfo.Info) string {
+			if len(info.Files) > 0 {
+				return showsDir
+			}
+			return moviesDir
+		},
+	}
+	cl := newStorageTestClient(t, cfg)
+	for _, info := range []metainfo.Info{movieInfo, showInfo} {
+		tor := addStorageTestTorrent(t, cl, info)
+		if !tor.PieceState(0).Complete {
+			t.Errorf("%s isn't complete, want it read from its directory", info.Name)
+		}
+	}
+
+	infoBytes, err := bencode.Marshal(showInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(showsDir, "Show", "S01E01.mkv")}
+	if got := cfg.filePaths(metainfo.HashBytes(infoBytes), &showInfo); !reflect.DeepEqual(got, want) {
+		t.Errorf("filePaths() = %v, want %v", got, want)
+	}
+}
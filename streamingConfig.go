@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// StreamingConfig are the streaming parameters of the ClientConfig that can
+// be tuned while running, with SetStreamingConfig or on /config.
+type StreamingConfig struct {
+	Readahead          int64
+	MaxPiecesAhead     int
+	ReadyPercentage    float64
+	BufferingThreshold time.Duration
+	DropBehindBytes    int64
+	UploadRateLimit    int64
+	DownloadRateLimit  int64
+}
+
+// validate checks the parameters are within their bounds.
+func (s StreamingConfig) validate() error {
+	switch {
+	case s.Readahead < 0:
+		return errors.New("readahead can't be negative")
+	case s.MaxPiecesAhead < 0:
+		return errors.New("max pieces ahead can't be negative")
+	case s.ReadyPercentage < 0 || s.ReadyPercentage > 100:
+		return errors.New("ready percentage must be between 0 and 100")
+	case s.BufferingThreshold < 0:
+		return errors.New("buffering threshold can't be negative")
+	case s.DropBehindBytes < 0:
+		return errors.New("drop behind bytes can't be negative")
+	case s.UploadRateLimit < 0 || s.DownloadRateLimit < 0:
+		return errors.New("rate limits can't be negative")
+	}
+	return nil
+}
+
+// StreamingConfig returns the streaming parameters in use.
+func (c *Client) StreamingConfig() StreamingConfig {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return StreamingConfig{
+		Readahead:          c.Config.Readahead,
+		MaxPiecesAhead:     c.Config.MaxPiecesAhead,
+		ReadyPercentage:    c.Config.ReadyPercentage,
+		BufferingThreshold: c.Config.BufferingThreshold,
+		DropBehindBytes:    c.Config.DropBehindBytes,
+		UploadRateLimit:    c.Config.UploadRateLimit,
+		DownloadRateLimit:  c.Config.DownloadRateLimit,
+	}
+}
+
+// SetStreamingConfig changes the streaming parameters, applying them to the
+// open readers, the piece priorities and the rate limiters.
+func (c *Client) SetStreamingConfig(s StreamingConfig) error {
+	if err := s.validate(); err != nil {
+		return ClientError{Type: "setting streaming config", Origin: err}
+	}
+
+	c.mutex.Lock()
+	c.Config.Readahead = s.Readahead
+	c.Config.MaxPiecesAhead = s.MaxPiecesAhead
+	c.Config.ReadyPercentage = s.ReadyPercentage
+	c.Config.BufferingThreshold = s.BufferingThreshold
+	c.Config.DropBehindBytes = s.DropBehindBytes
+	c.Config.UploadRateLimit = s.UploadRateLimit
+	c.Config.DownloadRateLimit = s.DownloadRateLimit
+	c.mutex.Unlock()
+
+	setRateLimit(c.uploadLimiter, s.UploadRateLimit)
+	setRateLimit(c.downloadLimiter, s.DownloadRateLimit)
+
+	if c.infoReady() {
+		c.updateReadahead()
+		c.prioritizeTorrent()
+	}
+	return nil
+}
+
+// GetConfig is an http handler returning the streaming parameters, and
+// changing them for PATCH requests.
+func (c *Client) GetConfig(w http.ResponseWriter, r *http.Request) {
+	if !c.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPatch:
+		if !c.authorizedToChange(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Fields missing from the body keep their current value.
+		config := c.StreamingConfig()
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := c.SetStreamingConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Streaming config changed to %+v\n", config)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.StreamingConfig()); err != nil {
+		log.Printf("Error encoding config: %s\n", err)
+	}
+}
+
+// rateLimitBurst is the smallest burst of a rate limiter, which has to fit
+// the chunks and reads of the peer connections.
+const rateLimitBurst = 1 << 20
+
+// newRateLimiter returns a rate limiter of bytesPerSecond, zero not limiting.
+func newRateLimiter(bytesPerSecond int64) *rate.Limiter {
+	limiter := rate.NewLimiter(rate.Inf, rateLimitBurst)
+	setRateLimit(limiter, bytesPerSecond)
+	return limiter
+}
+
+// setRateLimit sets a rate limiter to bytesPerSecond, zero not limiting.
+func setRateLimit(limiter *rate.Limiter, bytesPerSecond int64) {
+	if limiter == nil {
+		return
+	}
+	if bytesPerSecond == 0 {
+		limiter.SetLimit(rate.Inf)
+		return
+	}
+
+	limiter.SetLimit(rate.Limit(bytesPerSecond))
+	limiter.SetBurst(int(max(bytesPerSecond, rateLimitBurst)))
+}
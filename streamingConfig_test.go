@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestPatchConfig(t *testing.T) {
+	c := newTestClient(t, 4)
+	c.Config.Readahead = 1 << 20
+	c.uploadLimiter = newRateLimiter(0)
+	c.downloadLimiter = newRateLimiter(0)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "/config", strings.NewReader(body))
+		r.RemoteAddr = "127.0.0.1:4000"
+		w := httptest.NewRecorder()
+		c.GetConfig(w, r)
+		return w
+	}
+
+	w := patch(`{"Readahead": 2097152, "UploadRateLimit": 50000, "DownloadRateLimit": 2000000}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH /config = %d %s, want %d", w.Code, w.Body, http.StatusOK)
+	}
+	var got StreamingConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Readahead != 2<<20 || got.UploadRateLimit != 50000 || got.DownloadRateLimit != 2000000 {
+		t.Errorf("PATCH /config = %+v, want the readahead and rate limits patched", got)
+	}
+	if limit := c.uploadLimiter.Limit(); limit != 50000 {
+		t.Errorf("upload limit = %v, want 50000", limit)
+	}
+	if limit, burst := c.downloadLimiter.Limit(), c.downloadLimiter.Burst(); limit != 2000000 || burst != 2000000 {
+		t.Errorf("download limit = %v with a burst of %d, want 2000000 with the same burst", limit, burst)
+	}
+
+	if w := patch(`{"UploadRateLimit": -1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("PATCH /config with a negative rate limit = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if limit := c.uploadLimiter.Limit(); limit != 50000 {
+		t.Errorf("upload limit after an invalid patch = %v, want 50000", limit)
+	}
+
+	if w := patch(`{"UploadRateLimit": 0}`); w.Code != http.StatusOK {
+		t.Errorf("PATCH /config without upload limit = %d, want %d", w.Code, http.StatusOK)
+	}
+	if limit := c.uploadLimiter.Limit(); limit != rate.Inf {
+		t.Errorf("upload limit after removing it = %v, want unlimited", limit)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// subtitleExtensions are the subtitle formats that can be served as WebVTT.
+var subtitleExtensions = map[string]bool{
+	".srt": true,
+	".ass": true,
+	".ssa": true,
+	".sub": true,
+	".vtt": true,
+}
+
+// srtTimestamp matches the comma separated milliseconds of srt timestamps.
+var srtTimestamp = regexp.MustCompile(`(\d+:\d{2}:\d{2}),(\d{3})`)
+
+// assOverride matches the style overrides in ass dialogue, like {\i1}. It
+// matches the MicroDVD control codes, like {y:i}, too.
+var assOverride = regexp.MustCompile(`\{[^}]*\}`)
+
+// microDVDLine matches a MicroDVD subtitle, like {100}{200}Hello|world, timed
+// in frames.
+var microDVDLine = regexp.MustCompile(`^\{(\d+)\}\{(\d+)\}(.*)$`)
+
+// microDVDFrameRate is the frame rate MicroDVD subtitles are timed at, unless
+// their first line sets it.
+const microDVDFrameRate = 23.976
+
+// baseName returns the lowercased file name without its extension.
+func baseName(path string) string {
+	name := filepath.Base(path)
+	return strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// subtitleScore rates how well a subtitle file matches a video: the length of
+// the common start of their names, more if it's in the wanted language.
+func subtitleScore(video, subtitle, language string) int {
+	videoName, subtitleName := baseName(video), baseName(subtitle)
+
+	score := 0
+	for score < len(videoName) && score < len(subtitleName) && videoName[score] == subtitleName[score] {
+		score++
+	}
+
+	if language != "" {
+		for _, tag := range strings.FieldsFunc(subtitleName, func(r rune) bool {
+			return r == '.' || r == '_' || r == '-' || r == ' '
+		}) {
+			if strings.EqualFold(tag, language) {
+				score += 1000
+				break
+			}
+		}
+	}
+
+	return score
+}
+
+// selectSubtitle returns the index of the subtitle file best matching the
+// video, or -1 when there's none.
+func selectSubtitle(files []FileInfo, video, language string) int {
+	best, bestScore := -1, -1
+	for _, file := range files {
+		if !subtitleExtensions[strings.ToLower(filepath.Ext(file.Path))] {
+			continue
+		}
+		if score := subtitleScore(video, file.Path, language); score > bestScore {
+			best, bestScore = file.Index, score
+		}
+	}
+	return best
+}
+
+// subtitleFile returns the subtitle file matching the streamed file.
+func (c *Client) subtitleFile() *torrent.File {
+	if !c.infoReady() {
+		return nil
+	}
+
+	files := c.files()
+	video := torrentFileName(c.Torrent, c.selectedFile())
+	if index := selectSubtitle(c.ListFiles(), video, c.Config.SubtitleLanguage); index >= 0 {
+		return files[index]
+	}
+	return nil
+}
+
+// applySubtitlePriority downloads the subtitles early, as they're small and
+// needed from the start.
+func (c *Client) applySubtitlePriority() {
+	subtitle := c.subtitleFile()
+	if subtitle == nil || subtitle.Length() == 0 {
+		return
+	}
+
+	pieceLength := c.Torrent.Info().PieceLength
+	first := int(subtitle.Offset() / pieceLength)
+	last := int((subtitle.Offset() + subtitle.Length() - 1) / pieceLength)
+	for i := first; i <= last; i++ {
+		c.Torrent.Piece(i).SetPriority(torrent.PiecePriorityHigh)
+	}
+}
+
+// srtToVTT converts SubRip subtitles to WebVTT.
+func srtToVTT(srt []byte) []byte {
+	text := strings.Replace(string(srt), "\r\n", "\n", -1)
+	text = strings.TrimPrefix(text, "\ufeff")
+	return []byte("WEBVTT\n\n" + srtTimestamp.ReplaceAllString(text, "$1.$2"))
+}
+
+// parseASSTime parses an ass timestamp, like 0:01:02.50.
+func parseASSTime(timestamp string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(timestamp), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), nil
+}
+
+// assToVTT converts the dialogue of SubStation Alpha subtitles to WebVTT,
+// dropping the styling.
+func assToVTT(ass []byte) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("WEBVTT\n")
+
+	var format []string
+	var inEvents bool
+	scanner := bufio.NewScanner(bytes.NewReader(ass))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "["):
+			// Every section has its own format, only the events one has the
+			// dialogue.
+			inEvents = strings.EqualFold(line, "[Events]")
+			format = nil
+		case strings.HasPrefix(line, "Format:") && inEvents:
+			for _, field := range strings.Split(strings.TrimPrefix(line, "Format:"), ",") {
+				format = append(format, strings.TrimSpace(field))
+			}
+		case strings.HasPrefix(line, "Dialogue:") && format != nil:
+			// The text is the last field and can contain commas.
+			fields := strings.SplitN(strings.TrimPrefix(line, "Dialogue:"), ",", len(format))
+			if len(fields) != len(format) {
+				continue
+			}
+
+			var start, end time.Duration
+			var text string
+			var err error
+			for i, name := range format {
+				switch name {
+				case "Start":
+					start, err = parseASSTime(fields[i])
+				case "End":
+					end, err = parseASSTime(fields[i])
+				case "Text":
+					text = fields[i]
+				}
+				if err != nil {
+					break
+				}
+			}
+			if err != nil {
+				continue
+			}
+
+			text = assOverride.ReplaceAllString(text, "")
+			text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+			fmt.Fprintf(&buffer, "\n%s --> %s\n%s\n", formatVTTTime(start), formatVTTTime(end), text)
+		}
+	}
+
+	return buffer.Bytes()
+}
+
+// subToVTT converts MicroDVD subtitles to WebVTT. Image based .sub files, as
+// VobSub ones, have no text to convert.
+func subToVTT(sub []byte) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("WEBVTT\n")
+
+	frameRate := microDVDFrameRate
+	first := true
+	scanner := bufio.NewScanner(bytes.NewReader(sub))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		match := microDVDLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(match[1])
+		end, _ := strconv.Atoi(match[2])
+		text := match[3]
+
+		// A first {1}{1}23.976 line sets the frame rate.
+		if first {
+			first = false
+			if rate, err := strconv.ParseFloat(text, 64); err == nil && start <= 1 && end <= 1 && rate > 0 {
+				frameRate = rate
+				continue
+			}
+		}
+
+		text = strings.Replace(assOverride.ReplaceAllString(text, ""), "|", "\n", -1)
+		fmt.Fprintf(&buffer, "\n%s --> %s\n%s\n", formatVTTTime(frameTime(start, frameRate)), formatVTTTime(frameTime(end, frameRate)), text)
+	}
+
+	return buffer.Bytes()
+}
+
+// frameTime returns the time of a frame at a frame rate.
+func frameTime(frame int, frameRate float64) time.Duration {
+	return time.Duration(float64(frame) / frameRate * float64(time.Second))
+}
+
+// GetSubtitles is an http handler serving the subtitles in the torrent that
+// match the streamed file, as WebVTT.
+func (c *Client) GetSubtitles(w http.ResponseWriter, r *http.Request) {
+	subtitle := c.subtitleFile()
+	if subtitle == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	entry, err := NewFileReader(c, subtitle)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entry.SetContext(r.Context())
+	defer func() {
+		if err := entry.Close(); err != nil {
+			log.Printf("Error closing file reader: %s\n", err)
+		}
+	}()
+
+	data, err := ioutil.ReadAll(entry)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch strings.ToLower(filepath.Ext(subtitle.Path())) {
+	case ".srt":
+		data = srtToVTT(data)
+	case ".ass", ".ssa":
+		data = assToVTT(data)
+	case ".sub":
+		data = subToVTT(data)
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Write(data)
+}
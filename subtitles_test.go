@@ -0,0 +1,144 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSelectSubtitle(t *testing.T) {
+	files := []FileInfo{
+		{Index: 0, Path: "Show/Show.S01E01.mkv"},
+		{Index: 1, Path: "Show/Show.S01E01.en.srt"},
+		{Index: 2, Path: "Show/Show.S01E01.fr.srt"},
+		{Index: 3, Path: "Show/Show.S01E02.mkv"},
+		{Index: 4, Path: "Show/Show.S01E02.ass"},
+		{Index: 5, Path: "Show/Sample.srt"},
+		{Index: 6, Path: "Show/Show.S01E03.mkv"},
+		{Index: 7, Path: "Show/Show.S01E03.sub"},
+	}
+
+	tests := []struct {
+		files    []FileInfo
+		video    string
+		language string
+		want     int
+	}{
+		{files, "Show/Show.S01E01.mkv", "", 1},
+		{files, "Show/Show.S01E01.mkv", "fr", 2},
+		{files, "Show/Show.S01E01.mkv", "EN", 1},
+		{files, "Show/Show.S01E02.mkv", "", 4},
+		{files, "Show/Show.S01E03.mkv", "", 7},
+		{files[:1], "Show/Show.S01E01.mkv", "", -1},
+		{nil, "Show/Show.S01E01.mkv", "", -1},
+	}
+
+	for _, test := range tests {
+		if got := selectSubtitle(test.files, test.video, test.language); got != test.want {
+			t.Errorf("selectSubtitle(%s, %q) = %d, want %d", test.video, test.language, got, test.want)
+		}
+	}
+}
+
+func TestSrtToVTT(t *testing.T) {
+	tests := []struct {
+		srt  string
+		want string
+	}{
+		{
+			"1\r\n00:00:01,500 --> 00:00:03,000\r\nHello\r\n",
+			"WEBVTT\n\n1\n00:00:01.500 --> 00:00:03.000\nHello\n",
+		},
+		{
+			"\ufeff1\n00:01:02,003 --> 00:01:04,050\nTwo\nlines\n",
+			"WEBVTT\n\n1\n00:01:02.003 --> 00:01:04.050\nTwo\nlines\n",
+		},
+		{"", "WEBVTT\n\n"},
+	}
+
+	for _, test := range tests {
+		if got := string(srtToVTT([]byte(test.srt))); got != test.want {
+			t.Errorf("srtToVTT(%q) = %q, want %q", test.srt, got, test.want)
+		}
+	}
+}
+
+func TestAssToVTT(t *testing.T) {
+	tests := []struct {
+		name string
+		ass  string
+		want string
+	}{
+		{
+			name: "standard file",
+			ass: `[Script Info]
+Title: Example
+ScriptType: v4.00+
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, Bold, Italic
+Style: Default,Arial,20,&H00FFFFFF,0,0
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+Dialogue: 0,0:00:01.50,0:00:03.00,Default,,0,0,0,,Hello, {\i1}world{\i0}
+Dialogue: 0,0:01:02.00,0:01:04.25,Default,,0,0,0,,Two\Nlines
+`,
+			want: "WEBVTT\n\n00:00:01.500 --> 00:00:03.000\nHello, world\n\n00:01:02.000 --> 00:01:04.250\nTwo\nlines\n",
+		},
+		{
+			name: "events before styles",
+			ass: `[Events]
+Format: Start, End, Text
+Dialogue: 0:00:01.00,0:00:02.00,First
+
+[V4+ Styles]
+Format: Name, Fontname
+Dialogue: 0:00:03.00,0:00:04.00,Outside of the events
+`,
+			want: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nFirst\n",
+		},
+		{
+			name: "invalid timestamps",
+			ass: `[Events]
+Format: Start, End, Text
+Dialogue: soon,later,Skipped
+`,
+			want: "WEBVTT\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := string(assToVTT([]byte(test.ass))); got != test.want {
+			t.Errorf("%s: assToVTT() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSubToVTT(t *testing.T) {
+	tests := []struct {
+		name string
+		sub  string
+		want string
+	}{
+		{
+			name: "default frame rate",
+			sub:  "{0}{23976}Hello|{y:i}world\n",
+			want: "WEBVTT\n\n00:00:00.000 --> 00:16:40.000\nHello\nworld\n",
+		},
+		{
+			name: "frame rate line",
+			sub:  "\ufeff{1}{1}25\r\n{25}{50}One second\r\n{100}{125}Four seconds\r\n",
+			want: "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nOne second\n\n00:00:04.000 --> 00:00:05.000\nFour seconds\n",
+		},
+		{
+			name: "not MicroDVD",
+			sub:  "\x00\x00\x01\xba binary VobSub",
+			want: "WEBVTT\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := string(subToVTT([]byte(test.sub))); got != test.want {
+			t.Errorf("%s: subToVTT() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/tracker"
+)
+
+const (
+	// swarmScrapeInterval is how often the trackers are scraped.
+	swarmScrapeInterval = 5 * time.Minute
+	// scrapeTimeout is how long a tracker gets to answer a scrape.
+	scrapeTimeout = 10 * time.Second
+)
+
+// SwarmHealth is the size of the swarm as reported by the trackers.
+type SwarmHealth struct {
+	Seeders   int
+	Leechers  int
+	Completed int
+	// Trackers is how many trackers answered the scrape.
+	Trackers int
+}
+
+// String summarizes the swarm on one line.
+func (s SwarmHealth) String() string {
+	return fmt.Sprintf("%d seeds / %d peers", s.Seeders, s.Leechers)
+}
+
+// aggregateScrapes combines the scrapes of several trackers. The trackers
+// mostly see the same peers, so the largest counts are kept rather than
+// summing them.
+func aggregateScrapes(scrapes []SwarmHealth) (health SwarmHealth) {
+	for _, scrape := range scrapes {
+		if scrape.Seeders > health.Seeders {
+			health.Seeders = scrape.Seeders
+		}
+		if scrape.Leechers > health.Leechers {
+			health.Leechers = scrape.Leechers
+		}
+		if scrape.Completed > health.Completed {
+			health.Completed = scrape.Completed
+		}
+		health.Trackers++
+	}
+	return
+}
+
+// SwarmHealth returns the last scrape of the trackers.
+func (c *Client) SwarmHealth() SwarmHealth {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.swarmHealth
+}
+
+// watchSwarm scrapes the trackers of the torrent regularly. Private
+// torrents and PrivateMode are never scraped, as private trackers often
+// forbid it.
+func (c *Client) watchSwarm() {
+	if !c.waitInfo() || !c.scrapesSwarm() {
+		return
+	}
+
+	for {
+		var trackers []string
+		for _, tier := range c.Trackers() {
+			trackers = append(trackers, tier...)
+		}
+
+		scrapes := scrapeTrackers(trackers, c.Torrent.InfoHash())
+		c.mutex.Lock()
+		c.swarmHealth = aggregateScrapes(scrapes)
+		c.mutex.Unlock()
+
+		if !c.sleep(swarmScrapeInterval) {
+			return
+		}
+	}
+}
+
+// scrapesSwarm checks the trackers can be scraped for the torrent.
+func (c *Client) scrapesSwarm() bool {
+	return !c.Config.PrivateMode && !isPrivate(c.Torrent.Info())
+}
+
+// scrapeTrackers scrapes the trackers concurrently, skipping the ones that
+// fail or don't support it.
+func scrapeTrackers(trackers []string, infoHash metainfo.Hash) []SwarmHealth {
+	var scrapes []SwarmHealth
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+
+	for _, announce := range trackers {
+		wait.Add(1)
+		go func(announce string) {
+			defer wait.Done()
+
+			scrape, err := scrapeTracker(announce, infoHash)
+			if errors.Is(err, tracker.ErrBadScheme) {
+				return
+			} else if err != nil {
+				log.Printf("Error scraping %s: %s\n", announce, err)
+				return
+			}
+
+			mutex.Lock()
+			scrapes = append(scrapes, scrape)
+			mutex.Unlock()
+		}(announce)
+	}
+	wait.Wait()
+
+	return scrapes
+}
+
+// scrapeTracker scrapes an http or udp tracker with the library's client.
+func scrapeTracker(announce string, infoHash metainfo.Hash) (SwarmHealth, error) {
+	client, err := tracker.NewClient(announce, tracker.NewClientOpts{})
+	if err != nil {
+		return SwarmHealth{}, err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	response, err := client.Scrape(ctx, []metainfo.Hash{infoHash})
+	if err != nil {
+		return SwarmHealth{}, err
+	}
+	if len(response) == 0 {
+		return SwarmHealth{}, errors.New("torrent missing from the scrape")
+	}
+
+	return SwarmHealth{
+		Seeders:   int(response[0].Seeders),
+		Leechers:  int(response[0].Leechers),
+		Completed: int(response[0].Completed),
+	}, nil
+}
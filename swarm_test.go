@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestAggregateScrapes(t *testing.T) {
+	tests := []struct {
+		scrapes []SwarmHealth
+		want    SwarmHealth
+	}{
+		{nil, SwarmHealth{}},
+		{[]SwarmHealth{{Seeders: 3, Leechers: 4, Completed: 5}}, SwarmHealth{Seeders: 3, Leechers: 4, Completed: 5, Trackers: 1}},
+		{
+			[]SwarmHealth{{Seeders: 3, Leechers: 9, Completed: 5}, {Seeders: 7, Leechers: 2, Completed: 1}},
+			SwarmHealth{Seeders: 7, Leechers: 9, Completed: 5, Trackers: 2},
+		},
+	}
+
+	for _, test := range tests {
+		if got := aggregateScrapes(test.scrapes); got != test.want {
+			t.Errorf("aggregateScrapes(%v) = %+v, want %+v", test.scrapes, got, test.want)
+		}
+	}
+}
+
+func TestScrapeTrackers(t *testing.T) {
+	infoHash := metainfo.HashBytes([]byte("movie"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scrape" || r.URL.Query().Get("info_hash") != string(infoHash[:]) {
+			http.NotFound(w, r)
+			return
+		}
+		response := map[string]interface{}{
+			"files": map[string]interface{}{
+				string(infoHash[:]): map[string]int{"complete": 12, "downloaded": 40, "incomplete": 3},
+			},
+		}
+		if err := bencode.NewEncoder(w).Encode(response); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		trackers []string
+		want     []SwarmHealth
+	}{
+		{[]string{server.URL + "/announce"}, []SwarmHealth{{Seeders: 12, Leechers: 3, Completed: 40}}},
+		{[]string{"wss://tracker.example.com/announce"}, nil},
+		{[]string{"wss://tracker.example.com/announce", server.URL + "/announce"}, []SwarmHealth{{Seeders: 12, Leechers: 3, Completed: 40}}},
+	}
+
+	for _, test := range tests {
+		if got := scrapeTrackers(test.trackers, infoHash); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("scrapeTrackers(%v) = %+v, want %+v", test.trackers, got, test.want)
+		}
+	}
+}
+
+func TestScrapesSwarm(t *testing.T) {
+	c := newTestClient(t, 1)
+	tests := []struct {
+		privateMode bool
+		want        bool
+	}{
+		{false, true},
+		{true, false},
+	}
+
+	for _, test := range tests {
+		c.Config.PrivateMode = test.privateMode
+		if got := c.scrapesSwarm(); got != test.want {
+			t.Errorf("scrapesSwarm() in private mode %v = %v, want %v", test.privateMode, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/anacrolix/torrent"
+)
+
+// ErrTooManyTorrents is returned when adding a torrent would go over the
+// MaxTorrents.
+var ErrTooManyTorrents = errors.New("too many torrents")
+
+// ErrNotRemote is returned when a torrent added at runtime isn't a magnet, an
+// infohash or an http url, as local paths must not be read on request.
+var ErrNotRemote = errors.New("only magnets, infohashes and http urls can be added")
+
+// markStreamed records the streamed torrent as used now, for the eviction of
+// the least recently streamed torrent.
+func (c *Client) markStreamed(t *torrent.Torrent) {
+	c.mutex.Lock()
+	c.lastStreamed[t.InfoHash()] = c.now()
+	c.mutex.Unlock()
+}
+
+// enforceMaxTorrents keeps the client within MaxTorrents after adding t,
+// either by evicting the least recently streamed torrent or by dropping t.
+func (c *Client) enforceMaxTorrents(t *torrent.Torrent) error {
+	torrents := c.Client.Torrents()
+	if c.Config.MaxTorrents <= 0 || len(torrents) <= c.Config.MaxTorrents {
+		c.markStreamed(t)
+		return nil
+	}
+
+	if !c.Config.EvictTorrents {
+		t.Drop()
+		return ClientError{Type: "adding torrent", Origin: ErrTooManyTorrents}
+	}
+
+	// The streamed torrent and the new one are never evicted.
+	c.mutex.Lock()
+	var evict *torrent.Torrent
+	for i, candidate := range torrents {
+		if candidate.InfoHash() == c.Torrent.InfoHash() || candidate.InfoHash() == t.InfoHash() {
+			continue
+		}
+		if evict == nil || c.lastStreamed[candidate.InfoHash()].Before(c.lastStreamed[evict.InfoHash()]) {
+			evict = torrents[i]
+		}
+	}
+	if evict != nil {
+		delete(c.lastStreamed, evict.InfoHash())
+	}
+	c.mutex.Unlock()
+
+	if evict == nil {
+		t.Drop()
+		return ClientError{Type: "adding torrent", Origin: ErrTooManyTorrents}
+	}
+
+	log.Printf("Removing %s, the least recently streamed torrent, to make room\n", evict.Name())
+	evict.Drop()
+	c.markStreamed(t)
+	return nil
+}
+
+// PostAdd is an http handler adding the torrent parameter next to the
+// streamed one, answering 429 once MaxTorrents is reached.
+func (c *Client) PostAdd(w http.ResponseWriter, r *http.Request) {
+	if !c.authorizedToChange(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	t, err := c.AddTorrent(r.FormValue("torrent"))
+	if clientError, ok := err.(ClientError); ok && clientError.Origin == ErrTooManyTorrents {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct{ InfoHash, Name string }{t.InfoHash().HexString(), t.Name()}); err != nil {
+		log.Printf("Error encoding added torrent: %s\n", err)
+	}
+}
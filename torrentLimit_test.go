@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsRemoteTorrent(t *testing.T) {
+	tests := []struct {
+		torrentPath string
+		want        bool
+	}{
+		{"magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056", true},
+		{"c9e15763f722f23e98a29decdfae341b98d53056", true},
+		{"http://example.com/movie.torrent", true},
+		{"https://example.com/movie.torrent", true},
+		{"/etc/passwd", false},
+		{"movie.torrent", false},
+		{"file:///etc/passwd", false},
+		{"ftp://example.com/movie.torrent", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isRemoteTorrent(test.torrentPath); got != test.want {
+			t.Errorf("isRemoteTorrent(%q) = %v, want %v", test.torrentPath, got, test.want)
+		}
+	}
+}
+
+func TestAuthorizedToChange(t *testing.T) {
+	tests := []struct {
+		token      string
+		remoteAddr string
+		sent       string
+		want       bool
+	}{
+		{"", "127.0.0.1:4000", "", true},
+		{"", "[::1]:4000", "", true},
+		{"", "192.168.1.10:4000", "", false},
+		{"", "203.0.113.5:4000", "", false},
+		{"secret", "203.0.113.5:4000", "secret", true},
+		{"secret", "203.0.113.5:4000", "wrong", false},
+		{"secret", "127.0.0.1:4000", "", false},
+	}
+
+	for _, test := range tests {
+		c := &Client{Config: ClientConfig{AuthToken: test.token}}
+		r := httptest.NewRequest("POST", "/add", nil)
+		r.RemoteAddr = test.remoteAddr
+		if test.sent != "" {
+			r.Header.Set("Authorization", "Bearer "+test.sent)
+		}
+
+		if got := c.authorizedToChange(r); got != test.want {
+			t.Errorf("authorizedToChange with token %q from %s sending %q = %v, want %v", test.token, test.remoteAddr, test.sent, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// TorrentPriority is the preference a torrent gets over the other torrents of
+// the client, when running several of them.
+type TorrentPriority int
+
+// Torrent priorities.
+const (
+	TorrentPriorityPaused TorrentPriority = iota
+	TorrentPriorityNormal
+	TorrentPriorityHigh
+)
+
+// ErrUnknownTorrent is returned for an infohash that isn't in the client.
+var ErrUnknownTorrent = errors.New("unknown torrent")
+
+// piecePriority is the priority the pieces of a torrent with this priority
+// are requested at. The readahead of the stream always comes first.
+func (p TorrentPriority) piecePriority() torrent.PiecePriority {
+	switch p {
+	case TorrentPriorityPaused:
+		return torrent.PiecePriorityNone
+	case TorrentPriorityHigh:
+		return torrent.PiecePriorityHigh
+	default:
+		return torrent.PiecePriorityNormal
+	}
+}
+
+// SetTorrentPriority sets the priority of the streamed torrent or one added
+// with AddTorrent, so the watched one can be preferred over the background
+// downloads.
+func (c *Client) SetTorrentPriority(infoHash metainfo.Hash, level TorrentPriority) error {
+	if infoHash == c.Torrent.InfoHash() {
+		c.mutex.Lock()
+		c.torrentPriority = level
+		c.mutex.Unlock()
+
+		if c.infoReady() {
+			c.prioritizeTorrent()
+		}
+		return nil
+	}
+
+	for _, t := range c.Client.Torrents() {
+		if t.InfoHash() != infoHash {
+			continue
+		}
+
+		go func(t *torrent.Torrent) {
+			select {
+			case <-t.GotInfo():
+				setPiecePriorities(t, level.piecePriority())
+			case <-c.closing:
+			}
+		}(t)
+		return nil
+	}
+
+	return ClientError{Type: "setting torrent priority", Origin: ErrUnknownTorrent}
+}
+
+// bulkPriority is the priority of the streamed torrent's pieces outside the
+// readahead. Nothing is requested while paused for being idle.
+func (c *Client) bulkPriority() torrent.PiecePriority {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.idlePaused {
+		return torrent.PiecePriorityNone
+	}
+	return c.torrentPriority.piecePriority()
+}
+
+// setPiecePriorities sets the priority of all the pieces still missing.
+func setPiecePriorities(t *torrent.Torrent, priority torrent.PiecePriority) {
+	for i := 0; i < t.NumPieces(); i++ {
+		if !t.PieceState(i).Complete {
+			t.Piece(i).SetPriority(priority)
+		}
+	}
+}
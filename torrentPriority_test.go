@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestSetTorrentPriority(t *testing.T) {
+	c := newTestClient(t, 4)
+	waitHashed(t, c)
+	c.Config.MaxPiecesAhead = 0
+
+	infoBytes, err := bencode.Marshal(metainfo.Info{Name: "background.mkv", PieceLength: testPieceLength, Length: 4 * testPieceLength, Pieces: make([]byte, 20*4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := &torrent.TorrentSpec{}
+	spec.InfoHash = metainfo.HashBytes(infoBytes)
+	spec.InfoBytes = infoBytes
+	background, _, err := c.Client.AddTorrentSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetTorrentPriority(c.Torrent.InfoHash(), TorrentPriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetTorrentPriority(background.InfoHash(), TorrentPriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	// The background torrent is prioritized once it has its info.
+	deadline := time.After(5 * time.Second)
+	for background.PieceState(0).Priority != torrent.PiecePriorityNormal {
+		select {
+		case <-deadline:
+			t.Fatalf("background piece priority = %v, want %v", background.PieceState(0).Priority, torrent.PiecePriorityNormal)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	for i := 0; i < c.Torrent.NumPieces(); i++ {
+		if foreground, background := c.Torrent.PieceState(i).Priority, background.PieceState(i).Priority; foreground <= background {
+			t.Errorf("piece %d: foreground priority %v, want more than the background %v", i, foreground, background)
+		}
+	}
+
+	if err := c.SetTorrentPriority(c.Torrent.InfoHash(), TorrentPriorityPaused); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Torrent.PieceState(0).Priority; got != torrent.PiecePriorityNone {
+		t.Errorf("paused piece priority = %v, want %v", got, torrent.PiecePriorityNone)
+	}
+
+	err = c.SetTorrentPriority(metainfo.Hash{1}, TorrentPriorityHigh)
+	if clientError, ok := err.(ClientError); !ok || clientError.Origin != ErrUnknownTorrent {
+		t.Errorf("SetTorrentPriority() of an unknown torrent = %v, want %v", err, ErrUnknownTorrent)
+	}
+}
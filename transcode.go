@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamURL is where ffmpeg and ffprobe read the stream from. They run on
+// this host, so it's always the loopback address, whatever is advertised.
+func (c *Client) streamURL() string {
+	return "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(c.Port)) + "/"
+}
+
+// ffprobe runs ffprobe on the stream, returning its json output. It's killed
+// once ctx is done, like when the request probing ends.
+func (c *Client) ffprobe(ctx context.Context, args ...string) ([]byte, error) {
+	args = append([]string{"-v", "quiet", "-print_format", "json"}, args...)
+	return exec.CommandContext(ctx, "ffprobe", append(args, c.streamURL())...).Output()
+}
+
+// probeAudioLanguages returns the language of each audio stream, in order.
+func (c *Client) probeAudioLanguages(ctx context.Context) ([]string, error) {
+	output, err := c.ffprobe(ctx, "-show_streams", "-select_streams", "a")
+	if err != nil {
+		return nil, err
+	}
+
+	var probe struct {
+		Streams []struct {
+			Tags struct {
+				Language string `json:"language"`
+			} `json:"tags"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, err
+	}
+
+	languages := make([]string, len(probe.Streams))
+	for i, stream := range probe.Streams {
+		languages[i] = stream.Tags.Language
+	}
+	return languages, nil
+}
+
+// selectAudioStream returns the first audio stream in the language, or -1.
+func selectAudioStream(languages []string, language string) int {
+	for i, streamLanguage := range languages {
+		if language != "" && strings.EqualFold(streamLanguage, language) {
+			return i
+		}
+	}
+	return -1
+}
+
+// audioStream returns the audio stream in the configured language, or -1 for
+// the default one. The streams are only probed once per file.
+func (c *Client) audioStream(ctx context.Context) int {
+	if c.Config.AudioLanguage == "" {
+		return -1
+	}
+
+	c.mutex.Lock()
+	languages := c.audioLanguages
+	c.mutex.Unlock()
+
+	if languages == nil {
+		var err error
+		if languages, err = c.probeAudioLanguages(ctx); err != nil {
+			log.Printf("Error probing audio streams: %s\n", err)
+		} else {
+			c.mutex.Lock()
+			c.audioLanguages = languages
+			c.mutex.Unlock()
+		}
+	}
+	return selectAudioStream(languages, c.Config.AudioLanguage)
+}
+
+// transcodeOptions tweak the ffmpeg output.
+type transcodeOptions struct {
+	// Start seeks the input before remuxing it.
+	Start time.Duration
+	// AudioStream is the audio stream to keep, -1 keeps the default one.
+	AudioStream int
+	// VideoBitrate reencodes the video at this many kbit/s, scaled down to
+	// Height lines. Zero copies the video as is.
+	VideoBitrate int
+	Height       int
+}
+
+// ffmpegArgs builds the ffmpeg command line remuxing input to stdout.
+func ffmpegArgs(input string, opts transcodeOptions) []string {
+	audio := "0:a:0?"
+	if opts.AudioStream >= 0 {
+		audio = "0:a:" + strconv.Itoa(opts.AudioStream)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if opts.Start > 0 {
+		args = append(args, "-ss", strconv.FormatFloat(opts.Start.Seconds(), 'f', -1, 64))
+	}
+	args = append(args,
+		"-i", input,
+		"-map", "0:v:0?",
+		"-map", audio,
+	)
+
+	if opts.VideoBitrate > 0 {
+		bitrate := strconv.Itoa(opts.VideoBitrate) + "k"
+		args = append(args,
+			"-c:v", "libx264", "-preset", "veryfast",
+			"-b:v", bitrate, "-maxrate", bitrate, "-bufsize", strconv.Itoa(opts.VideoBitrate*2)+"k",
+		)
+		if opts.Height > 0 {
+			args = append(args, "-vf", "scale=-2:'min("+strconv.Itoa(opts.Height)+",ih)'")
+		}
+		args = append(args, "-c:a", "aac", "-b:a", "64k")
+	} else {
+		args = append(args, "-c:v", "copy", "-c:a", "aac")
+	}
+
+	return append(args, "-f", "matroska", "pipe:1")
+}
+
+// audioArgs builds the ffmpeg command line extracting the audio of input to
+// stdout, as an aac stream.
+func audioArgs(input string, audioStream int) []string {
+	audio := "0:a:0"
+	if audioStream >= 0 {
+		audio = "0:a:" + strconv.Itoa(audioStream)
+	}
+
+	return []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", input,
+		"-map", audio,
+		"-vn", "-sn",
+		"-c:a", "aac", "-b:a", "128k",
+		"-f", "adts", "pipe:1",
+	}
+}
+
+// GetTranscode is an http handler remuxing the file with ffmpeg, keeping the
+// audio track in the configured language when there is one. The start
+// parameter seeks to a position in seconds, as the output can't be ranged.
+func (c *Client) GetTranscode(w http.ResponseWriter, r *http.Request) {
+	if !c.Config.Transcode {
+		http.NotFound(w, r)
+		return
+	}
+
+	var start float64
+	if value := r.FormValue("start"); value != "" {
+		var err error
+		if start, err = strconv.ParseFloat(value, 64); err != nil || start < 0 {
+			http.Error(w, fmt.Sprintf("invalid start %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	opts := transcodeOptions{
+		Start:       time.Duration(start * float64(time.Second)),
+		AudioStream: c.audioStream(r.Context()),
+	}
+	c.serveFFmpeg(w, r, "video/x-matroska", ffmpegArgs(c.streamURL(), opts))
+}
+
+// GetAudio is an http handler serving only the audio of the file, in the
+// configured language when there is one, for listening to it.
+func (c *Client) GetAudio(w http.ResponseWriter, r *http.Request) {
+	if !c.Config.AudioOnly {
+		http.NotFound(w, r)
+		return
+	}
+
+	c.serveFFmpeg(w, r, "audio/aac", audioArgs(c.streamURL(), c.audioStream(r.Context())))
+}
+
+// GetPreview is an http handler serving a low bitrate transcode of the file,
+// which plays on slow connections while the file buffers. Once the file is
+// ready for playback, it redirects to the direct stream, unless the force
+// parameter is set.
+func (c *Client) GetPreview(w http.ResponseWriter, r *http.Request) {
+	if !c.Config.Preview {
+		http.NotFound(w, r)
+		return
+	}
+
+	if c.directPlayReady() && r.FormValue("force") == "" {
+		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		return
+	}
+
+	opts := transcodeOptions{
+		AudioStream:  -1,
+		VideoBitrate: c.Config.PreviewBitrate,
+		Height:       c.Config.PreviewHeight,
+	}
+	c.serveFFmpeg(w, r, "video/x-matroska", ffmpegArgs(c.streamURL(), opts))
+}
+
+// directPlayReady checks if enough is buffered to switch from the preview to
+// the direct stream.
+func (c *Client) directPlayReady() bool {
+	c.mutex.Lock()
+	buffering := c.buffering > 0
+	c.mutex.Unlock()
+
+	return c.ReadyForPlayback() && !buffering
+}
+
+// serveFFmpeg streams the output of ffmpeg into the response, unless
+// MaxTranscodes ffmpeg processes are already running.
+func (c *Client) serveFFmpeg(w http.ResponseWriter, r *http.Request, contentType string, args []string) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		http.Error(w, "ffmpeg isn't installed", http.StatusNotImplemented)
+		return
+	}
+
+	if !c.startTranscode() {
+		http.Error(w, "too many transcodes", http.StatusServiceUnavailable)
+		return
+	}
+	defer c.endTranscode()
+
+	w.Header().Set("Content-Type", contentType)
+
+	command := exec.CommandContext(r.Context(), path, args...)
+	command.Stdout = w
+	if err := command.Run(); err != nil && r.Context().Err() == nil {
+		log.Printf("Error running ffmpeg: %s\n", err)
+	}
+}
+
+// startTranscode counts a running ffmpeg process, returning false when
+// MaxTranscodes are already running.
+func (c *Client) startTranscode() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.Config.MaxTranscodes > 0 && c.transcodes >= c.Config.MaxTranscodes {
+		return false
+	}
+	c.transcodes++
+	return true
+}
+
+func (c *Client) endTranscode() {
+	c.mutex.Lock()
+	c.transcodes--
+	c.mutex.Unlock()
+}
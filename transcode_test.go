@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectAudioStream(t *testing.T) {
+	tests := []struct {
+		languages []string
+		language  string
+		want      int
+	}{
+		{nil, "fre", -1},
+		{[]string{"eng", "fre"}, "fre", 1},
+		{[]string{"eng", "FRE", "fre"}, "fre", 1},
+		{[]string{"eng", ""}, "", -1},
+		{[]string{"eng"}, "ger", -1},
+	}
+
+	for _, test := range tests {
+		if got := selectAudioStream(test.languages, test.language); got != test.want {
+			t.Errorf("selectAudioStream(%v, %q) = %d, want %d", test.languages, test.language, got, test.want)
+		}
+	}
+}
+
+func TestAudioStreamProbedOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffprobe is a shell script")
+	}
+
+	// A fake ffprobe counting its runs.
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := "#!/bin/sh\necho run >> " + runs + "\n" +
+		`echo '{"streams": [{"tags": {"language": "eng"}}, {"tags": {"language": "fre"}}]}'` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "ffprobe"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	c := &Client{}
+	c.Config.AudioLanguage = "fre"
+	for i := 0; i < 3; i++ {
+		if got := c.audioStream(context.Background()); got != 1 {
+			t.Errorf("audioStream() = %d, want 1", got)
+		}
+	}
+
+	data, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(data), "run"); count != 1 {
+		t.Errorf("ffprobe ran %d times, want once", count)
+	}
+}
+
+func TestFFprobeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := &Client{}
+	if _, err := c.ffprobe(ctx, "-show_chapters"); err == nil {
+		t.Error("ffprobe() with a cancelled context succeeded, want an error")
+	}
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	const input = "http://127.0.0.1:8080/"
+	tests := []struct {
+		name string
+		opts transcodeOptions
+		want []string
+	}{
+		{
+			"default audio, copied video",
+			transcodeOptions{AudioStream: -1},
+			[]string{"-hide_banner", "-loglevel", "error", "-i", input, "-map", "0:v:0?", "-map", "0:a:0?",
+				"-c:v", "copy", "-c:a", "aac", "-f", "matroska", "pipe:1"},
+		},
+		{
+			"second audio track",
+			transcodeOptions{AudioStream: 1},
+			[]string{"-hide_banner", "-loglevel", "error", "-i", input, "-map", "0:v:0?", "-map", "0:a:1",
+				"-c:v", "copy", "-c:a", "aac", "-f", "matroska", "pipe:1"},
+		},
+		{
+			"seeked",
+			transcodeOptions{Start: 90500 * time.Millisecond, AudioStream: -1},
+			[]string{"-hide_banner", "-loglevel", "error", "-ss", "90.5", "-i", input, "-map", "0:v:0?", "-map", "0:a:0?",
+				"-c:v", "copy", "-c:a", "aac", "-f", "matroska", "pipe:1"},
+		},
+		{
+			"reencoded video",
+			transcodeOptions{AudioStream: -1, VideoBitrate: 800},
+			[]string{"-hide_banner", "-loglevel", "error", "-i", input, "-map", "0:v:0?", "-map", "0:a:0?",
+				"-c:v", "libx264", "-preset", "veryfast", "-b:v", "800k", "-maxrate", "800k", "-bufsize", "1600k",
+				"-c:a", "aac", "-b:a", "64k", "-f", "matroska", "pipe:1"},
+		},
+		{
+			"reencoded and scaled video",
+			transcodeOptions{AudioStream: 0, VideoBitrate: 800, Height: 480},
+			[]string{"-hide_banner", "-loglevel", "error", "-i", input, "-map", "0:v:0?", "-map", "0:a:0",
+				"-c:v", "libx264", "-preset", "veryfast", "-b:v", "800k", "-maxrate", "800k", "-bufsize", "1600k",
+				"-vf", "scale=-2:'min(480,ih)'", "-c:a", "aac", "-b:a", "64k", "-f", "matroska", "pipe:1"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := ffmpegArgs(input, test.opts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: ffmpegArgs() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAudioArgs(t *testing.T) {
+	const input = "http://127.0.0.1:8080/"
+	tests := []struct {
+		audioStream int
+		want        string
+	}{
+		{-1, "0:a:0"},
+		{0, "0:a:0"},
+		{2, "0:a:2"},
+	}
+
+	for _, test := range tests {
+		want := []string{"-hide_banner", "-loglevel", "error", "-i", input, "-map", test.want,
+			"-vn", "-sn", "-c:a", "aac", "-b:a", "128k", "-f", "adts", "pipe:1"}
+		if got := audioArgs(input, test.audioStream); !reflect.DeepEqual(got, want) {
+			t.Errorf("audioArgs(%d) = %q, want %q", test.audioStream, got, want)
+		}
+	}
+}
+
+func TestGetTranscodeLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+
+	// A fake ffmpeg writing nothing.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		transcode  bool
+		transcodes int
+		start      string
+		want       int
+	}{
+		{false, 0, "", http.StatusNotFound},
+		{true, 0, "", http.StatusOK},
+		{true, 1, "90.5", http.StatusOK},
+		{true, 0, "-1", http.StatusBadRequest},
+		{true, 0, "1:30", http.StatusBadRequest},
+		{true, 2, "", http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		c := &Client{}
+		c.Config.Transcode = test.transcode
+		c.Config.MaxTranscodes = 2
+		c.transcodes = test.transcodes
+
+		w := httptest.NewRecorder()
+		c.GetTranscode(w, httptest.NewRequest(http.MethodGet, "/transcode?start="+test.start, nil))
+		if w.Code != test.want {
+			t.Errorf("GET /transcode?start=%s enabled %v with %d running = %d, want %d", test.start, test.transcode, test.transcodes, w.Code, test.want)
+		}
+		if c.transcodes != test.transcodes {
+			t.Errorf("transcodes after the request = %d, want %d", c.transcodes, test.transcodes)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// streamStarted marks a new http stream being served.
+func (c *Client) streamStarted() {
+	c.mutex.Lock()
+	c.streams++
+	paused := c.idlePaused
+	c.lastStreamed[c.Torrent.InfoHash()] = c.now()
+	c.mutex.Unlock()
+
+	if paused {
+		c.resumeFromIdle()
+	}
+}
+
+// streamEnded marks an http stream as finished.
+func (c *Client) streamEnded() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.streams--
+	if c.streams == 0 {
+		c.idleSince = c.now()
+	}
+}
+
+// idleFor returns for how long nothing has been streamed.
+func (c *Client) idleFor() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.streams > 0 {
+		return 0
+	}
+	return c.now().Sub(c.idleSince)
+}
+
+// expireData drops the torrent and removes its data once the file has been
+// complete and idle for the configured TTL.
+func (c *Client) expireData() {
+	// The file only counts as idle from the moment it completes.
+	select {
+	case <-c.fileCompleted:
+	case <-c.closing:
+		return
+	}
+
+	c.mutex.Lock()
+	if c.streams == 0 {
+		c.idleSince = c.now()
+	}
+	c.mutex.Unlock()
+
+	for c.idleFor() < c.Config.DataTTL {
+		if !c.sleep(time.Second) {
+			return
+		}
+	}
+
+	log.Printf("Removing %s after being idle for %s\n", c.Torrent.Name(), c.Config.DataTTL)
+	c.Torrent.Drop()
+	c.removeData()
+}
+
+// removeData removes the files of the torrent from disk, along with the state
+// kept about it. Only paths within the directory a file is stored in are
+// touched, whatever the torrent names its files.
+func (c *Client) removeData() {
+	dataDir := c.Config.dataDir(c.Torrent.InfoHash().HexString(), c.Torrent.Info())
+	for _, f := range c.Torrent.Files() {
+		dir := c.Config.storageDir(dataDir, f.Path())
+		path := c.filePath(f)
+		if !withinDir(dir, path) {
+			log.Printf("Error removing torrent data: %s is outside of %s\n", path, dir)
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing torrent data: %s\n", err)
+			continue
+		}
+		removeEmptyParents(filepath.Dir(path), dir)
+	}
+
+	if err := c.completion.forget(c.Torrent.InfoHash()); err != nil {
+		log.Printf("Error removing piece completion: %s\n", err)
+	}
+	for _, path := range []string{c.fileListPath(), c.priorityProfilePath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing torrent state: %s\n", err)
+		}
+	}
+}
+
+// withinDir checks if path is strictly inside dir.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// removeEmptyParents removes the directories left empty from dir up to, but
+// not including, root.
+func removeEmptyParents(dir, root string) {
+	for withinDir(root, dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWithinDir(t *testing.T) {
+	dir := filepath.Join("data", "peerflix")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "movie.mkv"), true},
+		{filepath.Join(dir, "show", "episode.mkv"), true},
+		{filepath.Join(dir, "..peerflix"), true},
+		{dir, false},
+		{"data", false},
+		{filepath.Join(dir, "..", "other"), false},
+		{filepath.Join(dir, "..", "..", "etc", "passwd"), false},
+		{filepath.Join("elsewhere", "movie.mkv"), false},
+	}
+
+	for _, test := range tests {
+		if got := withinDir(dir, test.path); got != test.want {
+			t.Errorf("withinDir(%q, %q) = %v, want %v", dir, test.path, got, test.want)
+		}
+	}
+}
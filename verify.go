@@ -0,0 +1,46 @@
+package main
+
+import "context"
+
+// pieceVerified checks if a piece is complete and not being hashed.
+func (c *Client) pieceVerified(piece int) bool {
+	state := c.Torrent.PieceState(piece)
+	return state.Complete && !pieceChecking(state)
+}
+
+// waitVerified blocks until the piece at the torrent offset is verified, and
+// returns how many of the next length bytes are in verified pieces. It gives
+// up once ctx is done or the client is closed.
+func (c *Client) waitVerified(ctx context.Context, offset, length int64) (int64, error) {
+	pieceLength := c.Torrent.Info().PieceLength
+	piece := int(offset / pieceLength)
+
+	if !c.pieceVerified(piece) {
+		subscription := c.Torrent.SubscribePieceStateChanges()
+		defer subscription.Close()
+
+		// Checked again once subscribed, so no change is missed.
+		for !c.pieceVerified(piece) {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-c.closing:
+				return 0, ErrClientClosed
+			case _, ok := <-subscription.Values:
+				if !ok {
+					return 0, ErrClientClosed
+				}
+			}
+		}
+	}
+
+	end := (int64(piece) + 1) * pieceLength
+	for end < offset+length && c.pieceVerified(int(end/pieceLength)) {
+		end += pieceLength
+	}
+
+	if end > offset+length {
+		return length, nil
+	}
+	return end - offset, nil
+}
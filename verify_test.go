@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitVerifiedGivesUp(t *testing.T) {
+	c := newTestClient(t, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.waitVerified(ctx, 0, 100); err != context.DeadlineExceeded {
+		t.Errorf("waitVerified() on a missing piece = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(c.closing)
+	if _, err := c.waitVerified(context.Background(), 0, 100); err != ErrClientClosed {
+		t.Errorf("waitVerified() on a closed client = %v, want %v", err, ErrClientClosed)
+	}
+}
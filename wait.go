@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+// fileComplete checks if all the pieces of the streamed file are downloaded.
+func (c *Client) fileComplete() bool {
+	if !c.infoReady() {
+		return false
+	}
+	return c.piecesComplete(c.selectedFile())
+}
+
+// piecesComplete checks if all the pieces of a file are downloaded.
+func (c *Client) piecesComplete(file *torrent.File) bool {
+	pieceLength := c.Torrent.Info().PieceLength
+	begin := int(file.Offset() / pieceLength)
+	end := int((file.Offset() + file.Length() + pieceLength - 1) / pieceLength)
+
+	for i := begin; i < end; i++ {
+		if !c.Torrent.PieceState(i).Complete {
+			return false
+		}
+	}
+
+	return true
+}
+
+// watchCompletion signals when the streamed file and the whole torrent are
+// done downloading.
+func (c *Client) watchCompletion() {
+	if !c.waitInfo() {
+		return
+	}
+
+	fileDone := false
+	for {
+		if !fileDone && c.fileComplete() {
+			fileDone = true
+			close(c.fileCompleted)
+		}
+		if c.Torrent.BytesCompleted() == c.Torrent.Length() {
+			if !fileDone {
+				close(c.fileCompleted)
+			}
+			close(c.torrentCompleted)
+			return
+		}
+
+		if !c.sleep(time.Second) {
+			return
+		}
+	}
+}
+
+// WaitForComplete blocks until the streamed file, or the whole torrent with
+// CompleteWholeTorrent, is downloaded or the context is done.
+func (c *Client) WaitForComplete(ctx context.Context) error {
+	completed := c.fileCompleted
+	if c.Config.CompleteWholeTorrent {
+		completed = c.torrentCompleted
+	}
+
+	select {
+	case <-completed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
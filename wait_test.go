@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+func TestWaitForComplete(t *testing.T) {
+	// Only the first episode is downloaded.
+	first := make([]byte, testPieceLength)
+	second := make([]byte, testPieceLength)
+	for i := range second {
+		second[i] = 1
+	}
+	info := metainfo.Info{
+		Name:        "Show",
+		PieceLength: testPieceLength,
+		Files: []metainfo.FileInfo{
+			{Path: []string{"S01E01.mkv"}, Length: testPieceLength},
+			{Path: []string{"S01E02.mkv"}, Length: testPieceLength},
+		},
+	}
+	for _, data := range [][]byte{first, second} {
+		hash := sha1.Sum(data)
+		info.Pieces = append(info.Pieces, hash[:]...)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "Show"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Show", "S01E01.mkv"), first, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		wholeTorrent bool
+		want         error
+	}{
+		{"file", false, nil},
+		{"whole torrent", true, context.DeadlineExceeded},
+	}
+	for _, test := range tests {
+		c := startTestClient(t, info, dir)
+		waitHashed(t, c)
+		c.Config.FileIndex = 0
+		c.Config.CompleteWholeTorrent = test.wholeTorrent
+		c.fileCompleted = make(chan struct{})
+		c.torrentCompleted = make(chan struct{})
+		go c.watchCompletion()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		if err := c.WaitForComplete(ctx); err != test.want {
+			t.Errorf("%s: WaitForComplete() = %v, want %v", test.name, err, test.want)
+		}
+		cancel()
+		c.Close()
+	}
+}
+
+func TestWaitForCompleteCancelled(t *testing.T) {
+	c := newTestClient(t, 1)
+	c.fileCompleted = make(chan struct{})
+	c.torrentCompleted = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.WaitForComplete(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("WaitForComplete() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitForComplete() didn't return once cancelled")
+	}
+}
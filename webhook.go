@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/anacrolix/torrent"
+)
+
+const (
+	// webhookAttempts is how many times the webhook is posted before giving
+	// up, waiting webhookBackoff, then twice as long, between the attempts.
+	webhookAttempts = 5
+	webhookBackoff  = time.Second
+	webhookTimeout  = 10 * time.Second
+)
+
+// webhookPayload is the json posted to the OnCompleteWebhook.
+type webhookPayload struct {
+	Name     string `json:"name"`
+	InfoHash string `json:"info_hash"`
+	Size     int64  `json:"size"`
+	Path     string `json:"path"`
+}
+
+// postCompleteWebhook posts the completed file, or the whole torrent with
+// CompleteWholeTorrent, to the OnCompleteWebhook once it's downloaded.
+func (c *Client) postCompleteWebhook() {
+	completed := c.fileCompleted
+	if c.Config.CompleteWholeTorrent {
+		completed = c.torrentCompleted
+	}
+	select {
+	case <-completed:
+	case <-c.closing:
+		return
+	}
+
+	file := c.selectedFile()
+	payload := webhookPayload{
+		Name:     c.Torrent.Name(),
+		InfoHash: c.Torrent.InfoHash().HexString(),
+		Size:     file.Length(),
+		Path:     c.filePath(file),
+	}
+	if c.Config.CompleteWholeTorrent {
+		payload.Size = c.Torrent.Length()
+		payload.Path = filepath.Join(c.Config.dataDir(payload.InfoHash, c.Torrent.Info()), c.Torrent.Name())
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding webhook payload: %s\n", err)
+		return
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		if err = postWebhook(client, c.Config.OnCompleteWebhook, body); err == nil {
+			return
+		}
+		if attempt == webhookAttempts {
+			log.Printf("Error posting webhook, giving up: %s\n", err)
+			return
+		}
+
+		log.Printf("Error posting webhook, retrying in %s: %s\n", backoff, err)
+		if !c.sleep(backoff) {
+			return
+		}
+		backoff *= 2
+	}
+}
+
+func postWebhook(client http.Client, url string, body []byte) error {
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("received status %s", response.Status)
+	}
+	return nil
+}
+
+// filePath returns where a file of the torrent is stored on disk.
+func (c *Client) filePath(f *torrent.File) string {
+	dataDir := c.Config.dataDir(c.Torrent.InfoHash().HexString(), c.Torrent.Info())
+	return filepath.Join(c.Config.storageDir(dataDir, f.Path()), f.Path())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPostCompleteWebhook(t *testing.T) {
+	payloads := make(chan webhookPayload, webhookAttempts)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		// The first attempt fails, and is retried.
+		if attempts == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	c := newSeededTestClient(t, []byte("the whole movie"))
+	c.Config.DataDir = filepath.Join("data", "movies")
+	c.Config.OnCompleteWebhook = server.URL
+	c.fileCompleted = make(chan struct{})
+	go c.postCompleteWebhook()
+	defer close(c.closing)
+	close(c.fileCompleted)
+
+	select {
+	case got := <-payloads:
+		want := webhookPayload{
+			Name:     "movie.mkv",
+			InfoHash: c.Torrent.InfoHash().HexString(),
+			Size:     int64(len("the whole movie")),
+			Path:     filepath.Join("data", "movies", "movie.mkv"),
+		}
+		if got != want {
+			t.Errorf("webhook payload = %+v, want %+v", got, want)
+		}
+	case <-time.After(webhookBackoff + 5*time.Second):
+		t.Fatal("webhook not posted")
+	}
+}
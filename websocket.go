@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// websocketBuffer is how many messages a slow client can fall behind
+	// before messages are dropped for it.
+	websocketBuffer = 16
+	// websocketWriteTimeout closes connections that stopped reading.
+	websocketWriteTimeout = 10 * time.Second
+	// websocketMaxPayload is the largest message accepted from clients,
+	// which only send control frames.
+	websocketMaxPayload = 4096
+)
+
+// websocketUpgrader accepts the handshakes of /ws. Dashboards can be served
+// from anywhere, access is checked with the auth token instead.
+var websocketUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// statsEvent is a message of /ws: a Stats snapshot every WebSocketInterval,
+// or an event as it happens.
+type statsEvent struct {
+	// Type is stats, buffering or peers.
+	Type        string
+	Stats       *Stats `json:",omitempty"`
+	Buffering   *bool  `json:",omitempty"`
+	Connections *int   `json:",omitempty"`
+}
+
+// subscribeEvents returns a channel receiving the buffering events.
+func (c *Client) subscribeEvents() chan statsEvent {
+	events := make(chan statsEvent, websocketBuffer)
+
+	c.mutex.Lock()
+	c.eventSubscribers[events] = struct{}{}
+	c.mutex.Unlock()
+
+	return events
+}
+
+func (c *Client) unsubscribeEvents(events chan statsEvent) {
+	c.mutex.Lock()
+	delete(c.eventSubscribers, events)
+	c.mutex.Unlock()
+}
+
+// publishEvent sends an event to the subscribers keeping up.
+func (c *Client) publishEvent(event statsEvent) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for events := range c.eventSubscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// GetWebSocket is an http handler pushing the stats and events over a
+// websocket, so dashboards don't have to poll /status.
+func (c *Client) GetWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !c.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The upgrader replies to the failed handshakes itself.
+	conn, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(websocketMaxPayload)
+
+	// Messages are dropped rather than queued for clients that can't keep up.
+	messages := make(chan []byte, websocketBuffer)
+	sendEvent := func(event statsEvent) {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Error encoding websocket event: %s\n", err)
+			return
+		}
+		select {
+		case messages <- payload:
+		default:
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readWebSocket(conn)
+	}()
+
+	// The connection is closed once the messages left are written.
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		writeWebSocket(conn, messages)
+	}()
+	defer func() {
+		close(messages)
+		<-written
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("Error closing websocket: %s\n", err)
+		}
+	}()
+
+	events := c.subscribeEvents()
+	defer c.unsubscribeEvents(events)
+
+	interval := c.Config.WebSocketInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	connections := -1
+	for {
+		stats := c.Stats()
+		if connections >= 0 && stats.Connections != connections {
+			sendEvent(statsEvent{Type: "peers", Connections: &stats.Connections})
+		}
+		connections = stats.Connections
+		sendEvent(statsEvent{Type: "stats", Stats: &stats})
+
+		select {
+		case <-ticker.C:
+		case event := <-events:
+			sendEvent(event)
+		case <-done:
+			return
+		}
+	}
+}
+
+// readWebSocket reads from the client until it closes the connection. The
+// library answers its pings and close, and rejects unmasked frames.
+func readWebSocket(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Error reading websocket: %s\n", err)
+			}
+			return
+		}
+	}
+}
+
+// writeWebSocket writes the messages to the client until the channel is
+// closed or the client stops reading.
+func writeWebSocket(conn *websocket.Conn, messages chan []byte) {
+	for message := range messages {
+		conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			conn.Close()
+			for range messages {
+			}
+			return
+		}
+	}
+}
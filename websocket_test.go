@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newWebSocketServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+
+	c := newTestClient(t, 1)
+	c.Config.AuthToken = token
+	c.Config.WebSocketInterval = time.Hour
+	server := httptest.NewServer(http.HandlerFunc(c.GetWebSocket))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	server := newWebSocketServer(t, "secret")
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{url, http.StatusUnauthorized},
+		{url + "?token=wrong", http.StatusUnauthorized},
+		{url + "?token=secret", http.StatusSwitchingProtocols},
+	}
+
+	for _, test := range tests {
+		conn, response, err := websocket.DefaultDialer.Dial(test.url, nil)
+		if response == nil {
+			t.Fatalf("dialing %s: %s", test.url, err)
+		}
+		if response.StatusCode != test.want {
+			t.Errorf("dialing %s = %d, want %d", test.url, response.StatusCode, test.want)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+
+	response, err := http.Get(server.URL + "?token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain request = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestWebSocketStats(t *testing.T) {
+	server := newWebSocketServer(t, "")
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	messageType, payload, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event statsEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatal(err)
+	}
+	if messageType != websocket.TextMessage || event.Type != "stats" || event.Stats == nil {
+		t.Errorf("first message = %d %s, want stats as text", messageType, payload)
+	}
+
+	// Pings are answered with the same payload.
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	if err := conn.WriteControl(websocket.PingMessage, []byte("hello"), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	go conn.ReadMessage()
+	select {
+	case data := <-pong:
+		if data != "hello" {
+			t.Errorf("pong = %q, want %q", data, "hello")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("ping not answered")
+	}
+}
+
+func TestWebSocketRejectsUnmaskedFrames(t *testing.T) {
+	server := newWebSocketServer(t, "")
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := "GET / HTTP/1.1\r\n" +
+		"Host: " + conn.RemoteAddr().String() + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q, want %q", got, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	}
+
+	// An unmasked text frame, which clients must never send.
+	if _, err := conn.Write([]byte{0x81, 0x02, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server closes the connection with a protocol error, after the
+	// messages already on their way.
+	for {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			t.Fatal("connection closed without a close frame")
+		}
+		payload := make([]byte, header[1]&0x7F)
+		if header[1]&0x7F == 126 {
+			extended := make([]byte, 2)
+			if _, err := io.ReadFull(reader, extended); err != nil {
+				t.Fatal(err)
+			}
+			payload = make([]byte, int(extended[0])<<8|int(extended[1]))
+		}
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			t.Fatal(err)
+		}
+		if header[0]&0x0F != websocket.CloseMessage {
+			continue
+		}
+
+		if code := int(payload[0])<<8 | int(payload[1]); code != websocket.CloseProtocolError {
+			t.Errorf("close code = %d, want %d", code, websocket.CloseProtocolError)
+		}
+		return
+	}
+}